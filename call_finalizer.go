@@ -3,6 +3,9 @@ package gopxgrid
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"reflect"
+	"time"
 )
 
 type (
@@ -10,6 +13,11 @@ type (
 		StatusCode int
 		Result     R
 		Body       string
+		Header     http.Header
+		// Elapsed is the time from sending the request until the response was received
+		Elapsed time.Duration
+		// RemoteAddr is the address of the node which served the request
+		RemoteAddr string
 	}
 
 	NoResultResponse struct {
@@ -31,23 +39,32 @@ type (
 	}
 
 	call[R any] struct {
-		svc     *pxGridService
-		call    string
-		payload any
-		result  R
-		mapper  func(*Response) (R, error)
+		svc       *pxGridService
+		method    string
+		call      string
+		payload   any
+		newResult func() any
+		mapper    func(*Response) (R, error)
+		// idempotent calls are retried as configured by RetryConfig
+		idempotent bool
 
 		fatal error
 	}
 
 	noResultCall[T any] struct {
-		svc     *pxGridService
-		call    string
-		payload any
-		mapper  func(*Response) error
+		svc        *pxGridService
+		call       string
+		payload    any
+		mapper     func(*Response) error
+		idempotent bool
 
 		fatal error
 	}
+
+	// idempotentCall is implemented by the calls which may be marked as retryable
+	idempotentCall interface {
+		setIdempotent()
+	}
 )
 
 func (c *call[R]) Do(ctx context.Context) (FullResponse[R], error) {
@@ -55,7 +72,7 @@ func (c *call[R]) Do(ctx context.Context) (FullResponse[R], error) {
 		return c.returnError(c.fatal)
 	}
 
-	res, err := c.svc.call(ctx, c.call, c.payload, c.result)
+	res, err := c.svc.send(ctx, c.call, c.payload, c.options())
 	if err != nil {
 		return c.returnError(err)
 	}
@@ -68,7 +85,7 @@ func (c *call[R]) DoOnNode(ctx context.Context, node int) (FullResponse[R], erro
 		return c.returnError(c.fatal)
 	}
 
-	res, err := c.svc.call(ctx, c.call, c.payload, c.result, IndexNodePicker(node))
+	res, err := c.svc.send(ctx, c.call, c.payload, c.options(), IndexNodePicker(node))
 	if err != nil {
 		return c.returnError(err)
 	}
@@ -94,7 +111,7 @@ func (c *call[R]) DoOnNodes(ctx context.Context, nodes ...int) (FullResponse[R],
 		return c.returnError(c.fatal)
 	}
 
-	res, err := c.svc.call(ctx, c.call, c.payload, c.result, IndexNodePicker(nodes...))
+	res, err := c.svc.send(ctx, c.call, c.payload, c.options(), IndexNodePicker(nodes...))
 	if err != nil {
 		return c.returnError(err)
 	}
@@ -102,51 +119,108 @@ func (c *call[R]) DoOnNodes(ctx context.Context, nodes ...int) (FullResponse[R],
 	return c.returnResult(res)
 }
 
+func (c *call[R]) setIdempotent() {
+	c.idempotent = true
+}
+
+func (c *call[R]) options() RESTOptions {
+	ops := RESTOptions{method: c.method, idempotent: c.idempotent}
+	if c.newResult != nil {
+		ops.result = c.newResult()
+	}
+	return ops
+}
+
 func (c *call[R]) returnError(err error) (FullResponse[R], error) {
-	return FullResponse[R]{Result: c.result}, err
+	var result R
+	return FullResponse[R]{Result: result}, err
 }
 
 func (c *call[R]) returnResult(res *Response) (FullResponse[R], error) {
+	full := FullResponse[R]{
+		StatusCode: res.StatusCode,
+		Body:       res.Body,
+		Header:     res.Header,
+		Elapsed:    res.Elapsed,
+		RemoteAddr: res.RemoteAddr,
+	}
+
 	if c.mapper != nil {
 		mapped, err := c.mapper(res)
-		return FullResponse[R]{
-			Result:     mapped,
-			StatusCode: res.StatusCode,
-			Body:       res.Body,
-		}, err
+		full.Result = mapped
+		return full, err
 	}
 
-	if res.Result == nil {
-		return FullResponse[R]{
-			Result:     c.result,
-			StatusCode: res.StatusCode,
-			Body:       res.Body,
-		}, nil
+	if res.Result != nil {
+		full.Result = res.Result.(R)
 	}
 
-	return FullResponse[R]{
-		Result:     res.Result.(R),
-		StatusCode: res.StatusCode,
-		Body:       res.Body,
-	}, nil
+	return full, nil
 }
 
+// newCall creates a call decoding the response into a new value pointed to by R,
+// if R is not a pointer the response is not decoded
 func newCall[R any](svc *pxGridService, apiCall string, payload any, mapper func(*Response) (R, error)) CallFinalizer[R] {
-	var result R
+	var newResult func() any
+	if t := reflect.TypeFor[R](); t.Kind() == reflect.Pointer {
+		newResult = func() any {
+			return reflect.New(t.Elem()).Interface()
+		}
+	}
+
+	return &call[R]{
+		svc:       svc,
+		call:      apiCall,
+		payload:   payload,
+		newResult: newResult,
+		mapper:    mapper,
+	}
+}
+
+// newCallWithResult creates a call decoding the response into a new *T,
+// the mapper is expected to convert *T into R
+func newCallWithResult[R any, T any](svc *pxGridService, apiCall string, payload any, mapper func(*Response) (R, error)) CallFinalizer[R] {
 	return &call[R]{
 		svc:     svc,
 		call:    apiCall,
 		payload: payload,
-		result:  result,
-		mapper:  mapper,
+		newResult: func() any {
+			return new(T)
+		},
+		mapper: mapper,
+	}
+}
+
+// newMethodCall creates a call sent with the given HTTP method instead of POST,
+// the call is idempotent if the method is
+func newMethodCall[R any](svc *pxGridService, method, apiCall string, payload any, mapper func(*Response) (R, error)) CallFinalizer[R] {
+	c := newCall[R](svc, apiCall, payload, mapper).(*call[R])
+	c.method = method
+	c.idempotent = isIdempotentMethod(method)
+	return c
+}
+
+// query marks the call as idempotent. Queries only read data, so they are
+// retried as configured by RetryConfig
+func query[C any](c C) C {
+	if i, ok := any(c).(idempotentCall); ok {
+		i.setIdempotent()
+	}
+	return c
+}
+
+// isIdempotentMethod reports whether the HTTP method is idempotent as defined by RFC 9110
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
 	}
+	return false
 }
 
 func newFailedCall[R any](err error) CallFinalizer[R] {
-	var result R
 	return &call[R]{
-		result: result,
-		fatal:  err,
+		fatal: err,
 	}
 }
 
@@ -155,8 +229,7 @@ func (c *noResultCall[T]) Do(ctx context.Context) (NoResultResponse, error) {
 		return c.returnError(c.fatal)
 	}
 
-	var result T
-	res, err := c.svc.call(ctx, c.call, c.payload, result)
+	res, err := c.svc.send(ctx, c.call, c.payload, c.options())
 	if err != nil {
 		return c.returnError(err)
 	}
@@ -169,8 +242,7 @@ func (c *noResultCall[T]) DoOnNode(ctx context.Context, node int) (NoResultRespo
 		return c.returnError(c.fatal)
 	}
 
-	var result T
-	res, err := c.svc.call(ctx, c.call, c.payload, result, IndexNodePicker(node))
+	res, err := c.svc.send(ctx, c.call, c.payload, c.options(), IndexNodePicker(node))
 	if err != nil {
 		return c.returnError(err)
 	}
@@ -196,8 +268,7 @@ func (c *noResultCall[T]) DoOnNodes(ctx context.Context, nodes ...int) (NoResult
 		return c.returnError(c.fatal)
 	}
 
-	var result T
-	res, err := c.svc.call(ctx, c.call, c.payload, result, IndexNodePicker(nodes...))
+	res, err := c.svc.send(ctx, c.call, c.payload, c.options(), IndexNodePicker(nodes...))
 	if err != nil {
 		return c.returnError(err)
 	}
@@ -205,6 +276,15 @@ func (c *noResultCall[T]) DoOnNodes(ctx context.Context, nodes ...int) (NoResult
 	return c.returnResult(res)
 }
 
+func (c *noResultCall[T]) setIdempotent() {
+	c.idempotent = true
+}
+
+func (c *noResultCall[T]) options() RESTOptions {
+	var result T
+	return RESTOptions{result: result, idempotent: c.idempotent}
+}
+
 func (c *noResultCall[T]) returnError(err error) (NoResultResponse, error) {
 	return NoResultResponse{}, err
 }
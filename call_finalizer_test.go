@@ -0,0 +1,26 @@
+package gopxgrid
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListCallDecodesWrapper(t *testing.T) {
+	srv := newJSONServer(t, map[string]any{
+		"policies": []map[string]any{{"name": "quarantine", "actions": []string{"QUARANTINE"}}},
+	})
+	c := newTestConsumer(t, nil)
+	anc := &pxGridANC{pxGridService: *newTestService(c, "com.cisco.ise.config.anc", srv.URL)}
+
+	call := anc.GetPolicies()
+	// results are not shared between calls of a finalizer
+	for range 2 {
+		res, err := call.Do(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Result == nil || len(*res.Result) != 1 || (*res.Result)[0].Name != "quarantine" {
+			t.Fatalf("unexpected result %+v", res.Result)
+		}
+	}
+}
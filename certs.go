@@ -0,0 +1,248 @@
+package gopxgrid
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ClientIdentity is a client certificate with its chain and the CA
+// certificates shipped with it, e.g. in a PKCS#12 bundle downloaded from ISE
+type ClientIdentity struct {
+	// Certificate holds the leaf and the intermediate certificates
+	Certificate *tls.Certificate
+	Leaf        *x509.Certificate
+	// Chain are the intermediate certificates sent with the leaf
+	Chain []*x509.Certificate
+	// CA are the self-signed certificates of the bundle, nil if there are none
+	CA *x509.CertPool
+}
+
+var (
+	ErrCertificateExpired     = errors.New("certificate expired")
+	ErrCertificateNotYetValid = errors.New("certificate is not yet valid")
+	ErrNotClientCertificate   = errors.New("certificate is not valid for client authentication")
+	ErrKeyMismatch            = errors.New("private key does not match certificate")
+)
+
+// LoadX509KeyPair loads a client certificate chain and its unencrypted key from PEM files
+func LoadX509KeyPair(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	if err := VerifyClientCertificate(cert.Leaf, time.Now()); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// LoadCertPool loads the PEM certificates of all files in the folder, files
+// without certificates are skipped. Fails if no certificate was found
+func LoadCertPool(caFolder string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	files, err := os.ReadDir(caFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		bts, err := os.ReadFile(filepath.Join(caFolder, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if pool.AppendCertsFromPEM(bts) {
+			loaded++
+		}
+	}
+	if loaded == 0 {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caFolder)
+	}
+	return pool, nil
+}
+
+// LoadPKCS12 decodes the client identity of a password protected PKCS#12 (.p12, .pfx) file
+func LoadPKCS12(file, password string) (*ClientIdentity, error) {
+	bts, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePKCS12(bts, password)
+}
+
+// ParsePKCS12 decodes the client identity of a PKCS#12 bundle
+func ParsePKCS12(data []byte, password string) (*ClientIdentity, error) {
+	key, leaf, certs, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PKCS#12: %w", err)
+	}
+	return newClientIdentity(key, leaf, certs)
+}
+
+// LoadEncryptedKeyPair loads a client certificate chain and its key from PEM
+// files. The key is decrypted with the password if it is an encrypted PKCS#8
+// key or a legacy encrypted PEM key, unencrypted keys are accepted too
+func LoadEncryptedKeyPair(certFile, keyFile, password string) (*ClientIdentity, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEncryptedKeyPair(certPEM, keyPEM, []byte(password))
+}
+
+// ParseEncryptedKeyPair is LoadEncryptedKeyPair of PEM data, the first
+// certificate is the leaf
+func ParseEncryptedKeyPair(certPEM, keyPEM, password []byte) (*ClientIdentity, error) {
+	var certs []*x509.Certificate
+	for rest := certPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+
+	key, err := parsePEMKey(keyPEM, password)
+	if err != nil {
+		return nil, err
+	}
+	return newClientIdentity(key, certs[0], certs[1:])
+}
+
+func parsePEMKey(keyPEM, password []byte) (any, error) {
+	for rest := keyPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("no PEM private key found")
+		}
+
+		der := block.Bytes
+		switch {
+		case block.Type == "ENCRYPTED PRIVATE KEY":
+			key, err := pkcs8.ParsePKCS8PrivateKey(der, password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+			}
+			return key, nil
+		case block.Type != "PRIVATE KEY" && !strings.HasSuffix(block.Type, " PRIVATE KEY"):
+			continue
+		case x509.IsEncryptedPEMBlock(block):
+			// legacy "Proc-Type: 4,ENCRYPTED" keys are insecure but still issued
+			var err error
+			if der, err = x509.DecryptPEMBlock(block, password); err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+			}
+		}
+
+		if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(der); err == nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("failed to parse private key of PEM block %q", block.Type)
+	}
+}
+
+func newClientIdentity(key any, leaf *x509.Certificate, certs []*x509.Certificate) (*ClientIdentity, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(leaf.PublicKey) {
+		return nil, ErrKeyMismatch
+	}
+	if err := VerifyClientCertificate(leaf, time.Now()); err != nil {
+		return nil, err
+	}
+
+	id := &ClientIdentity{
+		Certificate: &tls.Certificate{
+			Certificate: [][]byte{leaf.Raw},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		},
+		Leaf: leaf,
+	}
+	for _, c := range certs {
+		if isSelfSigned(c) {
+			if id.CA == nil {
+				id.CA = x509.NewCertPool()
+			}
+			id.CA.AddCert(c)
+			continue
+		}
+		id.Chain = append(id.Chain, c)
+		id.Certificate.Certificate = append(id.Certificate.Certificate, c.Raw)
+	}
+	return id, nil
+}
+
+func isSelfSigned(c *x509.Certificate) bool {
+	return bytes.Equal(c.RawSubject, c.RawIssuer) && c.CheckSignatureFrom(c) == nil
+}
+
+// VerifyClientCertificate checks the validity period of the certificate and
+// that its key usages allow TLS client authentication
+func VerifyClientCertificate(cert *x509.Certificate, now time.Time) error {
+	if cert == nil {
+		return errors.New("no certificate")
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("%w: valid from %s", ErrCertificateNotYetValid, cert.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: valid until %s", ErrCertificateExpired, cert.NotAfter.Format(time.RFC3339))
+	}
+
+	if len(cert.ExtKeyUsage) > 0 &&
+		!slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth) &&
+		!slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		return fmt.Errorf("%w: extended key usage lacks clientAuth", ErrNotClientCertificate)
+	}
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("%w: key usage lacks digitalSignature", ErrNotClientCertificate)
+	}
+	return nil
+}
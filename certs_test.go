@@ -0,0 +1,214 @@
+package gopxgrid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// newClientCert returns a client certificate of the key signed by the parent,
+// modify adjusts the template before signing
+func newClientCert(t *testing.T, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, modify func(*x509.Certificate)) *x509.Certificate {
+	t.Helper()
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "pxgrid-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if modify != nil {
+		modify(tmpl)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// testChain is a leaf issued by an intermediate of a self-signed root
+type testChain struct {
+	root, intermediate, leaf *x509.Certificate
+	key                      *ecdsa.PrivateKey
+}
+
+func newTestChain(t *testing.T) testChain {
+	t.Helper()
+
+	root, rootKey := newTestCert(t, "root", nil, nil)
+	intermediate, intermediateKey := newTestCert(t, "intermediate", root, rootKey)
+	key := newECKey(t)
+	return testChain{
+		root:         root,
+		intermediate: intermediate,
+		leaf:         newClientCert(t, key, intermediate, intermediateKey, nil),
+		key:          key,
+	}
+}
+
+func certPEM(certs ...*x509.Certificate) []byte {
+	var res []byte
+	for _, c := range certs {
+		res = append(res, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return res
+}
+
+func TestParsePKCS12(t *testing.T) {
+	chain := newTestChain(t)
+	data, err := pkcs12.Modern.Encode(chain.key, chain.leaf, []*x509.Certificate{chain.intermediate, chain.root}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := ParsePKCS12(data, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Leaf.Equal(chain.leaf) {
+		t.Fatal("leaf is not the certificate of the key")
+	}
+	if len(id.Chain) != 1 || !id.Chain[0].Equal(chain.intermediate) {
+		t.Fatalf("chain = %d certificates, want the intermediate", len(id.Chain))
+	}
+	if len(id.Certificate.Certificate) != 2 {
+		t.Fatalf("TLS certificate has %d certificates, want the leaf and the intermediate", len(id.Certificate.Certificate))
+	}
+	want := x509.NewCertPool()
+	want.AddCert(chain.root)
+	if id.CA == nil || !id.CA.Equal(want) {
+		t.Fatal("CA does not hold the self-signed root")
+	}
+
+	if _, err := ParsePKCS12(data, "wrong"); !errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		t.Fatalf("ParsePKCS12() = %v, want ErrIncorrectPassword", err)
+	}
+}
+
+func TestParseEncryptedKeyPair(t *testing.T) {
+	chain := newTestChain(t)
+	password := []byte("secret")
+
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(chain.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPKCS8, err := pkcs8.MarshalPrivateKey(chain.key, password, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(chain.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", ecDER, password, x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDER, err := x509.MarshalPKCS8PrivateKey(newECKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, rootKey := newTestCert(t, "root", nil, nil)
+	expired := newClientCert(t, chain.key, root, rootKey, func(c *x509.Certificate) {
+		c.NotBefore, c.NotAfter = time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)
+	})
+
+	tests := []struct {
+		name      string
+		certs     []byte
+		key       []byte
+		password  []byte
+		wantChain int
+		wantErr   bool
+		wantIs    error
+	}{
+		{name: "unencrypted", certs: certPEM(chain.leaf), key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER})},
+		{name: "encrypted pkcs8", certs: certPEM(chain.leaf, chain.intermediate), key: pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedPKCS8}), password: password, wantChain: 1},
+		{name: "legacy proc-type", certs: certPEM(chain.leaf), key: pem.EncodeToMemory(legacy), password: password},
+		{name: "key after other blocks", certs: certPEM(chain.leaf), key: append(certPEM(chain.root), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})...)},
+		{name: "wrong password pkcs8", certs: certPEM(chain.leaf), key: pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedPKCS8}), password: []byte("wrong"), wantErr: true},
+		{name: "wrong password legacy", certs: certPEM(chain.leaf), key: pem.EncodeToMemory(legacy), password: []byte("wrong"), wantErr: true},
+		{name: "key mismatch", certs: certPEM(chain.leaf), key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER}), wantErr: true, wantIs: ErrKeyMismatch},
+		{name: "expired", certs: certPEM(expired), key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER}), wantErr: true, wantIs: ErrCertificateExpired},
+		{name: "no certificate", key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER}), wantErr: true},
+		{name: "no key", certs: certPEM(chain.leaf), key: certPEM(chain.root), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseEncryptedKeyPair(tt.certs, tt.key, tt.password)
+			if (err != nil) != tt.wantErr || (tt.wantIs != nil && !errors.Is(err, tt.wantIs)) {
+				t.Fatalf("ParseEncryptedKeyPair() = %v, wantErr %v %v", err, tt.wantErr, tt.wantIs)
+			}
+			if err != nil {
+				return
+			}
+			if !id.Leaf.Equal(chain.leaf) || len(id.Chain) != tt.wantChain {
+				t.Fatalf("leaf %s with %d chain certificates, want %d", id.Leaf.Subject, len(id.Chain), tt.wantChain)
+			}
+		})
+	}
+}
+
+func TestVerifyClientCertificate(t *testing.T) {
+	root, rootKey := newTestCert(t, "root", nil, nil)
+	key := newECKey(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		modify  func(*x509.Certificate)
+		wantErr error
+	}{
+		{name: "client auth"},
+		{name: "no key usages", modify: func(c *x509.Certificate) { c.KeyUsage, c.ExtKeyUsage = 0, nil }},
+		{name: "any usage", modify: func(c *x509.Certificate) { c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageAny} }},
+		{name: "client and server auth", modify: func(c *x509.Certificate) {
+			c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		}},
+		{name: "server auth only", modify: func(c *x509.Certificate) { c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth} }, wantErr: ErrNotClientCertificate},
+		{name: "no digital signature", modify: func(c *x509.Certificate) { c.KeyUsage = x509.KeyUsageKeyEncipherment }, wantErr: ErrNotClientCertificate},
+		{name: "expired", modify: func(c *x509.Certificate) { c.NotBefore, c.NotAfter = now.Add(-2*time.Hour), now.Add(-time.Minute) }, wantErr: ErrCertificateExpired},
+		{name: "not yet valid", modify: func(c *x509.Certificate) { c.NotBefore = now.Add(time.Minute) }, wantErr: ErrCertificateNotYetValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := newClientCert(t, key, root, rootKey, tt.modify)
+			if err := VerifyClientCertificate(cert, now); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyClientCertificate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if VerifyClientCertificate(nil, now) == nil {
+		t.Fatal("VerifyClientCertificate(nil) = nil")
+	}
+}
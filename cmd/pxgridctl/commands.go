@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"net/netip"
+	"time"
+
+	gopxgrid "github.com/vkumov/go-pxgrid"
+)
+
+const activatePollInterval = 30 * time.Second
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	return nil
+}
+
+// printStream writes the records as they are decoded, JSON records are written one per line
+func printStream[T any](a *app, records iter.Seq2[T, error]) error {
+	first := true
+	for r, err := range records {
+		if err != nil {
+			return err
+		}
+		if err := a.out.printOne(r, first); err != nil {
+			return err
+		}
+		first = false
+	}
+	return nil
+}
+
+// ensureActive activates the account, service calls fail until it is enabled
+func (a *app) ensureActive(ctx context.Context) error {
+	res, err := a.consumer.Control().AccountActivate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to activate account: %w", err)
+	}
+	if !res.IsEnabled() {
+		return fmt.Errorf("account is %s, approve it in ISE or run activate -wait", res.AccountState)
+	}
+	return nil
+}
+
+func runActivate(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("activate")
+	wait := fs.Bool("wait", false, "Wait until the account is approved")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	for {
+		res, err := a.consumer.Control().AccountActivate(ctx)
+		if err != nil {
+			return err
+		}
+		if res.IsEnabled() || !res.IsPending() || !*wait {
+			return a.out.print(res)
+		}
+
+		a.logger.Info("Account is pending approval, waiting", "interval", activatePollInterval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(activatePollInterval):
+		}
+	}
+}
+
+func runLookup(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	if err := a.ensureActive(ctx); err != nil {
+		return err
+	}
+
+	res, err := a.consumer.Control().ServiceLookup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return a.out.print(res.Services)
+}
+
+func runSessions(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	rest := a.consumer.SessionDirectory().Rest()
+
+	switch args[0] {
+	case "get":
+		fs := newFlagSet("sessions get")
+		since := fs.String("since", "", "Only sessions changed since the duration ago or the timestamp")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		start, err := parseSince(*since)
+		if err != nil {
+			return err
+		}
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+
+		return printStream(a, rest.StreamSessionsSince(start, nil).Do(ctx))
+	case "by-ip", "by-mac":
+		if len(args) != 2 {
+			return errUsage
+		}
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+
+		call := rest.GetSessionByIPAddress
+		if args[0] == "by-mac" {
+			call = rest.GetSessionByMacAddress
+		}
+		res, err := call(args[1]).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Result == nil {
+			return fmt.Errorf("no session found for %s", args[1])
+		}
+		return a.out.print(res.Result)
+	}
+	return errUsage
+}
+
+func runANC(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	rest := a.consumer.ANCConfig().Rest()
+
+	switch args[0] {
+	case "policies":
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+		res, err := rest.GetPolicies().Do(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Result)
+	case "apply", "clear":
+		if len(args) != 3 {
+			return errUsage
+		}
+		endpoint, policy := args[1], args[2]
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+
+		_, ipErr := netip.ParseAddr(endpoint)
+		var call gopxgrid.CallFinalizer[*gopxgrid.ANCOperationStatus]
+		switch {
+		case args[0] == "apply" && ipErr == nil:
+			call = rest.ApplyEndpointByIPAddress(endpoint, policy)
+		case args[0] == "apply":
+			call = rest.ApplyEndpointByMACAddress(endpoint, policy)
+		case ipErr == nil:
+			call = rest.ClearEndpointByIPAddress(endpoint, policy)
+		default:
+			call = rest.ClearEndpointByMACAddress(endpoint, policy)
+		}
+
+		res, err := call.Do(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Result)
+	case "status":
+		if len(args) != 2 {
+			return errUsage
+		}
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+		res, err := rest.GetOperationStatus(args[1]).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Result == nil {
+			return fmt.Errorf("operation %s not found", args[1])
+		}
+		return a.out.print(res.Result)
+	}
+	return errUsage
+}
+
+func collectRecords[T any](ctx context.Context, call gopxgrid.IterCallFinalizer[gopxgrid.TrustSecRecord[T]]) ([]T, error) {
+	var records []T
+	for rec, err := range call.Do(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		if !rec.Deleted {
+			records = append(records, rec.Record)
+		}
+	}
+	return records, nil
+}
+
+func runTrustSec(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	fs := newFlagSet("trustsec " + args[0])
+	pageSize := fs.Int("page-size", gopxgrid.DefaultTrustSecPageSize, "Records fetched per request")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	rest := a.consumer.TrustSecConfiguration().Rest()
+
+	var (
+		records any
+		err     error
+	)
+	switch args[0] {
+	case "sgs":
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+		records, err = collectRecords(ctx, rest.IterSecurityGroups(*pageSize))
+	case "sgacls":
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+		records, err = collectRecords(ctx, rest.IterSecurityGroupACLs(*pageSize))
+	case "egress":
+		if err := a.ensureActive(ctx); err != nil {
+			return err
+		}
+		records, err = collectRecords(ctx, rest.IterEgressPolicies(*pageSize))
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	return a.out.print(records)
+}
+
+func runSXP(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 || args[0] != "bindings" {
+		return errUsage
+	}
+
+	fs := newFlagSet("sxp bindings")
+	vpn := fs.String("vpn", "", "Only bindings of the VPN")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if err := a.ensureActive(ctx); err != nil {
+		return err
+	}
+
+	var filter any
+	if *vpn != "" {
+		filter = &gopxgrid.SXPBindingFilter{VPN: *vpn}
+	}
+
+	return printStream(a, a.consumer.TrustSecSXP().Rest().StreamBindings(filter).Do(ctx))
+}
+
+func runHealth(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("health")
+	node := fs.String("node", "", "ISE node name, all nodes if empty")
+	since := fs.String("since", "1h", "Only samples taken since the duration ago or the timestamp")
+	perf := fs.Bool("perf", false, "Show performance instead of health samples")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	start, err := parseSince(*since)
+	if err != nil {
+		return err
+	}
+	if err := a.ensureActive(ctx); err != nil {
+		return err
+	}
+
+	rest := a.consumer.SystemHealth().Rest()
+	if *perf {
+		res, err := rest.GetPerformancesSince(*node, start).Do(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Result)
+	}
+
+	res, err := rest.GetHealthsSince(*node, start).Do(ctx)
+	if err != nil {
+		return err
+	}
+	return a.out.print(res.Result)
+}
+
+func runMDM(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("mdm")
+	mac := fs.String("mac", "", "Only the endpoint with the MAC address")
+	endpointType := fs.String("type", "", "Only endpoints of the type: NON_COMPLIANT, REGISTERED or DISCONNECTED")
+	osType := fs.String("os", "", "Only endpoints of the OS type: ANDROID, IOS or WINDOWS")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := a.ensureActive(ctx); err != nil {
+		return err
+	}
+
+	rest := a.consumer.MDM().Rest()
+	switch {
+	case *mac != "":
+		res, err := rest.GetEndpointByMacAddress(*mac).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Result == nil {
+			return fmt.Errorf("no MDM endpoint found for %s", *mac)
+		}
+		return a.out.print(res.Result)
+	case *endpointType != "":
+		res, err := rest.GetEndpointsByType(gopxgrid.MDMEndpointType(*endpointType)).Do(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Result)
+	case *osType != "":
+		res, err := rest.GetEndpointsByOsType(gopxgrid.MDMOSType(*osType)).Do(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Result)
+	}
+
+	res, err := rest.GetEndpoints(nil).Do(ctx)
+	if err != nil {
+		return err
+	}
+	return a.out.print(res.Result)
+}
+
+func runSubscribe(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	if err := a.ensureActive(ctx); err != nil {
+		return err
+	}
+
+	sub, err := a.consumer.Service(args[0]).On(args[1]).Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			a.logger.Warn("Failed to unsubscribe", "err", err)
+		}
+	}()
+
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case msg, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if msg.Err != nil {
+				a.logger.Error("Failed to read message", "err", msg.Err)
+				continue
+			}
+			if msg.UnmarshalError != nil {
+				a.logger.Error("Failed to unmarshal message", "err", msg.UnmarshalError)
+				continue
+			}
+			if err := a.out.printOne(msg.Body, first); err != nil {
+				return err
+			}
+			first = false
+		}
+	}
+}
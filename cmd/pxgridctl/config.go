@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	gopxgrid "github.com/vkumov/go-pxgrid"
+)
+
+type (
+	stringList []string
+
+	globalFlags struct {
+		configFile  string
+		hosts       stringList
+		pins        stringList
+		port        int
+		nodeName    string
+		description string
+		certFile    string
+		keyFile     string
+		keyPassword string
+		pkcs12File  string
+		password    string
+		caFolder    string
+		dns         string
+		dnsStrategy string
+		srv         string
+		proxy       string
+		insecure    bool
+		output      string
+		timeout     time.Duration
+		verbose     bool
+	}
+)
+
+func (h *stringList) String() string {
+	return strings.Join(*h, ",")
+}
+
+func (h *stringList) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.configFile, "config", "", "YAML or JSON configuration file, replaces the connection flags (optional)")
+	fs.Var(&g.hosts, "host", "pxGrid host name (multiple accepted)")
+	fs.IntVar(&g.port, "port", 8910, "Control port")
+	fs.StringVar(&g.nodeName, "n", "", "Node name")
+	fs.StringVar(&g.description, "d", "", "Description (optional)")
+	fs.StringVar(&g.certFile, "c", "", "Client certificate chain .pem filename (not required if password is specified)")
+	fs.StringVar(&g.keyFile, "k", "", "Client key .key filename (not required if password is specified)")
+	fs.StringVar(&g.keyPassword, "kp", "", "Password of an encrypted client key or of the PKCS#12 bundle (optional)")
+	fs.StringVar(&g.pkcs12File, "p12", "", "Client certificate PKCS#12 bundle filename, replaces -c and -k")
+	fs.StringVar(&g.password, "w", "", "Password (not required if client certificate is specified)")
+	fs.StringVar(&g.caFolder, "s", "", "Folder with CA certificates (optional)")
+	fs.StringVar(&g.dns, "dns", "", "DNS server (optional)")
+	fs.StringVar(&g.dnsStrategy, "dns-strategy", "46", "Address family strategy: 4, 46, 64 or 6")
+	fs.StringVar(&g.srv, "srv", "", "SRV record of the control hosts, e.g. _pxgrid._tcp.example.com (optional)")
+	fs.StringVar(&g.proxy, "proxy", "", "Proxy URL, http://host:port or socks5://host:port, HTTPS_PROXY is used if empty (optional)")
+	fs.BoolVar(&g.insecure, "insecure", false, "Insecure skip validation, pins are still checked")
+	fs.Var(&g.pins, "pin", "SPKI SHA-256 pin of the node public keys, sha256/<base64> (multiple accepted)")
+	fs.StringVar(&g.output, "o", "table", "Output format: json, ndjson, table or csv, streamed records are written as they arrive, one JSON record per line")
+	fs.DurationVar(&g.timeout, "timeout", 30*time.Second, "Timeout of a single command, subscribe is not limited")
+	fs.BoolVar(&g.verbose, "v", false, "Verbose logging")
+}
+
+func (g *globalFlags) config(logger *slog.Logger) (*gopxgrid.PxGridConfig, error) {
+	if g.configFile != "" {
+		c, err := gopxgrid.LoadConfig(g.configFile)
+		if err != nil {
+			return nil, err
+		}
+		return c.SetLogger(gopxgrid.FromSlog(logger)), nil
+	}
+
+	if len(g.hosts) == 0 && g.srv == "" {
+		return nil, errors.New("at least one -host or -srv is required")
+	}
+	if g.nodeName == "" {
+		return nil, errors.New("node name -n is required")
+	}
+
+	c := gopxgrid.NewPxGridConfig().
+		SetNodeName(g.nodeName).
+		SetDescription(g.description).
+		SetInsecureTLS(g.insecure).
+		SetSRV(g.srv).
+		SetLogger(gopxgrid.FromSlog(logger))
+	for _, h := range g.hosts {
+		c.AddHost(h, g.port)
+	}
+	if g.proxy != "" {
+		c.SetProxy(g.proxy)
+	}
+	for _, pin := range g.pins {
+		c.AddPin(pin)
+	}
+	c.Auth.Username = g.nodeName
+
+	// the strategy orders the addresses of the system resolver too
+	strategy, err := gopxgrid.ParseINETFamilyStrategy(g.dnsStrategy)
+	if err != nil {
+		return nil, err
+	}
+	c.SetDNS(g.dns, strategy)
+
+	switch {
+	case g.pkcs12File != "":
+		id, err := gopxgrid.LoadPKCS12(g.pkcs12File, g.keyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		c.SetClientCertificate(id.Certificate).SetCA(id.CA)
+	case g.certFile != "" && g.keyFile != "":
+		id, err := gopxgrid.LoadEncryptedKeyPair(g.certFile, g.keyFile, g.keyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		c.SetClientCertificate(id.Certificate).SetCA(id.CA)
+	case g.password != "":
+		c.Auth.Password = g.password
+	default:
+		return nil, errors.New("client certificate (-c, -k or -p12) or password (-w) is required")
+	}
+
+	if g.caFolder != "" {
+		pool, err := gopxgrid.LoadCertPool(g.caFolder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA pool: %w", err)
+		}
+		c.SetCA(pool)
+	}
+
+	return c, nil
+}
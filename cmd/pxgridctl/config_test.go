@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"iter"
+	"log/slog"
+	"testing"
+
+	gopxgrid "github.com/vkumov/go-pxgrid"
+)
+
+func TestGlobalFlagsDNS(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantServer string
+		want       gopxgrid.INETFamilyStrategy
+		wantErr    bool
+	}{
+		{name: "default", want: gopxgrid.IPv46},
+		{name: "strategy without server", args: []string{"-dns-strategy", "6"}, want: gopxgrid.IPv6},
+		{name: "server and strategy", args: []string{"-dns", "10.0.0.53", "-dns-strategy", "64"}, wantServer: "10.0.0.53", want: gopxgrid.IPv64},
+		{name: "bad strategy", args: []string{"-dns-strategy", "5"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g globalFlags
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			g.register(fs)
+			if err := fs.Parse(append([]string{"-host", "ise", "-n", "node", "-w", "secret"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := g.config(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("config() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.DNS.Server != tt.wantServer || cfg.DNS.FamilyStrategy != tt.want {
+				t.Fatalf("DNS = %+v, want server %q and strategy %v", cfg.DNS, tt.wantServer, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintStream(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+	records := func(fail bool) iter.Seq2[record, error] {
+		return func(yield func(record, error) bool) {
+			if !yield(record{Name: "a"}, nil) || !yield(record{Name: "b"}, nil) {
+				return
+			}
+			if fail {
+				yield(record{}, errors.New("stream broken"))
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		format  outputFormat
+		fail    bool
+		want    string
+		wantErr bool
+	}{
+		{name: "json", format: outputJSON, want: "{\"name\":\"a\"}\n{\"name\":\"b\"}\n"},
+		{name: "csv", format: outputCSV, want: "name\na\nb\n"},
+		{name: "records before the error are written", format: outputNDJSON, fail: true, want: "{\"name\":\"a\"}\n{\"name\":\"b\"}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			a := &app{out: &printer{w: &buf, format: tt.format}}
+			err := printStream(a, records(tt.fail))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("printStream() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Fatalf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Command pxgridctl queries and subscribes to Cisco ISE pxGrid services.
+//
+// Usage:
+//
+//	pxgridctl [global flags] <command> [command flags] [arguments]
+//
+// Run pxgridctl -h for the list of global flags and commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	gopxgrid "github.com/vkumov/go-pxgrid"
+)
+
+type (
+	app struct {
+		consumer *gopxgrid.PxGridConsumer
+		out      *printer
+		logger   *slog.Logger
+	}
+
+	command struct {
+		usage string
+		// stream commands are not limited by the global timeout
+		stream bool
+		run    func(ctx context.Context, a *app, args []string) error
+	}
+)
+
+var errUsage = errors.New("invalid usage")
+
+var commands = map[string]command{
+	"activate":  {usage: "activate [-wait]", run: runActivate},
+	"lookup":    {usage: "lookup <service>", run: runLookup},
+	"sessions":  {usage: "sessions get [-since <duration|timestamp>] | by-ip <ip> | by-mac <mac>", run: runSessions},
+	"anc":       {usage: "anc policies | apply <mac|ip> <policy> | clear <mac|ip> <policy> | status <operationId>", run: runANC},
+	"trustsec":  {usage: "trustsec sgs | sgacls | egress [-page-size <n>]", run: runTrustSec},
+	"sxp":       {usage: "sxp bindings [-vpn <name>]", run: runSXP},
+	"health":    {usage: "health [-node <name>] [-since <duration|timestamp>] [-perf]", run: runHealth},
+	"mdm":       {usage: "mdm [-mac <mac> | -type <type> | -os <os>]", run: runMDM},
+	"subscribe": {usage: "subscribe <service> <topic property>", stream: true, run: runSubscribe},
+}
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: %s [global flags] <command> [command flags] [arguments]\n\nCommands:\n", fs.Name())
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s\n", commands[name].usage)
+		}
+		fmt.Fprintln(w, "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pxgridctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = usage(fs)
+
+	var g globalFlags
+	g.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	format, err := parseOutputFormat(g.output)
+	if err != nil {
+		return err
+	}
+
+	level := slog.LevelWarn
+	if g.verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	cfg, err := g.config(logger)
+	if err != nil {
+		return err
+	}
+	consumer, err := gopxgrid.NewPxGridConsumer(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if !cmd.stream && g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	a := &app{
+		consumer: consumer,
+		out:      &printer{w: stdout, format: format},
+		logger:   logger,
+	}
+	err = cmd.run(ctx, a, fs.Args()[1:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(stderr, "Usage: pxgridctl [global flags] %s\n", cmd.usage)
+	}
+	return err
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) && !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// parseSince accepts a duration relative to now or an RFC 3339 timestamp
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := gopxgrid.ParsePxGridTime(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration nor a timestamp", s)
+	}
+	return t.Time, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+type outputFormat string
+
+const (
+	outputJSON   outputFormat = "json"
+	outputNDJSON outputFormat = "ndjson"
+	outputTable  outputFormat = "table"
+	outputCSV    outputFormat = "csv"
+)
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch f := outputFormat(s); f {
+	case outputJSON, outputNDJSON, outputTable, outputCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q", s)
+}
+
+// printer writes records in the selected format. Records are structs, pointers
+// to structs or maps; slices passed to print are written record by record.
+type printer struct {
+	w      io.Writer
+	format outputFormat
+}
+
+func (p *printer) print(v any) error {
+	rows := toRows(v)
+	switch p.format {
+	case outputJSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			return enc.Encode(rows)
+		}
+		return enc.Encode(v)
+	case outputNDJSON:
+		enc := json.NewEncoder(p.w)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	columns := columnsOf(rows)
+	cells := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		cells = append(cells, cellsOf(r, columns))
+	}
+
+	if p.format == outputCSV {
+		w := csv.NewWriter(p.w)
+		w.Write(columns)
+		w.WriteAll(cells)
+		return w.Error()
+	}
+
+	w := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range cells {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// printOne writes a single record of a stream, table and CSV headers are written with the first record
+func (p *printer) printOne(v any, first bool) error {
+	switch p.format {
+	case outputJSON, outputNDJSON:
+		return json.NewEncoder(p.w).Encode(v)
+	}
+
+	columns := columnsOf([]any{v})
+	row := cellsOf(v, columns)
+	if p.format == outputCSV {
+		w := csv.NewWriter(p.w)
+		if first {
+			w.Write(columns)
+		}
+		w.Write(row)
+		w.Flush()
+		return w.Error()
+	}
+
+	if first {
+		fmt.Fprintln(p.w, strings.ToUpper(strings.Join(columns, "\t")))
+	}
+	_, err := fmt.Fprintln(p.w, strings.Join(row, "\t"))
+	return err
+}
+
+func toRows(v any) []any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() && (rv.Elem().Kind() == reflect.Slice || rv.Elem().Kind() == reflect.Array) {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{v}
+	}
+
+	rows := make([]any, rv.Len())
+	for i := range rows {
+		rows[i] = rv.Index(i).Interface()
+	}
+	return rows
+}
+
+func structOf(v any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}, false
+		}
+		rv = rv.Elem()
+	}
+	return rv, rv.Kind() == reflect.Struct
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// columnsOf returns the JSON field names of struct records in declaration order
+// or the sorted keys of map records
+func columnsOf(rows []any) []string {
+	var columns []string
+	seen := make(map[string]struct{})
+	add := func(c string) {
+		if _, ok := seen[c]; !ok {
+			seen[c] = struct{}{}
+			columns = append(columns, c)
+		}
+	}
+
+	for _, r := range rows {
+		if rv, ok := structOf(r); ok {
+			for i := range rv.NumField() {
+				if name, ok := jsonName(rv.Type().Field(i)); ok {
+					add(name)
+				}
+			}
+			continue
+		}
+
+		if m, ok := r.(map[string]any); ok {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				add(k)
+			}
+			continue
+		}
+
+		add("value")
+	}
+	return columns
+}
+
+func cellsOf(r any, columns []string) []string {
+	values := make(map[string]any, len(columns))
+	if rv, ok := structOf(r); ok {
+		for i := range rv.NumField() {
+			if name, ok := jsonName(rv.Type().Field(i)); ok {
+				values[name] = rv.Field(i).Interface()
+			}
+		}
+	} else if m, ok := r.(map[string]any); ok {
+		values = m
+	} else {
+		values["value"] = r
+	}
+
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		cells[i] = cell(values[c])
+	}
+	return cells
+}
+
+func cell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var s string
+	if json.Unmarshal(bts, &s) == nil {
+		return s
+	}
+	if string(bts) == "null" {
+		return ""
+	}
+	return string(bts)
+}
@@ -3,6 +3,9 @@ package gopxgrid
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type INETFamilyStrategy int
@@ -20,6 +23,16 @@ var DefaultINETFamilyStrategy = IPv46
 type DNSConfig struct {
 	Server         string
 	FamilyStrategy INETFamilyStrategy
+	// Cache caches lookups for the TTL of the records, DefaultDNSCacheTTL is
+	// used without Server as the system resolver does not expose TTLs
+	Cache bool
+	// CacheMaxTTL caps the TTL of cached records if set
+	CacheMaxTTL time.Duration
+	// NegativeTTL is the TTL of failed lookups without an SOA record, DefaultDNSNegativeTTL if 0
+	NegativeTTL time.Duration
+	// SRV is the SRV record of the control hosts, e.g. _pxgrid._tcp.example.com.
+	// The discovered hosts are used before Hosts, which is optional then
+	SRV string
 }
 
 type Host struct {
@@ -34,8 +47,24 @@ type AuthConfig struct {
 
 type TLSConfig struct {
 	ClientCertificate *tls.Certificate
-	InsecureTLS       bool
-	CA                *x509.CertPool
+	// InsecureTLS skips the CA validation, pins are still enforced
+	InsecureTLS bool
+	CA          *x509.CertPool
+	// Pins are the SPKI SHA-256 pins of the node public keys, see ParseSPKIPin.
+	// A node is accepted if one of its certificates matches a pin
+	Pins []string
+	// HostPins replace Pins for the host names
+	HostPins map[string][]string
+}
+
+// RetryConfig configures retries of REST requests failed with a transport error
+// or with a 429, 502, 503 or 504 status, requests are not retried if Count is 0.
+// Only idempotent calls are retried: the queries of the services, ServiceLookup and
+// calls of AnyRESTWithMethod with an idempotent HTTP method
+type RetryConfig struct {
+	Count       int
+	WaitTime    time.Duration
+	MaxWaitTime time.Duration
 }
 
 type PxGridConfig struct {
@@ -45,7 +74,19 @@ type PxGridConfig struct {
 	Description string
 	TLS         TLSConfig
 	DNS         DNSConfig
+	Proxy       ProxyConfig
 	Logger      Logger
+	Retry       RetryConfig
+	// Timeout limits every REST request and websocket handshake. Streamed
+	// responses are limited until the headers are received and then by the
+	// time between reads of the body. No limit if 0
+	Timeout time.Duration
+
+	Interceptors []Interceptor
+	Observers    []Observer
+	RateLimits   RateLimitConfig
+	// TracerProvider enables tracing of control, REST and pubsub calls, tracing is off if nil
+	TracerProvider trace.TracerProvider
 }
 
 func NewPxGridConfig() *PxGridConfig {
@@ -102,8 +143,98 @@ func (c *PxGridConfig) SetCA(ca *x509.CertPool) *PxGridConfig {
 	return c
 }
 
+// AddPin pins the public key of all hosts without host pins
+func (c *PxGridConfig) AddPin(pin string) *PxGridConfig {
+	c.TLS.Pins = append(c.TLS.Pins, pin)
+	return c
+}
+
+// AddHostPin pins the public key of the host, Pins are not used for the host then
+func (c *PxGridConfig) AddHostPin(host, pin string) *PxGridConfig {
+	if c.TLS.HostPins == nil {
+		c.TLS.HostPins = make(map[string][]string)
+	}
+	c.TLS.HostPins[host] = append(c.TLS.HostPins[host], pin)
+	return c
+}
+
 func (c *PxGridConfig) SetDNS(server string, family INETFamilyStrategy) *PxGridConfig {
 	c.DNS.Server = server
 	c.DNS.FamilyStrategy = family
 	return c
 }
+
+// SetRetry enables retries of failed idempotent REST requests, the wait time grows from wait up to maxWait
+func (c *PxGridConfig) SetRetry(count int, wait, maxWait time.Duration) *PxGridConfig {
+	c.Retry = RetryConfig{
+		Count:       count,
+		WaitTime:    wait,
+		MaxWaitTime: maxWait,
+	}
+	return c
+}
+
+// SetTimeout limits REST requests and websocket handshakes
+func (c *PxGridConfig) SetTimeout(timeout time.Duration) *PxGridConfig {
+	c.Timeout = timeout
+	return c
+}
+
+// SetDNSCache enables caching of DNS lookups, maxTTL caps the TTL of records if set
+func (c *PxGridConfig) SetDNSCache(maxTTL, negativeTTL time.Duration) *PxGridConfig {
+	c.DNS.Cache = true
+	c.DNS.CacheMaxTTL = maxTTL
+	c.DNS.NegativeTTL = negativeTTL
+	return c
+}
+
+// SetSRV discovers the control hosts through the SRV record, e.g. _pxgrid._tcp.example.com
+func (c *PxGridConfig) SetSRV(name string) *PxGridConfig {
+	c.DNS.SRV = name
+	return c
+}
+
+// SetProxy connects through the proxy except to the bypassed hosts, see ProxyConfig
+func (c *PxGridConfig) SetProxy(proxyURL string, bypass ...string) *PxGridConfig {
+	c.Proxy = ProxyConfig{
+		URL:    proxyURL,
+		Bypass: bypass,
+	}
+	return c
+}
+
+// SetTracerProvider enables tracing with the tracer provider
+func (c *PxGridConfig) SetTracerProvider(tp trace.TracerProvider) *PxGridConfig {
+	c.TracerProvider = tp
+	return c
+}
+
+// AddObserver registers an observer of websocket connections and received messages
+func (c *PxGridConfig) AddObserver(observer Observer) *PxGridConfig {
+	c.Observers = append(c.Observers, observer)
+	return c
+}
+
+// AddInterceptor appends an interceptor to the chain wrapping every REST call
+func (c *PxGridConfig) AddInterceptor(interceptor Interceptor) *PxGridConfig {
+	c.Interceptors = append(c.Interceptors, interceptor)
+	return c
+}
+
+// SetServiceRateLimit limits the rate of REST calls to a service, e.g. SessionDirectoryServiceName
+func (c *PxGridConfig) SetServiceRateLimit(service string, rate float64, burst int) *PxGridConfig {
+	if c.RateLimits.Services == nil {
+		c.RateLimits.Services = make(map[string]RateLimit)
+	}
+	c.RateLimits.Services[service] = RateLimit{Rate: rate, Burst: burst}
+	return c
+}
+
+// SetNodeRateLimit limits the rate of REST calls to a pxGrid node
+func (c *PxGridConfig) SetNodeRateLimit(nodeName string, rate float64, burst int) *PxGridConfig {
+	if c.RateLimits.Nodes == nil {
+		c.RateLimits.Nodes = make(map[string]RateLimit)
+	}
+	c.RateLimits.Nodes[nodeName] = RateLimit{Rate: rate, Burst: burst}
+	return c
+}
@@ -0,0 +1,511 @@
+package gopxgrid
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultControlPort is the port of the pxGrid control service
+const DefaultControlPort = 8910
+
+type (
+	// ConfigFile is the file representation of PxGridConfig, durations are
+	// strings parsed with time.ParseDuration, e.g. "30s"
+	ConfigFile struct {
+		Hosts       []ConfigFileHost `json:"hosts" yaml:"hosts"`
+		NodeName    string           `json:"nodeName" yaml:"nodeName"`
+		Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+		Password    string           `json:"password,omitempty" yaml:"password,omitempty"`
+		TLS         ConfigFileTLS    `json:"tls" yaml:"tls"`
+		DNS         ConfigFileDNS    `json:"dns" yaml:"dns"`
+		Proxy       ConfigFileProxy  `json:"proxy" yaml:"proxy"`
+		Retry       ConfigFileRetry  `json:"retry" yaml:"retry"`
+		Timeout     string           `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	}
+
+	ConfigFileHost struct {
+		Host string `json:"host" yaml:"host"`
+		Port int    `json:"port,omitempty" yaml:"port,omitempty"`
+		// Pins replace tls.pins for the host
+		Pins []string `json:"pins,omitempty" yaml:"pins,omitempty"`
+	}
+
+	ConfigFileTLS struct {
+		// Certificate is the client certificate chain PEM file
+		Certificate string `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+		// Key is the PEM key file of the client certificate, encrypted keys are
+		// decrypted with KeyPassword
+		Key string `json:"key,omitempty" yaml:"key,omitempty"`
+		// PKCS12 is the client identity bundle file, replaces Certificate and
+		// Key. The bundle is decrypted with KeyPassword
+		PKCS12      string `json:"pkcs12,omitempty" yaml:"pkcs12,omitempty"`
+		KeyPassword string `json:"keyPassword,omitempty" yaml:"keyPassword,omitempty"`
+		// CAFolder is the folder with the PEM CA certificates, the CA
+		// certificates of the client identity or the system CAs are used if empty
+		CAFolder string `json:"caFolder,omitempty" yaml:"caFolder,omitempty"`
+		Insecure bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+		// Pins are the SPKI SHA-256 pins of the node public keys
+		Pins []string `json:"pins,omitempty" yaml:"pins,omitempty"`
+	}
+
+	ConfigFileDNS struct {
+		Server string `json:"server,omitempty" yaml:"server,omitempty"`
+		// Family is one of 4, 46, 64 or 6, see ParseINETFamilyStrategy
+		Family      string `json:"family,omitempty" yaml:"family,omitempty"`
+		Cache       bool   `json:"cache,omitempty" yaml:"cache,omitempty"`
+		CacheMaxTTL string `json:"cacheMaxTTL,omitempty" yaml:"cacheMaxTTL,omitempty"`
+		NegativeTTL string `json:"negativeTTL,omitempty" yaml:"negativeTTL,omitempty"`
+		// SRV is the SRV record of the control hosts, hosts are optional then
+		SRV string `json:"srv,omitempty" yaml:"srv,omitempty"`
+	}
+
+	// ConfigFileProxy is ProxyConfig, the environment proxy is used if URL is empty
+	ConfigFileProxy struct {
+		URL    string   `json:"url,omitempty" yaml:"url,omitempty"`
+		Bypass []string `json:"bypass,omitempty" yaml:"bypass,omitempty"`
+		Direct bool     `json:"direct,omitempty" yaml:"direct,omitempty"`
+	}
+
+	ConfigFileRetry struct {
+		Count   int    `json:"count,omitempty" yaml:"count,omitempty"`
+		Wait    string `json:"wait,omitempty" yaml:"wait,omitempty"`
+		MaxWait string `json:"maxWait,omitempty" yaml:"maxWait,omitempty"`
+	}
+
+	// ConfigFieldError is an invalid value of a configuration field. Field is
+	// the path in the file, e.g. hosts[1].port, or the environment variable
+	ConfigFieldError struct {
+		Field string
+		Err   error
+	}
+)
+
+// Environment variables overriding the values of a configuration file
+const (
+	EnvHosts          = "PXGRID_HOSTS" // comma separated host[:port] list
+	EnvNodeName       = "PXGRID_NODE_NAME"
+	EnvDescription    = "PXGRID_DESCRIPTION"
+	EnvPassword       = "PXGRID_PASSWORD"
+	EnvTLSCertificate = "PXGRID_TLS_CERTIFICATE"
+	EnvTLSKey         = "PXGRID_TLS_KEY"
+	EnvTLSPKCS12      = "PXGRID_TLS_PKCS12"
+	EnvTLSKeyPassword = "PXGRID_TLS_KEY_PASSWORD"
+	EnvTLSCAFolder    = "PXGRID_TLS_CA_FOLDER"
+	EnvTLSInsecure    = "PXGRID_TLS_INSECURE"
+	EnvTLSPins        = "PXGRID_TLS_PINS" // comma separated
+	EnvDNSServer      = "PXGRID_DNS_SERVER"
+	EnvDNSFamily      = "PXGRID_DNS_FAMILY"
+	EnvDNSCache       = "PXGRID_DNS_CACHE"
+	EnvDNSSRV         = "PXGRID_DNS_SRV"
+	EnvProxyURL       = "PXGRID_PROXY_URL"
+	EnvProxyBypass    = "PXGRID_PROXY_BYPASS" // comma separated
+	EnvRetryCount     = "PXGRID_RETRY_COUNT"
+	EnvRetryWait      = "PXGRID_RETRY_WAIT"
+	EnvRetryMaxWait   = "PXGRID_RETRY_MAX_WAIT"
+	EnvTimeout        = "PXGRID_TIMEOUT"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+func (e *ConfigFieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *ConfigFieldError) Unwrap() error {
+	return e.Err
+}
+
+func fieldError(field string, format string, args ...any) error {
+	return &ConfigFieldError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// ParseINETFamilyStrategy parses 4, 46, 64 or 6, an optional "ipv" prefix is accepted
+func ParseINETFamilyStrategy(s string) (INETFamilyStrategy, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), "ipv") {
+	case "4":
+		return IPv4, nil
+	case "46":
+		return IPv46, nil
+	case "64":
+		return IPv64, nil
+	case "6":
+		return IPv6, nil
+	}
+	return IPUnknown, fmt.Errorf("unknown address family strategy %q", s)
+}
+
+// LoadConfig reads the YAML (.yaml, .yml) or JSON (.json) configuration file,
+// applies the PXGRID_* environment overrides and builds a validated config.
+// Only the environment is used if path is empty.
+//
+// The returned error joins a ConfigFieldError for every invalid field.
+func LoadConfig(path string) (*PxGridConfig, error) {
+	var f ConfigFile
+	if path != "" {
+		if err := readConfigFile(path, &f); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return f.Config()
+}
+
+func readConfigFile(path string, f *ConfigFile) error {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var (
+		tag    string
+		raw    any
+		decode func(v any) error
+	)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		tag = "yaml"
+		err = yaml.Unmarshal(bts, &raw)
+		decode = func(v any) error {
+			dec := yaml.NewDecoder(bytes.NewReader(bts))
+			dec.KnownFields(true)
+			if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			return nil
+		}
+	case ".json":
+		tag = "json"
+		err = json.Unmarshal(bts, &raw)
+		decode = func(v any) error {
+			dec := json.NewDecoder(bytes.NewReader(bts))
+			dec.DisallowUnknownFields()
+			err := dec.Decode(v)
+			if te := (*json.UnmarshalTypeError)(nil); errors.As(err, &te) && te.Field != "" {
+				return fmt.Errorf("%w: %w", ErrInvalidConfig, fieldError(jsonFieldPath(te.Field), "cannot use %s as %s", te.Value, te.Type))
+			}
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown config file format %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	// the decoders name the unknown field but not where it is
+	if errs := unknownConfigFields(raw, reflect.TypeOf(f), "", tag); len(errs) > 0 {
+		return fmt.Errorf("failed to parse config %s: %w: %w", path, ErrInvalidConfig, errors.Join(errs...))
+	}
+	if err := decode(f); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// jsonFieldPath converts the dotted path of a JSON decoding error, e.g. hosts.1.port, to hosts[1].port
+func jsonFieldPath(field string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil && i > 0 {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// unknownConfigFields returns an error per key of the decoded value v which is not a
+// field of t, the fields are named by the tag. Errors carry the path of the key
+func unknownConfigFields(v any, t reflect.Type, path, tag string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := range t.NumField() {
+			if name, _, _ := strings.Cut(t.Field(i).Tag.Get(tag), ","); name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok {
+				errs = append(errs, fieldError(field, "unknown field"))
+				continue
+			}
+			errs = append(errs, unknownConfigFields(m[k], ft, field, tag)...)
+		}
+	case reflect.Slice:
+		items, _ := v.([]any)
+		for i, item := range items {
+			errs = append(errs, unknownConfigFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), tag)...)
+		}
+	}
+	return errs
+}
+
+func (f *ConfigFile) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+
+	str := func(key string, dst *string) {
+		if v, ok := lookup(key); ok {
+			*dst = v
+		}
+	}
+	str(EnvNodeName, &f.NodeName)
+	str(EnvDescription, &f.Description)
+	str(EnvPassword, &f.Password)
+	str(EnvTLSCertificate, &f.TLS.Certificate)
+	str(EnvTLSKey, &f.TLS.Key)
+	str(EnvTLSPKCS12, &f.TLS.PKCS12)
+	str(EnvTLSKeyPassword, &f.TLS.KeyPassword)
+	str(EnvTLSCAFolder, &f.TLS.CAFolder)
+	str(EnvDNSServer, &f.DNS.Server)
+	str(EnvDNSFamily, &f.DNS.Family)
+	str(EnvDNSSRV, &f.DNS.SRV)
+	str(EnvProxyURL, &f.Proxy.URL)
+	str(EnvRetryWait, &f.Retry.Wait)
+	str(EnvRetryMaxWait, &f.Retry.MaxWait)
+	str(EnvTimeout, &f.Timeout)
+
+	if v, ok := lookup(EnvTLSInsecure); ok {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fieldError(EnvTLSInsecure, "invalid boolean %q", v))
+		}
+		f.TLS.Insecure = insecure
+	}
+	if v, ok := lookup(EnvDNSCache); ok {
+		cache, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fieldError(EnvDNSCache, "invalid boolean %q", v))
+		}
+		f.DNS.Cache = cache
+	}
+	if v, ok := lookup(EnvTLSPins); ok {
+		f.TLS.Pins = splitList(v)
+	}
+	if v, ok := lookup(EnvProxyBypass); ok {
+		f.Proxy.Bypass = splitList(v)
+	}
+	if v, ok := lookup(EnvRetryCount); ok {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fieldError(EnvRetryCount, "invalid number %q", v))
+		}
+		f.Retry.Count = count
+	}
+	if v, ok := lookup(EnvHosts); ok {
+		f.Hosts = nil
+		for _, h := range strings.Split(v, ",") {
+			host, err := parseHostPort(strings.TrimSpace(h))
+			if err != nil {
+				errs = append(errs, &ConfigFieldError{Field: EnvHosts, Err: err})
+				continue
+			}
+			f.Hosts = append(f.Hosts, host)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func parseHostPort(s string) (ConfigFileHost, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		// no port, a bare IPv6 address is accepted too
+		return ConfigFileHost{Host: strings.Trim(s, "[]")}, nil
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return ConfigFileHost{}, fmt.Errorf("invalid port in %q", s)
+	}
+	return ConfigFileHost{Host: host, Port: p}, nil
+}
+
+func parseConfigDuration(field, s string, errs *[]error) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		*errs = append(*errs, fieldError(field, "invalid duration %q", s))
+		return 0
+	}
+	if d < 0 {
+		*errs = append(*errs, fieldError(field, "must not be negative"))
+		return 0
+	}
+	return d
+}
+
+// Config validates the file and builds the config, the client certificate and
+// the CA folder are loaded from disk
+func (f *ConfigFile) Config() (*PxGridConfig, error) {
+	var errs []error
+
+	c := NewPxGridConfig().
+		SetNodeName(f.NodeName).
+		SetDescription(f.Description).
+		SetInsecureTLS(f.TLS.Insecure)
+
+	if len(f.Hosts) == 0 && f.DNS.SRV == "" {
+		errs = append(errs, fieldError("hosts", "at least one host is required without dns.srv"))
+	}
+	for i, h := range f.Hosts {
+		switch {
+		case strings.TrimSpace(h.Host) == "":
+			errs = append(errs, fieldError(fmt.Sprintf("hosts[%d].host", i), "is required"))
+		case h.Port < 0 || h.Port > 65535:
+			errs = append(errs, fieldError(fmt.Sprintf("hosts[%d].port", i), "%d is out of range", h.Port))
+		default:
+			port := h.Port
+			if port == 0 {
+				port = DefaultControlPort
+			}
+			c.AddHost(h.Host, port)
+		}
+		for j, pin := range h.Pins {
+			if _, err := ParseSPKIPin(pin); err != nil {
+				errs = append(errs, &ConfigFieldError{Field: fmt.Sprintf("hosts[%d].pins[%d]", i, j), Err: err})
+				continue
+			}
+			c.AddHostPin(h.Host, pin)
+		}
+	}
+	for i, pin := range f.TLS.Pins {
+		if _, err := ParseSPKIPin(pin); err != nil {
+			errs = append(errs, &ConfigFieldError{Field: fmt.Sprintf("tls.pins[%d]", i), Err: err})
+			continue
+		}
+		c.AddPin(pin)
+	}
+
+	if f.NodeName == "" {
+		errs = append(errs, fieldError("nodeName", "is required"))
+	}
+	c.SetAuth(f.NodeName, f.Password)
+
+	var id *ClientIdentity
+	switch {
+	case f.TLS.PKCS12 != "" && (f.TLS.Certificate != "" || f.TLS.Key != ""):
+		errs = append(errs, fieldError("tls.pkcs12", "must not be used with tls.certificate and tls.key"))
+	case f.TLS.PKCS12 != "":
+		var err error
+		if id, err = LoadPKCS12(f.TLS.PKCS12, f.TLS.KeyPassword); err != nil {
+			errs = append(errs, &ConfigFieldError{Field: "tls.pkcs12", Err: err})
+		}
+	case f.TLS.Certificate != "" && f.TLS.Key == "":
+		errs = append(errs, fieldError("tls.key", "is required with tls.certificate"))
+	case f.TLS.Certificate == "" && f.TLS.Key != "":
+		errs = append(errs, fieldError("tls.certificate", "is required with tls.key"))
+	case f.TLS.Certificate != "":
+		var err error
+		if id, err = LoadEncryptedKeyPair(f.TLS.Certificate, f.TLS.Key, f.TLS.KeyPassword); err != nil {
+			errs = append(errs, &ConfigFieldError{Field: "tls.certificate", Err: err})
+		}
+	case f.Password == "":
+		errs = append(errs, fieldError("password", "is required without tls.certificate or tls.pkcs12"))
+	}
+	if id != nil {
+		c.SetClientCertificate(id.Certificate)
+		c.SetCA(id.CA)
+	}
+
+	if f.TLS.CAFolder != "" {
+		pool, err := LoadCertPool(f.TLS.CAFolder)
+		if err != nil {
+			errs = append(errs, &ConfigFieldError{Field: "tls.caFolder", Err: err})
+		}
+		c.SetCA(pool)
+	}
+
+	family := DefaultINETFamilyStrategy
+	if f.DNS.Family != "" {
+		var err error
+		if family, err = ParseINETFamilyStrategy(f.DNS.Family); err != nil {
+			errs = append(errs, &ConfigFieldError{Field: "dns.family", Err: err})
+		}
+	}
+	if f.DNS.Server != "" {
+		if _, err := ParseDNSHost(f.DNS.Server); err != nil {
+			errs = append(errs, &ConfigFieldError{Field: "dns.server", Err: err})
+		}
+	}
+	c.SetDNS(f.DNS.Server, family).SetSRV(f.DNS.SRV)
+	maxTTL := parseConfigDuration("dns.cacheMaxTTL", f.DNS.CacheMaxTTL, &errs)
+	negativeTTL := parseConfigDuration("dns.negativeTTL", f.DNS.NegativeTTL, &errs)
+	if f.DNS.Cache {
+		c.SetDNSCache(maxTTL, negativeTTL)
+	}
+
+	c.Proxy = ProxyConfig{URL: f.Proxy.URL, Bypass: f.Proxy.Bypass, Direct: f.Proxy.Direct}
+	if err := c.Proxy.validate(); err != nil {
+		errs = append(errs, &ConfigFieldError{Field: "proxy.url", Err: err})
+	}
+
+	if f.Retry.Count < 0 {
+		errs = append(errs, fieldError("retry.count", "must not be negative"))
+	}
+	wait := parseConfigDuration("retry.wait", f.Retry.Wait, &errs)
+	maxWait := parseConfigDuration("retry.maxWait", f.Retry.MaxWait, &errs)
+	if wait > 0 && maxWait > 0 && maxWait < wait {
+		errs = append(errs, fieldError("retry.maxWait", "must not be less than retry.wait"))
+	}
+	c.SetRetry(max(f.Retry.Count, 0), wait, maxWait)
+	c.SetTimeout(parseConfigDuration("timeout", f.Timeout, &errs))
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return c, nil
+}
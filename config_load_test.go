@@ -0,0 +1,77 @@
+package gopxgrid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		wantFields []string
+		wantErr    bool
+	}{
+		{name: "yaml", file: "c.yaml", content: "nodeName: node\nhosts:\n  - host: ise\n    port: 8910\ntls:\n  insecure: true\n"},
+		{name: "json", file: "c.json", content: `{"nodeName":"node","hosts":[{"host":"ise"}],"retry":{"count":2}}`},
+		{name: "empty yaml", file: "c.yml", content: ""},
+		{name: "yaml unknown fields", file: "c.yaml", content: "nodeName: node\nhosts:\n  - host: ise\n  - host: ise2\n    prot: 1\ntls:\n  insecur: true\ntimeot: 1s\n",
+			wantFields: []string{"hosts[1].prot", "timeot", "tls.insecur"}, wantErr: true},
+		{name: "json unknown fields", file: "c.json", content: `{"dns":{"server":"1.1.1.1","famly":"4"},"proxy":{"ur":"http://p"}}`,
+			wantFields: []string{"dns.famly", "proxy.ur"}, wantErr: true},
+		{name: "json type error", file: "c.json", content: `{"hosts":[{"host":"ise","port":"8910"}]}`,
+			wantFields: []string{"hosts[0].port"}, wantErr: true},
+		{name: "yaml syntax", file: "c.yaml", content: "nodeName: [", wantErr: true},
+		{name: "unknown format", file: "c.toml", content: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var f ConfigFile
+			err := readConfigFile(path, &f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readConfigFile() = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var fields []string
+			for _, e := range unwrapAll(err) {
+				if fe := (*ConfigFieldError)(nil); errors.As(e, &fe) {
+					fields = append(fields, fe.Field)
+				}
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Fatalf("fields = %v, want %v in %v", fields, tt.wantFields, err)
+			}
+			if len(tt.wantFields) > 0 && !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("readConfigFile() = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+// unwrapAll returns the leaves of the error tree
+func unwrapAll(err error) []error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *ConfigFieldError:
+		return []error{e}
+	case interface{ Unwrap() []error }:
+		var res []error
+		for _, inner := range e.Unwrap() {
+			res = append(res, unwrapAll(inner)...)
+		}
+		return res
+	case interface{ Unwrap() error }:
+		return unwrapAll(e.Unwrap())
+	}
+	return []error{err}
+}
@@ -2,16 +2,22 @@ package gopxgrid
 
 import (
 	"context"
-	"crypto/x509"
 	"errors"
-	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PxGridConsumer struct {
-	cfg *PxGridConfig
-	svc *transport
+	cfg    *PxGridConfig
+	svc    *transport
+	limits *rateLimiter
+	tracer trace.Tracer
 
 	ancConfig        ANCConfig
 	endpointAsset    EndpointAsset
@@ -53,10 +59,18 @@ func NewPxGridConsumer(cfg *PxGridConfig) (*PxGridConsumer, error) {
 	if cfg == nil {
 		return nil, errors.New("invalid config")
 	}
+	if err := cfg.TLS.validatePins(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Proxy.validate(); err != nil {
+		return nil, err
+	}
 
 	c := &PxGridConsumer{
-		cfg: mergeWithDefaultConfig(cfg),
-		svc: newTransport(cfg),
+		cfg:    mergeWithDefaultConfig(cfg),
+		svc:    newTransport(cfg),
+		limits: newRateLimiter(cfg.RateLimits),
+		tracer: newTracer(cfg.TracerProvider),
 	}
 
 	c.ancConfig = NewPxGridANCConfig(c)
@@ -78,9 +92,40 @@ type RESTOptions struct {
 	overridePassword string
 	noAuth           bool
 	result           any
+	stream           bool
+	method           string
+	// idempotent calls are retried as configured by RetryConfig
+	idempotent bool
+
+	callName string
+	service  string
+	node     string
 }
 
 func (c *PxGridConsumer) RESTRequest(ctx context.Context, fullURL string, payload any, ops RESTOptions) (*Response, error) {
+	method := ops.method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	call := &RESTCall{
+		Method:  method,
+		Name:    ops.callName,
+		Service: ops.service,
+		Node:    ops.node,
+		URL:     fullURL,
+		Payload: payload,
+		Header:  http.Header{},
+	}
+
+	invoker := chainInterceptors(c.cfg.Interceptors, func(ctx context.Context, call *RESTCall) (*Response, error) {
+		return c.doRESTRequest(ctx, call, ops)
+	})
+
+	return invoker(ctx, call)
+}
+
+func (c *PxGridConsumer) doRESTRequest(ctx context.Context, call *RESTCall, ops RESTOptions) (*Response, error) {
 	req := c.svc.NewRequest(ctx)
 	if ops.noAuth {
 		req.NoAuth()
@@ -95,33 +140,65 @@ func (c *PxGridConsumer) RESTRequest(ctx context.Context, fullURL string, payloa
 	if ops.result != nil {
 		req.SetResult(ops.result)
 	}
-	if c.svc.tls.CA != nil {
-		req.SetRootCAs(c.svc.tls.CA)
-	} else {
-		sys, err := x509.SystemCertPool()
-		if err != nil {
-			return nil, err
+	if ops.stream {
+		req.SetStream(true)
+	}
+	req.SetIdempotent(ops.idempotent)
+	for k, v := range call.Header {
+		for _, vv := range v {
+			req.AddHeader(k, vv)
 		}
-		req.SetRootCAs(sys)
 	}
 
-	res, err := req.Post(fullURL, payload)
+	res, err := req.Do(call.Method, call.URL, call.Payload)
 	if err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
+// controlHosts returns the hosts of the SRV record followed by the configured hosts
+func (c *PxGridConsumer) controlHosts(ctx context.Context) []Host {
+	if c.cfg.DNS.SRV == "" {
+		return c.cfg.Hosts
+	}
+
+	srvs, err := c.svc.LookupSRV(ctx, c.cfg.DNS.SRV)
+	if err != nil {
+		c.cfg.Logger.WarnContext(ctx, "Failed to discover control hosts", "srv", c.cfg.DNS.SRV, "error", err)
+		return c.cfg.Hosts
+	}
+
+	hosts := make([]Host, 0, len(srvs)+len(c.cfg.Hosts))
+	for _, srv := range srvs {
+		// a "." target tells that the service is not available
+		target := strings.TrimSuffix(srv.Target, ".")
+		if target == "" {
+			continue
+		}
+		hosts = append(hosts, Host{Host: target, ControlPort: int(srv.Port)})
+	}
+	return append(hosts, c.cfg.Hosts...)
+}
+
 func (c *PxGridConsumer) controlRest(ctx context.Context, urlControl string, payload any, ops RESTOptions) (*Response, error) {
-	for _, n := range c.cfg.Hosts {
-		port := 8910
+	for _, n := range c.controlHosts(ctx) {
+		port := DefaultControlPort
 		if n.ControlPort != 0 {
 			port = n.ControlPort
 		}
 
-		fullURL := "https://" + fmt.Sprintf("%s:%d", n.Host, port) + "/pxgrid/control/" + urlControl
-		res, err := c.RESTRequest(ctx, fullURL, payload, ops)
+		fullURL := "https://" + net.JoinHostPort(n.Host, strconv.Itoa(port)) + "/pxgrid/control/" + urlControl
+		ops.callName = urlControl
+		ops.node = n.Host
+
+		spanCtx, span := c.tracer.Start(ctx, "pxgrid.control "+urlControl, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrCall.String(urlControl), attrNode.String(n.Host)))
+		res, err := c.RESTRequest(spanCtx, fullURL, payload, ops)
+		setResponseAttributes(span, res)
+		endSpan(span, err)
 		if err != nil {
+			c.cfg.Logger.DebugContext(ctx, "Control request failed", "call", urlControl, "host", n.Host, "error", err)
 			continue
 		}
 		return res, nil
@@ -130,6 +207,11 @@ func (c *PxGridConsumer) controlRest(ctx context.Context, urlControl string, pay
 	return nil, ErrNoHosts
 }
 
+// RateLimitStats returns counters of the configured service and node rate limits
+func (c *PxGridConsumer) RateLimitStats() RateLimitStats {
+	return c.limits.Stats()
+}
+
 func (c *PxGridConsumer) ANCConfig() ANCConfig {
 	return c.ancConfig
 }
@@ -163,6 +245,15 @@ func (c *PxGridConsumer) PubSub(service string) PubSub {
 	return svc
 }
 
+// Service returns a generic client of the named service, e.g. for services without a typed client
+func (c *PxGridConsumer) Service(name string) PxGridService {
+	return &pxGridService{
+		name: name,
+		ctrl: c,
+		log:  c.cfg.Logger.With("svc", name),
+	}
+}
+
 func (c *PxGridConsumer) RadiusFailure() RadiusFailure {
 	return c.radiusFailure
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -107,7 +109,8 @@ func (c *PxGridConsumer) ServiceLookup(ctx context.Context, svc string) (Service
 	}
 
 	res, err := c.controlRest(ctx, "ServiceLookup", payload, RESTOptions{
-		result: &ServiceLookupResponse{},
+		result:     &ServiceLookupResponse{},
+		idempotent: true,
 	})
 	if err != nil {
 		return ServiceLookupResponse{}, err
@@ -116,7 +119,10 @@ func (c *PxGridConsumer) ServiceLookup(ctx context.Context, svc string) (Service
 	return *(res.Result.(*ServiceLookupResponse)), nil
 }
 
-func (c *PxGridConsumer) AccessSecret(ctx context.Context, peerNodeName string) (string, error) {
+func (c *PxGridConsumer) AccessSecret(ctx context.Context, peerNodeName string) (_ string, err error) {
+	ctx, span := c.tracer.Start(ctx, "pxgrid.AccessSecret", trace.WithAttributes(attrPeerNode.String(peerNodeName)))
+	defer func() { endSpan(span, err) }()
+
 	payload := map[string]interface{}{
 		"peerNodeName": peerNodeName,
 	}
@@ -0,0 +1,133 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// happyEyeballsDelay is the connection attempt delay recommended by RFC 8305
+	happyEyeballsDelay = 250 * time.Millisecond
+	dialAttemptTimeout = 30 * time.Second
+)
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// orderIPAddrs filters the addresses by the strategy and interleaves the
+// families starting with the preferred one, IPv4 and IPv6 keep one family only
+func orderIPAddrs(addrs []net.IPAddr, strategy INETFamilyStrategy) ([]net.IPAddr, error) {
+	var v4, v6 []net.IPAddr
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			v4 = append(v4, a)
+		} else {
+			v6 = append(v6, a)
+		}
+	}
+
+	var first, second []net.IPAddr
+	switch strategy {
+	case IPv4:
+		first = v4
+	case IPv6:
+		first = v6
+	case IPv46:
+		first, second = v4, v6
+	case IPv64:
+		first, second = v6, v4
+	default:
+		return nil, &net.AddrError{Err: "unknown strategy", Addr: ""}
+	}
+
+	ordered := make([]net.IPAddr, 0, len(first)+len(second))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+
+	if len(ordered) == 0 {
+		switch strategy {
+		case IPv4:
+			return nil, &net.AddrError{Err: "no IPv4 address found", Addr: ""}
+		case IPv6:
+			return nil, &net.AddrError{Err: "no IPv6 address found", Addr: ""}
+		}
+		return nil, &net.AddrError{Err: "no IPv4 or IPv6 address found", Addr: ""}
+	}
+	return ordered, nil
+}
+
+// dialParallel connects to the addresses in order, a new attempt starts when
+// the previous one failed or after happyEyeballsDelay. The first established
+// connection wins, the others are cancelled or closed
+func dialParallel(ctx context.Context, dialer *net.Dialer, network string, addrs []net.IPAddr, port string) (net.Conn, error) {
+	if len(addrs) == 1 {
+		return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].String(), port))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so that attempts never block once dialParallel returned
+	results := make(chan dialResult, len(addrs))
+
+	next, pending := 0, 0
+	start := func() {
+		addr := net.JoinHostPort(addrs[next].String(), port)
+		next++
+		pending++
+		go func() {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(happyEyeballsDelay)
+	defer timer.Stop()
+
+	start()
+	var errs []error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go closeLateConns(results, pending)
+				return r.conn, nil
+			}
+			errs = append(errs, r.err)
+			if next < len(addrs) {
+				start()
+				timer.Reset(happyEyeballsDelay)
+			}
+		case <-timer.C:
+			if next < len(addrs) {
+				start()
+				timer.Reset(happyEyeballsDelay)
+			}
+		case <-ctx.Done():
+			go closeLateConns(results, pending)
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, fmt.Errorf("all %d addresses failed: %w", len(addrs), errors.Join(errs...))
+}
+
+func closeLateConns(results <-chan dialResult, n int) {
+	for range n {
+		if r := <-results; r.conn != nil {
+			r.conn.Close()
+		}
+	}
+}
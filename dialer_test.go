@@ -0,0 +1,124 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func ipAddrs(ips ...string) []net.IPAddr {
+	res := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		res[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return res
+}
+
+func TestOrderIPAddrs(t *testing.T) {
+	addrs := ipAddrs("10.0.0.1", "10.0.0.2", "2001:db8::1", "10.0.0.3")
+	tests := []struct {
+		name     string
+		addrs    []net.IPAddr
+		strategy INETFamilyStrategy
+		want     []string
+		wantErr  bool
+	}{
+		{name: "4", addrs: addrs, strategy: IPv4, want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{name: "6", addrs: addrs, strategy: IPv6, want: []string{"2001:db8::1"}},
+		{name: "46", addrs: addrs, strategy: IPv46, want: []string{"10.0.0.1", "2001:db8::1", "10.0.0.2", "10.0.0.3"}},
+		{name: "64", addrs: addrs, strategy: IPv64, want: []string{"2001:db8::1", "10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{name: "no v6", addrs: ipAddrs("10.0.0.1"), strategy: IPv6, wantErr: true},
+		{name: "none", strategy: IPv46, wantErr: true},
+		{name: "unknown", addrs: addrs, strategy: IPUnknown, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderIPAddrs(tt.addrs, tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("orderIPAddrs() = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ips []string
+			for _, a := range got {
+				ips = append(ips, a.IP.String())
+			}
+			if !slices.Equal(ips, tt.want) {
+				t.Fatalf("orderIPAddrs() = %v, want %v", ips, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialParallel(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// 127.0.0.2 refuses the connection, 127.0.0.3 hangs until the attempt is cancelled
+	dialer := &net.Dialer{
+		ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			if strings.HasPrefix(address, "127.0.0.3:") {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		addrs   []net.IPAddr
+		timeout time.Duration
+		minTime time.Duration
+		maxTime time.Duration
+		wantErr bool
+	}{
+		{name: "single", addrs: ipAddrs("127.0.0.1"), maxTime: happyEyeballsDelay},
+		{name: "refused first", addrs: ipAddrs("127.0.0.2", "127.0.0.1"), maxTime: happyEyeballsDelay},
+		{name: "hanging first", addrs: ipAddrs("127.0.0.3", "127.0.0.1"), minTime: happyEyeballsDelay, maxTime: 4 * happyEyeballsDelay},
+		{name: "all fail", addrs: ipAddrs("127.0.0.2", "127.0.0.2"), maxTime: happyEyeballsDelay, wantErr: true},
+		{name: "deadline", addrs: ipAddrs("127.0.0.3", "127.0.0.3"), timeout: 2 * happyEyeballsDelay, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			conn, err := dialParallel(ctx, dialer, "tcp", tt.addrs, port)
+			elapsed := time.Since(start)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dialParallel() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			if tt.timeout > 0 && !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("dialParallel() = %v, want deadline exceeded", err)
+			}
+			if elapsed < tt.minTime || (tt.maxTime > 0 && elapsed > tt.maxTime) {
+				t.Fatalf("took %s, want between %s and %s", elapsed, tt.minTime, tt.maxTime)
+			}
+		})
+	}
+}
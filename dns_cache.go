@@ -0,0 +1,388 @@
+package gopxgrid
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// DefaultDNSCacheTTL is the TTL of lookups of the system resolver, which does not expose the record TTLs
+	DefaultDNSCacheTTL = 30 * time.Second
+	// DefaultDNSNegativeTTL is the TTL of failed lookups if the server did not send an SOA record
+	DefaultDNSNegativeTTL = 5 * time.Second
+
+	dnsQueryTimeout = 5 * time.Second
+	// addrLookup is the cache key type of the combined A and AAAA lookup
+	addrLookup = dnsmessage.TypeALL
+)
+
+type (
+	dnsKey struct {
+		name  string
+		qtype dnsmessage.Type
+	}
+
+	dnsEntry struct {
+		addrs   []net.IPAddr
+		srvs    []*net.SRV
+		err     error
+		expires time.Time
+	}
+
+	// dnsCache caches lookups for the TTL of the records. Records are queried
+	// directly from the configured server, the system resolver is used otherwise
+	dnsCache struct {
+		server      *net.UDPAddr
+		resolver    *net.Resolver
+		maxTTL      time.Duration
+		negativeTTL time.Duration
+		now         func() time.Time
+
+		mu      sync.Mutex
+		entries map[dnsKey]dnsEntry
+	}
+
+	// dnsAnswer is the outcome of a single query
+	dnsAnswer struct {
+		addrs []net.IPAddr
+		srvs  []*net.SRV
+		ttl   time.Duration
+		err   error
+	}
+)
+
+func newDNSCache(server *net.UDPAddr, resolver *net.Resolver, cfg *DNSConfig) *dnsCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	negativeTTL := cfg.NegativeTTL
+	if negativeTTL == 0 {
+		negativeTTL = DefaultDNSNegativeTTL
+	}
+
+	return &dnsCache{
+		server:      server,
+		resolver:    resolver,
+		maxTTL:      cfg.CacheMaxTTL,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[dnsKey]dnsEntry),
+	}
+}
+
+func (c *dnsCache) get(key dnsKey) (dnsEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return dnsEntry{}, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return dnsEntry{}, false
+	}
+	return e, true
+}
+
+func (c *dnsCache) store(key dnsKey, a dnsAnswer) {
+	ttl := a.ttl
+	if c.maxTTL > 0 && ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = dnsEntry{addrs: a.addrs, srvs: a.srvs, err: a.err, expires: c.now().Add(ttl)}
+}
+
+// LookupIPAddr returns the A and AAAA records of the host
+func (c *dnsCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return []net.IPAddr{{IP: ip.AsSlice(), Zone: ip.Zone()}}, nil
+	}
+
+	key := dnsKey{name: strings.ToLower(host), qtype: addrLookup}
+	if e, ok := c.get(key); ok {
+		return slices.Clone(e.addrs), e.err
+	}
+
+	var a dnsAnswer
+	if c.server == nil {
+		a.addrs, a.err = c.resolver.LookupIPAddr(ctx, host)
+		a.ttl = DefaultDNSCacheTTL
+		if a.err != nil {
+			a.ttl = c.negativeTTL
+		}
+	} else {
+		a = c.queryAddrs(ctx, host)
+	}
+	if ctx.Err() == nil {
+		c.store(key, a)
+	}
+	return slices.Clone(a.addrs), a.err
+}
+
+func (c *dnsCache) queryAddrs(ctx context.Context, host string) dnsAnswer {
+	v4 := c.query(ctx, host, dnsmessage.TypeA)
+	v6 := c.query(ctx, host, dnsmessage.TypeAAAA)
+
+	a := dnsAnswer{addrs: append(v4.addrs, v6.addrs...)}
+	if len(a.addrs) > 0 {
+		// a family without records does not shorten the TTL of the other one
+		switch {
+		case len(v4.addrs) == 0:
+			a.ttl = v6.ttl
+		case len(v6.addrs) == 0:
+			a.ttl = v4.ttl
+		default:
+			a.ttl = min(v4.ttl, v6.ttl)
+		}
+		return a
+	}
+
+	a.err, a.ttl = v4.err, min(v4.ttl, v6.ttl)
+	if a.err == nil {
+		a.err = v6.err
+	}
+	return a
+}
+
+// LookupSRV returns the SRV records of the name ordered by priority and
+// weight as in RFC 2782, the order of equal priorities differs between calls
+func (c *dnsCache) LookupSRV(ctx context.Context, name string) ([]*net.SRV, error) {
+	key := dnsKey{name: strings.ToLower(name), qtype: dnsmessage.TypeSRV}
+	e, ok := c.get(key)
+	if !ok {
+		var a dnsAnswer
+		if c.server == nil {
+			_, a.srvs, a.err = c.resolver.LookupSRV(ctx, "", "", name)
+			a.ttl = DefaultDNSCacheTTL
+			if a.err != nil {
+				a.ttl = c.negativeTTL
+			}
+		} else {
+			a = c.query(ctx, name, dnsmessage.TypeSRV)
+		}
+		if ctx.Err() == nil {
+			c.store(key, a)
+		}
+		e = dnsEntry{srvs: a.srvs, err: a.err}
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	srvs := make([]*net.SRV, len(e.srvs))
+	for i, s := range e.srvs {
+		cp := *s
+		srvs[i] = &cp
+	}
+	orderSRV(srvs)
+	return srvs, nil
+}
+
+func (c *dnsCache) query(ctx context.Context, name string, qtype dnsmessage.Type) dnsAnswer {
+	notFound := func(ttl time.Duration) dnsAnswer {
+		return dnsAnswer{
+			err: &net.DNSError{Err: "no such host", Name: name, Server: c.server.String(), IsNotFound: true},
+			ttl: ttl,
+		}
+	}
+
+	m, err := c.exchange(ctx, name, qtype)
+	if err != nil {
+		return dnsAnswer{err: &net.DNSError{Err: err.Error(), Name: name, Server: c.server.String(), IsTemporary: true}, ttl: c.negativeTTL}
+	}
+	switch m.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return notFound(c.soaTTL(m))
+	default:
+		return dnsAnswer{
+			err: &net.DNSError{Err: "server failure: " + m.Header.RCode.String(), Name: name, Server: c.server.String(), IsTemporary: true},
+			ttl: c.negativeTTL,
+		}
+	}
+
+	var (
+		a   dnsAnswer
+		ttl = uint32(1<<32 - 1)
+	)
+	for _, r := range m.Answers {
+		switch body := r.Body.(type) {
+		case *dnsmessage.AResource:
+			a.addrs = append(a.addrs, net.IPAddr{IP: net.IP(body.A[:])})
+		case *dnsmessage.AAAAResource:
+			a.addrs = append(a.addrs, net.IPAddr{IP: net.IP(body.AAAA[:])})
+		case *dnsmessage.SRVResource:
+			a.srvs = append(a.srvs, &net.SRV{
+				Target:   body.Target.String(),
+				Port:     body.Port,
+				Priority: body.Priority,
+				Weight:   body.Weight,
+			})
+		default:
+			// CNAME chain of the answer
+			continue
+		}
+		ttl = min(ttl, r.Header.TTL)
+	}
+	if len(a.addrs) == 0 && len(a.srvs) == 0 {
+		return notFound(c.soaTTL(m))
+	}
+
+	a.ttl = time.Duration(ttl) * time.Second
+	return a
+}
+
+// soaTTL is the negative caching TTL of RFC 2308, the SOA minimum capped by the SOA TTL
+func (c *dnsCache) soaTTL(m *dnsmessage.Message) time.Duration {
+	for _, r := range m.Authorities {
+		if soa, ok := r.Body.(*dnsmessage.SOAResource); ok {
+			return time.Duration(min(soa.MinTTL, r.Header.TTL)) * time.Second
+		}
+	}
+	return c.negativeTTL
+}
+
+func (c *dnsCache) exchange(ctx context.Context, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uint16(rand.Uint32())
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	req, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	m, err := c.exchangeOver(ctx, "udp", id, req)
+	if err == nil && m.Header.Truncated {
+		m, err = c.exchangeOver(ctx, "tcp", id, req)
+	}
+	return m, err
+}
+
+func (c *dnsCache) exchangeOver(ctx context.Context, network string, id uint16, req []byte) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, c.server.String())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		req = append(binary.BigEndian.AppendUint16(nil, uint16(len(req))), req...)
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	for {
+		var buf []byte
+		if network == "tcp" {
+			var size [2]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return nil, err
+			}
+			buf = make([]byte, binary.BigEndian.Uint16(size[:]))
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return nil, err
+			}
+		} else {
+			buf = make([]byte, 65535)
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			buf = buf[:n]
+		}
+
+		var m dnsmessage.Message
+		if err := m.Unpack(buf); err != nil {
+			return nil, fmt.Errorf("invalid DNS response: %w", err)
+		}
+		// a stray response of an earlier query on UDP, wait for ours
+		if m.Header.ID != id || !m.Header.Response {
+			if network == "tcp" {
+				return nil, errors.New("DNS response ID mismatch")
+			}
+			continue
+		}
+		return &m, nil
+	}
+}
+
+// orderSRV sorts by priority and orders records of equal priority by the
+// weighted random selection of RFC 2782
+func orderSRV(srvs []*net.SRV) {
+	slices.SortStableFunc(srvs, func(a, b *net.SRV) int {
+		return int(a.Priority) - int(b.Priority)
+	})
+
+	for i := 0; i < len(srvs); {
+		j := i + 1
+		for j < len(srvs) && srvs[j].Priority == srvs[i].Priority {
+			j++
+		}
+		shuffleByWeight(srvs[i:j])
+		i = j
+	}
+}
+
+func shuffleByWeight(srvs []*net.SRV) {
+	total := 0
+	for _, s := range srvs {
+		total += int(s.Weight)
+	}
+	for i := range srvs {
+		if total == 0 {
+			return
+		}
+		// the records of weight 0 go first so that they are picked when the random number is 0
+		slices.SortStableFunc(srvs[i:], func(a, b *net.SRV) int {
+			return cmp.Compare(min(a.Weight, 1), min(b.Weight, 1))
+		})
+		pick := rand.IntN(total + 1)
+		sum := 0
+		for j := i; j < len(srvs); j++ {
+			sum += int(srvs[j].Weight)
+			if sum >= pick {
+				srvs[i], srvs[j] = srvs[j], srvs[i]
+				break
+			}
+		}
+		total -= int(srvs[i].Weight)
+	}
+}
@@ -0,0 +1,342 @@
+package gopxgrid
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsStub answers queries over UDP and TCP on the same port with the
+// messages returned by answer, the ID and question are copied from the query
+type dnsStub struct {
+	addr   *net.UDPAddr
+	answer func(network string, q dnsmessage.Message) []dnsmessage.Message
+
+	mu      sync.Mutex
+	queries map[string]int
+}
+
+func newDNSStub(t *testing.T, answer func(network string, q dnsmessage.Message) []dnsmessage.Message) *dnsStub {
+	t.Helper()
+
+	var (
+		pc  net.PacketConn
+		ln  net.Listener
+		err error
+	)
+	for range 10 {
+		pc, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ln, err = net.Listen("tcp", pc.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		pc.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pc.Close()
+		ln.Close()
+	})
+
+	s := &dnsStub{
+		addr:    pc.LocalAddr().(*net.UDPAddr),
+		answer:  answer,
+		queries: make(map[string]int),
+	}
+	go s.serveUDP(pc)
+	go s.serveTCP(ln)
+	return s
+}
+
+func (s *dnsStub) count(network string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[network]
+}
+
+func (s *dnsStub) reply(network string, req []byte) [][]byte {
+	var q dnsmessage.Message
+	if err := q.Unpack(req); err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.queries[network]++
+	s.mu.Unlock()
+
+	var out [][]byte
+	for _, m := range s.answer(network, q) {
+		if m.Header.ID == 0 {
+			m.Header.ID = q.Header.ID
+		}
+		m.Header.Response = true
+		m.Questions = q.Questions
+		b, err := m.Pack()
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (s *dnsStub) serveUDP(pc net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range s.reply("udp", buf[:n]) {
+			pc.WriteTo(b, addr)
+		}
+	}
+}
+
+func (s *dnsStub) serveTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var size [2]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			req := make([]byte, binary.BigEndian.Uint16(size[:]))
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			for _, b := range s.reply("tcp", req) {
+				conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(b))), b...))
+			}
+		}()
+	}
+}
+
+func aRecord(q dnsmessage.Message, ttl uint32, ip [4]byte) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: ip},
+	}
+}
+
+func newStubCache(s *dnsStub, cfg *DNSConfig) (*dnsCache, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newDNSCache(s.addr, nil, cfg)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestDNSCacheTTL(t *testing.T) {
+	s := newDNSStub(t, func(_ string, q dnsmessage.Message) []dnsmessage.Message {
+		m := dnsmessage.Message{}
+		if q.Questions[0].Type == dnsmessage.TypeA {
+			m.Answers = []dnsmessage.Resource{aRecord(q, 60, [4]byte{192, 0, 2, 1})}
+		}
+		return []dnsmessage.Message{m}
+	})
+	c, now := newStubCache(s, &DNSConfig{})
+
+	lookup := func() {
+		t.Helper()
+		addrs, err := c.LookupIPAddr(context.Background(), "ise.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(192, 0, 2, 1)) {
+			t.Fatalf("addrs = %v", addrs)
+		}
+	}
+
+	lookup()
+	if got := s.count("udp"); got != 2 {
+		t.Fatalf("queries = %d, want A and AAAA", got)
+	}
+
+	// the AAAA query without records does not shorten the TTL of the A records
+	*now = now.Add(59 * time.Second)
+	lookup()
+	if got := s.count("udp"); got != 2 {
+		t.Fatalf("queries = %d, want the cached answer", got)
+	}
+
+	*now = now.Add(time.Second)
+	lookup()
+	if got := s.count("udp"); got != 4 {
+		t.Fatalf("queries = %d, want a new lookup after the TTL", got)
+	}
+}
+
+func TestDNSCacheMaxTTL(t *testing.T) {
+	s := newDNSStub(t, func(_ string, q dnsmessage.Message) []dnsmessage.Message {
+		return []dnsmessage.Message{{Answers: []dnsmessage.Resource{aRecord(q, 3600, [4]byte{192, 0, 2, 1})}}}
+	})
+	c, now := newStubCache(s, &DNSConfig{CacheMaxTTL: 10 * time.Second})
+
+	for range 2 {
+		if _, err := c.LookupIPAddr(context.Background(), "ise.example.com"); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(10 * time.Second)
+	}
+	if got := s.count("udp"); got != 4 {
+		t.Fatalf("queries = %d, want the TTL capped", got)
+	}
+}
+
+func TestDNSCacheNegativeTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		soa  bool
+		ttl  time.Duration
+	}{
+		{name: "SOA minimum", soa: true, ttl: 30 * time.Second},
+		{name: "no SOA", ttl: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newDNSStub(t, func(_ string, q dnsmessage.Message) []dnsmessage.Message {
+				m := dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}}
+				if tt.soa {
+					zone := dnsmessage.MustNewName("example.com.")
+					m.Authorities = []dnsmessage.Resource{{
+						Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 120},
+						Body:   &dnsmessage.SOAResource{NS: zone, MBox: zone, MinTTL: 30},
+					}}
+				}
+				return []dnsmessage.Message{m}
+			})
+			c, now := newStubCache(s, &DNSConfig{NegativeTTL: 5 * time.Second})
+
+			lookup := func() {
+				t.Helper()
+				_, err := c.LookupSRV(context.Background(), "_pxgrid._tcp.example.com")
+				var dnsErr *net.DNSError
+				if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+					t.Fatalf("err = %v, want not found", err)
+				}
+			}
+
+			lookup()
+			*now = now.Add(tt.ttl - time.Second)
+			lookup()
+			if got := s.count("udp"); got != 1 {
+				t.Fatalf("queries = %d, want the cached failure", got)
+			}
+			*now = now.Add(time.Second)
+			lookup()
+			if got := s.count("udp"); got != 2 {
+				t.Fatalf("queries = %d, want a new lookup after %v", got, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestDNSCacheTruncatedFallsBackToTCP(t *testing.T) {
+	s := newDNSStub(t, func(network string, q dnsmessage.Message) []dnsmessage.Message {
+		if network == "udp" {
+			return []dnsmessage.Message{{Header: dnsmessage.Header{Truncated: true}}}
+		}
+		return []dnsmessage.Message{{Answers: []dnsmessage.Resource{aRecord(q, 60, [4]byte{192, 0, 2, 1})}}}
+	})
+	c, _ := newStubCache(s, &DNSConfig{})
+
+	m, err := c.exchange(context.Background(), "ise.example.com", dnsmessage.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Header.Truncated || len(m.Answers) != 1 {
+		t.Fatalf("answer = %+v, want the TCP answer", m)
+	}
+	if s.count("udp") != 1 || s.count("tcp") != 1 {
+		t.Fatalf("udp = %d, tcp = %d queries", s.count("udp"), s.count("tcp"))
+	}
+}
+
+func TestDNSCacheIDMismatch(t *testing.T) {
+	t.Run("udp skips stray responses", func(t *testing.T) {
+		s := newDNSStub(t, func(_ string, q dnsmessage.Message) []dnsmessage.Message {
+			return []dnsmessage.Message{
+				{Header: dnsmessage.Header{ID: q.Header.ID + 1}, Answers: []dnsmessage.Resource{aRecord(q, 60, [4]byte{192, 0, 2, 9})}},
+				{Answers: []dnsmessage.Resource{aRecord(q, 60, [4]byte{192, 0, 2, 1})}},
+			}
+		})
+		c, _ := newStubCache(s, &DNSConfig{})
+
+		m, err := c.exchange(context.Background(), "ise.example.com", dnsmessage.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Answers[0].Body.(*dnsmessage.AResource).A; got != [4]byte{192, 0, 2, 1} {
+			t.Fatalf("A = %v, want the answer with the query ID", got)
+		}
+	})
+
+	t.Run("tcp fails", func(t *testing.T) {
+		s := newDNSStub(t, func(network string, q dnsmessage.Message) []dnsmessage.Message {
+			if network == "udp" {
+				return []dnsmessage.Message{{Header: dnsmessage.Header{Truncated: true}}}
+			}
+			return []dnsmessage.Message{{Header: dnsmessage.Header{ID: q.Header.ID + 1}}}
+		})
+		c, _ := newStubCache(s, &DNSConfig{})
+
+		if _, err := c.exchange(context.Background(), "ise.example.com", dnsmessage.TypeA); err == nil {
+			t.Fatal("want an ID mismatch error")
+		}
+	})
+}
+
+func TestOrderSRV(t *testing.T) {
+	t.Run("priority", func(t *testing.T) {
+		srvs := []*net.SRV{
+			{Target: "c.", Priority: 20, Weight: 10},
+			{Target: "a.", Priority: 10, Weight: 0},
+			{Target: "b.", Priority: 15, Weight: 100},
+		}
+		orderSRV(srvs)
+		for i, want := range []string{"a.", "b.", "c."} {
+			if srvs[i].Target != want {
+				t.Fatalf("srvs[%d] = %s, want %s", i, srvs[i].Target, want)
+			}
+		}
+	})
+
+	t.Run("weight", func(t *testing.T) {
+		const runs = 20000
+		first := map[string]int{}
+		for range runs {
+			// the zero weight is last so that only the RFC 2782 ordering can pick it first
+			srvs := []*net.SRV{
+				{Target: "heavy.", Priority: 10, Weight: 30},
+				{Target: "light.", Priority: 10, Weight: 10},
+				{Target: "zero.", Priority: 10, Weight: 0},
+			}
+			orderSRV(srvs)
+			first[srvs[0].Target]++
+		}
+
+		// the zero weight record is picked for the random number 0 of [0, 40]
+		want := map[string]float64{"heavy.": 30.0 / 41, "light.": 10.0 / 41, "zero.": 1.0 / 41}
+		for target, p := range want {
+			got := float64(first[target]) / runs
+			if got < p*0.8 || got > p*1.2 {
+				t.Errorf("%s first in %.3f of the runs, want about %.3f", target, got, p)
+			}
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package gopxgrid
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// EndpointSource holds what a single service knows about an endpoint
+	EndpointSource[T any] struct {
+		Data  T
+		Found bool
+		// Err is set if the service could not be queried, e.g. ErrServiceUnavailable
+		Err error
+		// FetchedAt is the time the data was retrieved
+		FetchedAt time.Time
+		// UpdatedAt is the time the data was last updated according to the service, zero if unknown
+		UpdatedAt time.Time
+	}
+
+	// EndpointView merges what all services know about a MAC address
+	EndpointView struct {
+		MACAddress string
+		Session    EndpointSource[*Session]
+		MDM        EndpointSource[*MDMEndpoint]
+		ANC        EndpointSource[*ANCEndpoint]
+		Failures   EndpointSource[[]Failure]
+		Asset      EndpointSource[*ANCAsset]
+	}
+
+	endpointViewOptions struct {
+		assets        *EndpointAssetCache
+		sourceTimeout time.Duration
+		failureWindow time.Duration
+	}
+
+	EndpointViewOption func(*endpointViewOptions)
+
+	// EndpointAssetCache keeps the latest asset per MAC address received from the asset topic
+	EndpointAssetCache struct {
+		assets map[string]cachedAsset
+		l      sync.RWMutex
+	}
+
+	cachedAsset struct {
+		asset      ANCAsset
+		receivedAt time.Time
+	}
+)
+
+// WithEndpointAssetCache makes EndpointView include the asset known to the cache
+func WithEndpointAssetCache(cache *EndpointAssetCache) EndpointViewOption {
+	return func(o *endpointViewOptions) {
+		o.assets = cache
+	}
+}
+
+// WithEndpointSourceTimeout limits the time spent on every single service
+func WithEndpointSourceTimeout(timeout time.Duration) EndpointViewOption {
+	return func(o *endpointViewOptions) {
+		o.sourceTimeout = timeout
+	}
+}
+
+// DefaultEndpointFailureWindow is the time EndpointView looks back for RADIUS failures
+const DefaultEndpointFailureWindow = time.Hour
+
+// WithEndpointFailureWindow sets the time EndpointView looks back for RADIUS failures.
+// The service has no query by MAC address, so the failures of all endpoints in
+// the window are fetched and filtered. A negative window fetches all failures
+// ISE keeps, which may be large on busy deployments
+func WithEndpointFailureWindow(window time.Duration) EndpointViewOption {
+	return func(o *endpointViewOptions) {
+		o.failureWindow = window
+	}
+}
+
+// NormalizeMAC returns the MAC address in the upper case colon separated form used by ISE
+func NormalizeMAC(mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not a MAC address", ErrInvalidInput, mac)
+	}
+	return strings.ToUpper(hw.String()), nil
+}
+
+func sameMAC(normalized, other string) bool {
+	if other == "" {
+		return false
+	}
+	n, err := NormalizeMAC(other)
+	return err == nil && n == normalized
+}
+
+// EndpointView queries the session directory, MDM, ANC and RADIUS failure services
+// concurrently and merges what they know about the MAC address.
+// Failing or unavailable services are reported per source and do not fail the view.
+// Failures are looked up within DefaultEndpointFailureWindow, see WithEndpointFailureWindow.
+func (c *PxGridConsumer) EndpointView(ctx context.Context, mac string, opts ...EndpointViewOption) (*EndpointView, error) {
+	normalized, err := NormalizeMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	o := endpointViewOptions{failureWindow: DefaultEndpointFailureWindow}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	view := &EndpointView{MACAddress: normalized}
+	var wg sync.WaitGroup
+	run := func(fn func(ctx context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := ctx
+			if o.sourceTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, o.sourceTimeout)
+				defer cancel()
+			}
+			fn(ctx)
+		}()
+	}
+
+	run(func(ctx context.Context) {
+		res, err := c.SessionDirectory().Rest().GetSessionByMacAddress(normalized).Do(ctx)
+		view.Session = endpointSource(res.Result, err)
+		if view.Session.Found {
+			view.Session.UpdatedAt = res.Result.Timestamp.Time
+		}
+	})
+
+	run(func(ctx context.Context) {
+		res, err := c.MDM().Rest().GetEndpointByMacAddress(normalized).Do(ctx)
+		view.MDM = endpointSource(res.Result, err)
+		if view.MDM.Found {
+			view.MDM.UpdatedAt = res.Result.LastSyncTime.Time
+		}
+	})
+
+	run(func(ctx context.Context) {
+		res, err := c.ANCConfig().Rest().GetEndpointByMAC(normalized).Do(ctx)
+		view.ANC = endpointSource(res.Result, err)
+	})
+
+	run(func(ctx context.Context) {
+		var since time.Time
+		if o.failureWindow >= 0 {
+			since = time.Now().Add(-o.failureWindow)
+		}
+		res, err := c.RadiusFailure().Rest().GetFailuresSince(since).Do(ctx)
+		view.Failures = EndpointSource[[]Failure]{Err: err, FetchedAt: time.Now()}
+		if err != nil || res.Result == nil {
+			return
+		}
+
+		for _, f := range *res.Result {
+			if !sameMAC(normalized, f.MACAddress) && !sameMAC(normalized, f.CallingStationID) {
+				continue
+			}
+			view.Failures.Data = append(view.Failures.Data, f)
+			if f.Timestamp.Time.After(view.Failures.UpdatedAt) {
+				view.Failures.UpdatedAt = f.Timestamp.Time
+			}
+		}
+		view.Failures.Found = len(view.Failures.Data) > 0
+	})
+
+	if o.assets != nil {
+		asset, receivedAt, ok := o.assets.Get(normalized)
+		view.Asset = EndpointSource[*ANCAsset]{Found: ok, FetchedAt: time.Now(), UpdatedAt: receivedAt}
+		if ok {
+			view.Asset.Data = &asset
+		}
+	}
+
+	wg.Wait()
+	return view, nil
+}
+
+func endpointSource[T any](data *T, err error) EndpointSource[*T] {
+	src := EndpointSource[*T]{
+		Err:       err,
+		FetchedAt: time.Now(),
+	}
+	if err == nil && data != nil {
+		src.Data = data
+		src.Found = true
+	}
+	return src
+}
+
+func NewEndpointAssetCache() *EndpointAssetCache {
+	return &EndpointAssetCache{
+		assets: make(map[string]cachedAsset),
+	}
+}
+
+// Apply applies an asset topic message to the cache
+func (c *EndpointAssetCache) Apply(msg ANCAssetTopicMessage) error {
+	mac, err := NormalizeMAC(msg.Asset.AssetMACAddress)
+	if err != nil {
+		return err
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if msg.OperationType == OperationTypeDelete {
+		delete(c.assets, mac)
+		return nil
+	}
+
+	c.assets[mac] = cachedAsset{asset: msg.Asset, receivedAt: time.Now()}
+	return nil
+}
+
+// Follow applies messages of the asset topic subscription until the context
+// is done or the subscription is closed. Messages which failed to be read or
+// applied are passed to onError if it is not nil.
+func (c *EndpointAssetCache) Follow(ctx context.Context, sub *Subscription[ANCAssetTopicMessage], onError func(error)) error {
+	return follow(ctx, sub, c.Apply, onError)
+}
+
+// Get returns the latest asset of the MAC address and the time it was received
+func (c *EndpointAssetCache) Get(mac string) (ANCAsset, time.Time, bool) {
+	normalized, err := NormalizeMAC(mac)
+	if err != nil {
+		return ANCAsset{}, time.Time{}, false
+	}
+
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	a, ok := c.assets[normalized]
+	return a.asset, a.receivedAt, ok
+}
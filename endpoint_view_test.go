@@ -0,0 +1,178 @@
+package gopxgrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testMAC = "00:11:22:33:44:55"
+
+// writeJSON answers the request with the value
+func writeJSON(v any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+func TestEndpointViewQueriesConcurrently(t *testing.T) {
+	ts := FormatTimestamp(time.Now().Add(-time.Minute))
+
+	// every source waits until all of them were queried
+	var arrived sync.WaitGroup
+	arrived.Add(4)
+	all := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(all)
+	}()
+	barrier := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			arrived.Done()
+			select {
+			case <-all:
+			case <-time.After(5 * time.Second):
+				t.Error("sources are not queried concurrently")
+			}
+			h(w, r)
+		}
+	}
+
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		SessionDirectoryServiceName + "/getSessionByMacAddress": barrier(writeJSON(map[string]any{"macAddress": testMAC, "state": "STARTED", "timestamp": ts})),
+		MDMServiceName + "/getEndpointByMacAddress":             barrier(writeJSON(map[string]any{"macAddress": testMAC, "registered": true, "lastSyncTime": ts})),
+		ANCConfigServiceName + "/getEndpointByMAC":              barrier(writeJSON(map[string]any{"macAddress": testMAC, "policyName": "quarantine"})),
+		RadiusFailureServiceName + "/getFailures": barrier(writeJSON(map[string]any{"failures": []map[string]any{
+			{"id": "1", "macAddress": "00-11-22-33-44-55", "timestamp": ts},
+			{"id": "2", "callingStationId": "0011.2233.4455"},
+			{"id": "3", "macAddress": "66:77:88:99:AA:BB"},
+		}})),
+	})
+	c := newISEConsumer(t, srv, nil)
+
+	view, err := c.EndpointView(context.Background(), "00-11-22-33-44-55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.MACAddress != testMAC {
+		t.Fatalf("MACAddress = %q, want %q", view.MACAddress, testMAC)
+	}
+	if !view.Session.Found || view.Session.Data.State != "STARTED" || view.Session.UpdatedAt.IsZero() {
+		t.Fatalf("session = %+v", view.Session)
+	}
+	if !view.MDM.Found || !view.MDM.Data.Registered || view.MDM.UpdatedAt.IsZero() {
+		t.Fatalf("mdm = %+v", view.MDM)
+	}
+	if !view.ANC.Found || view.ANC.Data.PolicyName != "quarantine" {
+		t.Fatalf("anc = %+v", view.ANC)
+	}
+
+	var ids []string
+	for _, f := range view.Failures.Data {
+		ids = append(ids, f.ID)
+	}
+	if !view.Failures.Found || !slices.Equal(ids, []string{"1", "2"}) {
+		t.Fatalf("failures = %v, want the failures of the MAC address", ids)
+	}
+	if view.Asset.Found || !view.Asset.FetchedAt.IsZero() {
+		t.Fatalf("asset = %+v, want no asset without a cache", view.Asset)
+	}
+}
+
+func TestEndpointViewFailingSources(t *testing.T) {
+	// MDM and RADIUS failure are not registered, ANC fails
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		SessionDirectoryServiceName + "/getSessionByMacAddress": writeJSON(map[string]any{"macAddress": testMAC}),
+		ANCConfigServiceName + "/getEndpointByMAC": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	})
+	c := newISEConsumer(t, srv, nil)
+
+	assets := NewEndpointAssetCache()
+	if err := assets.Apply(ANCAssetTopicMessage{Asset: ANCAsset{AssetMACAddress: testMAC}}); err != nil {
+		t.Fatal(err)
+	}
+
+	view, err := c.EndpointView(context.Background(), testMAC, WithEndpointAssetCache(assets))
+	if err != nil {
+		t.Fatalf("EndpointView() = %v, want the failures reported per source", err)
+	}
+	if !view.Session.Found || view.Session.Err != nil {
+		t.Fatalf("session = %+v", view.Session)
+	}
+	if !view.Asset.Found {
+		t.Fatalf("asset = %+v, want the cached asset", view.Asset)
+	}
+	for name, src := range map[string]struct {
+		found bool
+		err   error
+	}{
+		"mdm":      {view.MDM.Found, view.MDM.Err},
+		"failures": {view.Failures.Found, view.Failures.Err},
+	} {
+		if src.found || !errors.Is(src.err, ErrServiceUnavailable) {
+			t.Errorf("%s found %v, err %v, want ErrServiceUnavailable", name, src.found, src.err)
+		}
+	}
+	if view.ANC.Found || view.ANC.Err == nil || errors.Is(view.ANC.Err, ErrServiceUnavailable) {
+		t.Fatalf("anc = %+v, want the status code error", view.ANC)
+	}
+}
+
+func TestEndpointViewFailureWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []EndpointViewOption
+		want time.Duration
+	}{
+		{name: "default", want: DefaultEndpointFailureWindow},
+		{name: "window", opts: []EndpointViewOption{WithEndpointFailureWindow(10 * time.Minute)}, want: 10 * time.Minute},
+		{name: "all failures", opts: []EndpointViewOption{WithEndpointFailureWindow(-1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req map[string]any
+			srv := newISEServer(t, map[string]http.HandlerFunc{
+				RadiusFailureServiceName + "/getFailures": func(w http.ResponseWriter, r *http.Request) {
+					json.NewDecoder(r.Body).Decode(&req)
+					json.NewEncoder(w).Encode(map[string]any{"failures": []any{}})
+				},
+			})
+			c := newISEConsumer(t, srv, nil)
+
+			before := time.Now()
+			if _, err := c.EndpointView(context.Background(), testMAC, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			start, ok := req["startTimestamp"].(string)
+			if tt.want == 0 {
+				if ok {
+					t.Fatalf("startTimestamp = %q, want all failures", start)
+				}
+				return
+			}
+			ts, err := ParsePxGridTime(start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the timestamp has millisecond precision
+			if since := before.Add(-tt.want); ts.Time.Before(since.Add(-time.Millisecond)) || ts.Time.After(time.Now().Add(-tt.want)) {
+				t.Fatalf("startTimestamp = %s, want %s ago", start, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointViewInvalidMAC(t *testing.T) {
+	c := newTestConsumer(t, nil)
+	if _, err := c.EndpointView(context.Background(), "not a mac"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("EndpointView() = %v, want ErrInvalidInput", err)
+	}
+}
@@ -2,14 +2,11 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
@@ -32,35 +29,6 @@ func dataPrinter[T any](dataChan <-chan *gopxgrid.Message[T]) {
 	}
 }
 
-func getX509Pair(certFile, keyFile string) (*tls.Certificate, error) {
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		return nil, err
-	}
-	return &cert, nil
-}
-
-func loadCertPool(caFolder string) (*x509.CertPool, error) {
-	pool := x509.NewCertPool()
-	files, err := os.ReadDir(caFolder)
-	if err != nil {
-		return nil, err
-	}
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		bts, err := os.ReadFile(filepath.Join(caFolder, file.Name()))
-		if err != nil {
-			return nil, err
-		}
-		if !pool.AppendCertsFromPEM(bts) {
-			logger.Warn("Failed to append certificate", "file", file.Name())
-		}
-	}
-	return pool, nil
-}
-
 func pxGridConfigFromFlags() *gopxgrid.PxGridConfig {
 	c := gopxgrid.NewPxGridConfig()
 
@@ -99,7 +67,7 @@ func pxGridConfigFromFlags() *gopxgrid.PxGridConfig {
 	}
 
 	if certCfg.certFile != "" && certCfg.keyFile != "" {
-		cert, err := getX509Pair(certCfg.certFile, certCfg.keyFile)
+		cert, err := gopxgrid.LoadX509KeyPair(certCfg.certFile, certCfg.keyFile)
 		if err != nil {
 			logger.Error("Failed to load client certificate", "err", err)
 			os.Exit(1)
@@ -113,7 +81,7 @@ func pxGridConfigFromFlags() *gopxgrid.PxGridConfig {
 	}
 
 	if certCfg.caFolder != "" {
-		pool, err := loadCertPool(certCfg.caFolder)
+		pool, err := gopxgrid.LoadCertPool(certCfg.caFolder)
 		if err != nil {
 			logger.Error("Failed to load CA pool", "err", err)
 			os.Exit(1)
@@ -127,7 +95,7 @@ func pxGridConfigFromFlags() *gopxgrid.PxGridConfig {
 
 func main() {
 	config := pxGridConfigFromFlags()
-	config.SetLogger(gopxgrid.FromSlog(logger))
+	config.SetLogger(gopxgrid.NewRedactingLogger(gopxgrid.FromSlog(logger)))
 
 	logger.Info("Connecting to pxGrid", slog.Any("config", config))
 
@@ -0,0 +1,220 @@
+package gopxgrid
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultHandleRetryWait is the first wait between retries of a handler call
+	DefaultHandleRetryWait = 100 * time.Millisecond
+	// DefaultHandleRetryMaxWait is the longest wait between retries of a handler call
+	DefaultHandleRetryMaxWait = 2 * time.Second
+)
+
+type (
+	// MessageHandler processes a message, failed calls are retried and
+	// dead-lettered as configured by HandleOptions
+	MessageHandler[T any] func(ctx context.Context, m *Message[T]) error
+
+	// HandleOptions configures Subscriber.Handle
+	HandleOptions[T any] struct {
+		// Workers is the number of concurrent handler calls, runtime.GOMAXPROCS(0) if 0
+		Workers int
+		// QueueSize is the number of messages buffered per worker
+		QueueSize int
+		// Key returns the ordering key of a message, e.g. the MAC address of a
+		// session. Messages with the same key are handled one at a time in the
+		// order of arrival. Messages are handled in any order if Key is nil
+		Key func(m *Message[T]) string
+		// Timeout limits every handler call through its context, no limit if 0
+		Timeout time.Duration
+		// Retry retries failed handler calls Count times, the wait starts at
+		// WaitTime and doubles up to MaxWaitTime, DefaultHandleRetryWait and
+		// DefaultHandleRetryMaxWait if 0. Messages of the same key wait for the retries
+		Retry RetryConfig
+		// DeadLetter receives the messages failed after the retries and the
+		// messages with a receive or unmarshal error, which are not passed to
+		// the handler. The failures are logged if nil
+		DeadLetter func(ctx context.Context, m *Message[T], err error)
+	}
+
+	// PanicError is the error of a handler call which panicked
+	PanicError struct {
+		Value any
+		Stack []byte
+	}
+)
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panicked: %v", e.Value)
+}
+
+func (s *subscriber[T]) Handle(ctx context.Context, handler MessageHandler[T], opts HandleOptions[T]) error {
+	sub, err := s.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// keep reading until the subscription is closed so that the delivery ends
+		go func() {
+			for range sub.C {
+			}
+		}()
+		sub.Unsubscribe()
+	}()
+
+	return handleMessages(ctx, sub.C, handler, opts, s.svc.ctrl.tracer, s.svc.log)
+}
+
+// handleMessages dispatches the messages of in to the workers until ctx is done or in
+// is closed, it returns once the running handler calls are done. Messages queued when
+// ctx is done are dropped, messages which could not be received or unmarshalled
+// are dead-lettered right away
+func handleMessages[T any](ctx context.Context, in <-chan *Message[T], handler MessageHandler[T], opts HandleOptions[T],
+	tracer trace.Tracer, log Logger,
+) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// a worker per key hash keeps the order of a key, workers share a queue otherwise
+	queues := make([]chan *Message[T], 1)
+	if opts.Key != nil {
+		queues = make([]chan *Message[T], workers)
+	}
+	for i := range queues {
+		queues[i] = make(chan *Message[T], opts.QueueSize)
+	}
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func(queue <-chan *Message[T]) {
+			defer wg.Done()
+			for m := range queue {
+				if ctx.Err() == nil {
+					handleMessage(ctx, m, handler, &opts, tracer, log)
+				}
+			}
+		}(queues[i%len(queues)])
+	}
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		var (
+			m  *Message[T]
+			ok bool
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok = <-in:
+		}
+		if !ok {
+			return ErrSubscriptionClosed
+		}
+		if err := m.Err; err != nil || m.UnmarshalError != nil {
+			if err == nil {
+				err = m.UnmarshalError
+			}
+			deadLetter(ctx, m, err, &opts, log)
+			continue
+		}
+
+		queue := queues[0]
+		if opts.Key != nil {
+			h := fnv.New32a()
+			h.Write([]byte(opts.Key(m)))
+			queue = queues[h.Sum32()%uint32(len(queues))]
+		}
+		select {
+		case queue <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func handleMessage[T any](ctx context.Context, m *Message[T], handler MessageHandler[T], opts *HandleOptions[T],
+	tracer trace.Tracer, log Logger,
+) {
+	// the handling span is linked to the ended receipt span of the message
+	ctx, span := tracer.Start(ctx, "pxgrid.handle "+m.Destination, trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.LinkFromContext(m.Context())), trace.WithAttributes(attrTopic.String(m.Destination)))
+
+	var err error
+	defer func() { endSpan(span, err) }()
+
+	wait, maxWait := opts.Retry.WaitTime, opts.Retry.MaxWaitTime
+	if wait <= 0 {
+		wait = DefaultHandleRetryWait
+	}
+	if maxWait <= 0 {
+		maxWait = max(DefaultHandleRetryMaxWait, wait)
+	}
+	for attempt := 0; ; attempt++ {
+		if err = callHandler(ctx, m, handler, opts.Timeout); err == nil {
+			return
+		}
+		if attempt >= opts.Retry.Count {
+			break
+		}
+
+		log.DebugContext(ctx, "Handler failed, retrying", "destination", m.Destination, "attempt", attempt+1, "error", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wait = min(wait*2, maxWait)
+	}
+
+	if ctx.Err() != nil {
+		// stopped while handling, the message is not dead-lettered
+		log.WarnContext(ctx, "Handler stopped", "destination", m.Destination, "offset", m.Offset, "error", err)
+		return
+	}
+	deadLetter(ctx, m, err, opts, log)
+}
+
+// deadLetter passes the failed message to opts.DeadLetter or logs the failure
+func deadLetter[T any](ctx context.Context, m *Message[T], err error, opts *HandleOptions[T], log Logger) {
+	if opts.DeadLetter != nil {
+		opts.DeadLetter(ctx, m, err)
+		return
+	}
+	log.ErrorContext(ctx, "Handler failed", "destination", m.Destination, "offset", m.Offset, "error", err)
+}
+
+func callHandler[T any](ctx context.Context, m *Message[T], handler MessageHandler[T], timeout time.Duration) (err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return handler(ctx, m)
+}
@@ -0,0 +1,247 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingTracer records the start configs of the spans
+type recordingTracer struct {
+	noop.Tracer
+
+	l       sync.Mutex
+	configs []trace.SpanConfig
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.l.Lock()
+	t.configs = append(t.configs, trace.NewSpanStartConfig(opts...))
+	t.l.Unlock()
+	return t.Tracer.Start(ctx, name, opts...)
+}
+
+func testMessage(body int, key string) *Message[int] {
+	return &Message[int]{Message: &stomp.Message{Destination: "/topic/" + key}, Body: body}
+}
+
+func TestHandleMessageLinksReceiptSpan(t *testing.T) {
+	receipt := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	m := testMessage(1, "a")
+	m.ctx = trace.ContextWithSpanContext(context.Background(), receipt)
+
+	tracer := &recordingTracer{}
+	var handlerSpan trace.SpanContext
+	handleMessage(context.Background(), m, func(ctx context.Context, m *Message[int]) error {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil
+	}, &HandleOptions[int]{}, tracer, testLogger)
+
+	if len(tracer.configs) != 1 {
+		t.Fatalf("started %d spans, want 1", len(tracer.configs))
+	}
+	links := tracer.configs[0].Links()
+	if len(links) != 1 || !links[0].SpanContext.Equal(receipt) {
+		t.Fatalf("links = %v, want the receipt span", links)
+	}
+	if handlerSpan.SpanID() == receipt.SpanID() {
+		t.Fatal("handler runs under the receipt span")
+	}
+}
+
+// deadLetters collects the dead-lettered messages
+type deadLetters struct {
+	l      sync.Mutex
+	bodies []int
+	errs   []error
+}
+
+func (d *deadLetters) add(_ context.Context, m *Message[int], err error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+	d.bodies = append(d.bodies, m.Body)
+	d.errs = append(d.errs, err)
+}
+
+// runHandler handles the messages until they are all dispatched, it returns
+// the error of handleMessages once the running calls are done
+func runHandler(t *testing.T, msgs []*Message[int], handler MessageHandler[int], opts HandleOptions[int]) error {
+	t.Helper()
+
+	in := make(chan *Message[int], len(msgs))
+	for _, m := range msgs {
+		in <- m
+	}
+	close(in)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return handleMessages(ctx, in, handler, opts, noop.NewTracerProvider().Tracer(""), testLogger)
+}
+
+func TestHandleMessagesKeepsKeyOrder(t *testing.T) {
+	const keys, perKey = 8, 50
+
+	var msgs []*Message[int]
+	for i := range perKey {
+		for k := range keys {
+			msgs = append(msgs, testMessage(i, strconv.Itoa(k)))
+		}
+	}
+
+	var (
+		l       sync.Mutex
+		handled = map[string][]int{}
+	)
+	err := runHandler(t, msgs, func(ctx context.Context, m *Message[int]) error {
+		time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+		l.Lock()
+		handled[m.Destination] = append(handled[m.Destination], m.Body)
+		l.Unlock()
+		return nil
+	}, HandleOptions[int]{
+		Workers:   4,
+		QueueSize: 4,
+		Key:       func(m *Message[int]) string { return m.Destination },
+	})
+	if !errors.Is(err, ErrSubscriptionClosed) {
+		t.Fatalf("err = %v, want ErrSubscriptionClosed", err)
+	}
+
+	if len(handled) != keys {
+		t.Fatalf("handled %d keys, want %d", len(handled), keys)
+	}
+	for key, bodies := range handled {
+		if len(bodies) != perKey || !slices.IsSorted(bodies) {
+			t.Errorf("%s handled in the order %v", key, bodies)
+		}
+	}
+}
+
+func TestHandleMessagesRecoversPanic(t *testing.T) {
+	var (
+		dead    deadLetters
+		l       sync.Mutex
+		handled []int
+	)
+	runHandler(t, []*Message[int]{testMessage(1, "a"), testMessage(2, "a")}, func(ctx context.Context, m *Message[int]) error {
+		if m.Body == 1 {
+			panic("boom")
+		}
+		l.Lock()
+		handled = append(handled, m.Body)
+		l.Unlock()
+		return nil
+	}, HandleOptions[int]{Workers: 1, DeadLetter: dead.add})
+
+	var perr *PanicError
+	if len(dead.errs) != 1 || !errors.As(dead.errs[0], &perr) || perr.Value != "boom" || len(perr.Stack) == 0 {
+		t.Fatalf("dead letters = %v, want the panic", dead.errs)
+	}
+	if !slices.Equal(handled, []int{2}) {
+		t.Fatalf("handled %v, want the message after the panic", handled)
+	}
+}
+
+func TestHandleMessagesTimeout(t *testing.T) {
+	var dead deadLetters
+	runHandler(t, []*Message[int]{testMessage(1, "a")}, func(ctx context.Context, m *Message[int]) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, HandleOptions[int]{Timeout: 10 * time.Millisecond, DeadLetter: dead.add})
+
+	if len(dead.errs) != 1 || !errors.Is(dead.errs[0], context.DeadlineExceeded) {
+		t.Fatalf("dead letters = %v, want the deadline", dead.errs)
+	}
+}
+
+func TestHandleMessagesRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name      string
+		failures  int
+		retry     RetryConfig
+		wantCalls int
+		wantDead  bool
+		minWait   time.Duration
+	}{
+		{name: "succeeds", failures: 2, retry: RetryConfig{Count: 3, WaitTime: time.Millisecond}, wantCalls: 3},
+		{name: "dead letter", failures: 10, retry: RetryConfig{Count: 2, WaitTime: time.Millisecond}, wantCalls: 3, wantDead: true},
+		{name: "no retries", failures: 10, wantCalls: 1, wantDead: true},
+		{name: "default wait", failures: 1, retry: RetryConfig{Count: 1}, wantCalls: 2, minWait: DefaultHandleRetryWait},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				dead  deadLetters
+				calls int
+			)
+			start := time.Now()
+			runHandler(t, []*Message[int]{testMessage(1, "a")}, func(ctx context.Context, m *Message[int]) error {
+				calls++
+				if calls <= tt.failures {
+					return errFailed
+				}
+				return nil
+			}, HandleOptions[int]{Workers: 1, Retry: tt.retry, DeadLetter: dead.add})
+
+			if calls != tt.wantCalls {
+				t.Fatalf("%d calls, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantDead != (len(dead.errs) == 1) || tt.wantDead && !errors.Is(dead.errs[0], errFailed) {
+				t.Fatalf("dead letters = %v", dead.errs)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minWait {
+				t.Fatalf("retried after %v, want at least %v", elapsed, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestHandleMessagesDeadLettersUnreadable(t *testing.T) {
+	errReceive := errors.New("receive failed")
+	errUnmarshal := errors.New("unmarshal failed")
+
+	failed := testMessage(1, "a")
+	failed.Err = errReceive
+	invalid := testMessage(2, "a")
+	invalid.UnmarshalError = errUnmarshal
+
+	var (
+		dead    deadLetters
+		handled []int
+	)
+	err := runHandler(t, []*Message[int]{failed, invalid, testMessage(3, "a")}, func(ctx context.Context, m *Message[int]) error {
+		handled = append(handled, m.Body)
+		return nil
+	}, HandleOptions[int]{
+		Workers:    1,
+		Retry:      RetryConfig{Count: 3},
+		DeadLetter: dead.add,
+		Key:        func(m *Message[int]) string { return m.Destination },
+	})
+	if !errors.Is(err, ErrSubscriptionClosed) {
+		t.Fatalf("err = %v, want ErrSubscriptionClosed", err)
+	}
+
+	if !slices.Equal(dead.bodies, []int{1, 2}) || !errors.Is(dead.errs[0], errReceive) || !errors.Is(dead.errs[1], errUnmarshal) {
+		t.Fatalf("dead letters %v: %v", dead.bodies, dead.errs)
+	}
+	if !slices.Equal(handled, []int{3}) {
+		t.Fatalf("handled %v, want the readable message only", handled)
+	}
+}
@@ -0,0 +1,434 @@
+package gopxgrid
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHealthPollInterval     = time.Minute
+	DefaultHealthHistory          = 60
+	DefaultHealthDiscoverInterval = 10 * time.Minute
+)
+
+// ErrHealthNoTimestamp is reported for samples dropped because they have no timestamp
+var ErrHealthNoTimestamp = errors.New("health sample without timestamp")
+
+type (
+	// HealthMetric is a value of SysHealth or SysPerformance thresholds are evaluated on
+	HealthMetric string
+
+	HealthEventType string
+
+	// HealthThreshold raises an alert once the metric reaches Alert and clears it
+	// once the metric drops to Clear or below. Clear lower than Alert gives hysteresis,
+	// zero Clear is treated as equal to Alert.
+	HealthThreshold struct {
+		Alert float64
+		Clear float64
+	}
+
+	// HealthPollerConfig configures HealthPoller, zero values are replaced by the defaults
+	HealthPollerConfig struct {
+		Interval time.Duration
+		// Nodes are the ISE nodes polled one by one. If empty, all nodes are polled
+		// at once to discover the servers, which are then polled one by one
+		Nodes []string
+		// DiscoverInterval is how often all nodes are polled at once to discover new
+		// servers if Nodes is empty
+		DiscoverInterval time.Duration
+		// History is the number of samples of each kind kept per server
+		History int
+		// Lookback is how far back the first poll reaches, defaults to Interval
+		Lookback   time.Duration
+		Thresholds map[HealthMetric]HealthThreshold
+	}
+
+	// HealthEvent is emitted when a metric of a server crosses its threshold
+	HealthEvent struct {
+		Type      HealthEventType
+		Server    string
+		Metric    HealthMetric
+		Value     float64
+		Threshold float64
+		Timestamp time.Time
+	}
+
+	// HealthPoller polls system health and performance of ISE nodes
+	// and evaluates thresholds on the collected samples
+	HealthPoller struct {
+		rest SystemHealthRest
+		cfg  HealthPollerConfig
+
+		servers   map[string]*serverHealth
+		listeners map[int]func(HealthEvent)
+		nextID    int
+		// discovered is the time of the last poll of all nodes
+		discovered time.Time
+
+		l sync.RWMutex
+	}
+
+	serverHealth struct {
+		healths      []SysHealth
+		performances []SysPerformance
+		lastHealth   time.Time
+		lastPerf     time.Time
+		alerts       map[HealthMetric]HealthEvent
+	}
+)
+
+const (
+	HealthMetricCPU           HealthMetric = "cpuUsage"
+	HealthMetricMemory        HealthMetric = "memoryUsage"
+	HealthMetricDiskRoot      HealthMetric = "diskUsageRoot"
+	HealthMetricDiskOpt       HealthMetric = "diskUsageOpt"
+	HealthMetricRADIUSLatency HealthMetric = "radiusLatency"
+
+	HealthEventAlert HealthEventType = "alert"
+	HealthEventClear HealthEventType = "clear"
+)
+
+var healthMetrics = []HealthMetric{HealthMetricCPU, HealthMetricMemory, HealthMetricDiskRoot, HealthMetricDiskOpt}
+
+func (m HealthMetric) ofHealth(h *SysHealth) float64 {
+	switch m {
+	case HealthMetricCPU:
+		return h.CPUUsage
+	case HealthMetricMemory:
+		return h.MemoryUsage
+	case HealthMetricDiskRoot:
+		return h.DiskUsageRoot
+	case HealthMetricDiskOpt:
+		return h.DiskUsageOpt
+	}
+	return 0
+}
+
+func NewHealthPoller(rest SystemHealthRest, cfg HealthPollerConfig) *HealthPoller {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHealthPollInterval
+	}
+	if cfg.History <= 0 {
+		cfg.History = DefaultHealthHistory
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = cfg.Interval
+	}
+	if cfg.DiscoverInterval <= 0 {
+		cfg.DiscoverInterval = DefaultHealthDiscoverInterval
+	}
+
+	return &HealthPoller{
+		rest:      rest,
+		cfg:       cfg,
+		servers:   make(map[string]*serverHealth),
+		listeners: make(map[int]func(HealthEvent)),
+	}
+}
+
+// OnEvent registers a listener called for every alert and clear event.
+// Listeners are called synchronously while polling.
+// The returned function removes the listener.
+func (p *HealthPoller) OnEvent(fn func(HealthEvent)) func() {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	id := p.nextID
+	p.nextID++
+	p.listeners[id] = fn
+
+	return func() {
+		p.l.Lock()
+		defer p.l.Unlock()
+		delete(p.listeners, id)
+	}
+}
+
+func (p *HealthPoller) notify(events []HealthEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	p.l.RLock()
+	ids := make([]int, 0, len(p.listeners))
+	for id := range p.listeners {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	listeners := make([]func(HealthEvent), 0, len(ids))
+	for _, id := range ids {
+		listeners = append(listeners, p.listeners[id])
+	}
+	p.l.RUnlock()
+
+	for _, e := range events {
+		for _, fn := range listeners {
+			fn(e)
+		}
+	}
+}
+
+// since returns the start timestamp of the next poll, must be called with the read lock held
+func (p *HealthPoller) since(node string, last func(*serverHealth) time.Time) time.Time {
+	floor := time.Now().Add(-p.cfg.Lookback)
+
+	var since time.Time
+	if node != "" {
+		if s, ok := p.servers[node]; ok {
+			since = last(s)
+		}
+	} else {
+		for _, s := range p.servers {
+			if l := last(s); !l.IsZero() && (since.IsZero() || l.Before(since)) {
+				since = l
+			}
+		}
+	}
+
+	if since.Before(floor) {
+		return floor
+	}
+	return since
+}
+
+// nodes returns the nodes to poll, the discovered servers if no nodes are configured.
+// An empty name polls all nodes at once
+func (p *HealthPoller) nodes() []string {
+	if len(p.cfg.Nodes) > 0 {
+		return p.cfg.Nodes
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if len(p.servers) == 0 || time.Since(p.discovered) >= p.cfg.DiscoverInterval {
+		p.discovered = time.Now()
+		return []string{""}
+	}
+
+	nodes := make([]string, 0, len(p.servers))
+	for name := range p.servers {
+		nodes = append(nodes, name)
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// Poll polls all configured or discovered nodes once
+func (p *HealthPoller) Poll(ctx context.Context) error {
+	nodes := p.nodes()
+
+	var errs []error
+	for _, node := range nodes {
+		if err := p.pollNode(ctx, node); err != nil {
+			if node != "" {
+				err = fmt.Errorf("node %s: %w", node, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *HealthPoller) pollNode(ctx context.Context, node string) error {
+	p.l.RLock()
+	healthSince := p.since(node, func(s *serverHealth) time.Time { return s.lastHealth })
+	perfSince := p.since(node, func(s *serverHealth) time.Time { return s.lastPerf })
+	p.l.RUnlock()
+
+	var errs []error
+	healths, err := p.rest.GetHealthsSince(node, healthSince).Do(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get healths: %w", err))
+	} else if healths.Result != nil {
+		events, dropped := p.addHealths(*healths.Result)
+		p.notify(events)
+		if dropped > 0 {
+			errs = append(errs, fmt.Errorf("%w: dropped %d health samples", ErrHealthNoTimestamp, dropped))
+		}
+	}
+
+	perfs, err := p.rest.GetPerformancesSince(node, perfSince).Do(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get performances: %w", err))
+	} else if perfs.Result != nil {
+		events, dropped := p.addPerformances(*perfs.Result)
+		p.notify(events)
+		if dropped > 0 {
+			errs = append(errs, fmt.Errorf("%w: dropped %d performance samples", ErrHealthNoTimestamp, dropped))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *HealthPoller) server(name string) *serverHealth {
+	s, ok := p.servers[name]
+	if !ok {
+		s = &serverHealth{alerts: make(map[HealthMetric]HealthEvent)}
+		p.servers[name] = s
+	}
+	return s
+}
+
+// addHealths adds the new samples, it returns the events and the number of samples without timestamp
+func (p *HealthPoller) addHealths(samples []SysHealth) ([]HealthEvent, int) {
+	slices.SortStableFunc(samples, func(a, b SysHealth) int { return a.Timestamp.Time.Compare(b.Timestamp.Time) })
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	var (
+		events  []HealthEvent
+		dropped int
+	)
+	for _, h := range samples {
+		if h.Timestamp.IsZero() {
+			dropped++
+			continue
+		}
+		s := p.server(h.ServerName)
+		if !h.Timestamp.Time.After(s.lastHealth) {
+			continue
+		}
+
+		s.lastHealth = h.Timestamp.Time
+		s.healths = appendBounded(s.healths, h, p.cfg.History)
+		for _, m := range healthMetrics {
+			if e, ok := p.evaluate(s, h.ServerName, m, m.ofHealth(&h), h.Timestamp.Time); ok {
+				events = append(events, e)
+			}
+		}
+	}
+	return events, dropped
+}
+
+// addPerformances adds the new samples, it returns the events and the number of samples without timestamp
+func (p *HealthPoller) addPerformances(samples []SysPerformance) ([]HealthEvent, int) {
+	slices.SortStableFunc(samples, func(a, b SysPerformance) int { return a.Timestamp.Time.Compare(b.Timestamp.Time) })
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	var (
+		events  []HealthEvent
+		dropped int
+	)
+	for _, perf := range samples {
+		if perf.Timestamp.IsZero() {
+			dropped++
+			continue
+		}
+		s := p.server(perf.ServerName)
+		if !perf.Timestamp.Time.After(s.lastPerf) {
+			continue
+		}
+
+		s.lastPerf = perf.Timestamp.Time
+		s.performances = appendBounded(s.performances, perf, p.cfg.History)
+		if e, ok := p.evaluate(s, perf.ServerName, HealthMetricRADIUSLatency, perf.RADIUSLatency, perf.Timestamp.Time); ok {
+			events = append(events, e)
+		}
+	}
+	return events, dropped
+}
+
+// evaluate checks the value against the threshold of the metric, must be called with the lock held
+func (p *HealthPoller) evaluate(s *serverHealth, server string, m HealthMetric, value float64, ts time.Time) (HealthEvent, bool) {
+	th, ok := p.cfg.Thresholds[m]
+	if !ok {
+		return HealthEvent{}, false
+	}
+	clearAt := th.Clear
+	if clearAt == 0 {
+		clearAt = th.Alert
+	}
+
+	_, alerting := s.alerts[m]
+	switch {
+	case !alerting && value >= th.Alert:
+		e := HealthEvent{Type: HealthEventAlert, Server: server, Metric: m, Value: value, Threshold: th.Alert, Timestamp: ts}
+		s.alerts[m] = e
+		return e, true
+	case alerting && value <= clearAt && (clearAt < th.Alert || value < th.Alert):
+		delete(s.alerts, m)
+		return HealthEvent{Type: HealthEventClear, Server: server, Metric: m, Value: value, Threshold: clearAt, Timestamp: ts}, true
+	}
+	return HealthEvent{}, false
+}
+
+func appendBounded[T any](s []T, v T, limit int) []T {
+	s = append(s, v)
+	if len(s) > limit {
+		s = slices.Delete(s, 0, len(s)-limit)
+	}
+	return s
+}
+
+// Run polls at the interval until the context is done.
+// Poll errors are passed to onError if it is not nil.
+func (p *HealthPoller) Run(ctx context.Context, onError func(error)) error {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Servers returns the names of the servers samples were received from
+func (p *HealthPoller) Servers() []string {
+	p.l.RLock()
+	defer p.l.RUnlock()
+
+	res := make([]string, 0, len(p.servers))
+	for name := range p.servers {
+		res = append(res, name)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// History returns the kept health and performance samples of the server, oldest first
+func (p *HealthPoller) History(server string) ([]SysHealth, []SysPerformance) {
+	p.l.RLock()
+	defer p.l.RUnlock()
+
+	s, ok := p.servers[server]
+	if !ok {
+		return nil, nil
+	}
+	return slices.Clone(s.healths), slices.Clone(s.performances)
+}
+
+// Alerts returns the alerts which are not cleared yet
+func (p *HealthPoller) Alerts() []HealthEvent {
+	p.l.RLock()
+	defer p.l.RUnlock()
+
+	var res []HealthEvent
+	for _, s := range p.servers {
+		for _, e := range s.alerts {
+			res = append(res, e)
+		}
+	}
+	slices.SortFunc(res, func(a, b HealthEvent) int {
+		return cmp.Or(strings.Compare(a.Server, b.Server), strings.Compare(string(a.Metric), string(b.Metric)))
+	})
+	return res
+}
@@ -0,0 +1,92 @@
+package gopxgrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHealthPollerNodes(t *testing.T) {
+	var (
+		l         sync.Mutex
+		requested []string
+	)
+	ts := FormatTimestamp(time.Now())
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		SystemHealthServiceName + "/getHealths": func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				NodeName string `json:"nodeName"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			l.Lock()
+			requested = append(requested, req.NodeName)
+			l.Unlock()
+
+			healths := []map[string]any{}
+			for _, name := range []string{"ise1", "ise2"} {
+				if req.NodeName == "" || req.NodeName == name {
+					healths = append(healths, map[string]any{"serverName": name, "timestamp": ts, "cpuUsage": 95})
+				}
+			}
+			healths = append(healths, map[string]any{"serverName": "ise3", "cpuUsage": 10})
+			json.NewEncoder(w).Encode(map[string]any{"healths": healths})
+		},
+		SystemHealthServiceName + "/getPerformances": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"performances": []any{}})
+		},
+	})
+
+	tests := []struct {
+		name     string
+		cfg      HealthPollerConfig
+		polls    int
+		want     []string
+		wantErrs int
+	}{
+		{name: "configured", cfg: HealthPollerConfig{Nodes: []string{"ise2"}}, polls: 2, want: []string{"ise2", "ise2"}, wantErrs: 2},
+		{name: "discovered", polls: 2, want: []string{"", "ise1", "ise2"}, wantErrs: 3},
+		{name: "rediscovered", cfg: HealthPollerConfig{DiscoverInterval: time.Nanosecond}, polls: 2, want: []string{"", ""}, wantErrs: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			tt.cfg.Thresholds = map[HealthMetric]HealthThreshold{HealthMetricCPU: {Alert: 90, Clear: 80}}
+			p := NewHealthPoller(NewPxGridSystemHealth(newISEConsumer(t, srv, nil)).Rest(), tt.cfg)
+			var events []HealthEvent
+			p.OnEvent(func(e HealthEvent) { events = append(events, e) })
+
+			errs := 0
+			for range tt.polls {
+				err := p.Poll(context.Background())
+				for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+					if !errors.Is(e, ErrHealthNoTimestamp) {
+						t.Fatalf("Poll() = %v, want ErrHealthNoTimestamp only", err)
+					}
+					errs++
+				}
+			}
+			if !slices.Equal(requested, tt.want) {
+				t.Fatalf("polled nodes %q, want %q", requested, tt.want)
+			}
+			if errs != tt.wantErrs {
+				t.Fatalf("reported %d dropped samples, want %d", errs, tt.wantErrs)
+			}
+			if slices.Contains(p.Servers(), "ise3") {
+				t.Fatalf("Servers() = %v, samples without timestamp were added", p.Servers())
+			}
+			for _, e := range events {
+				if e.Type != HealthEventAlert || e.Metric != HealthMetricCPU {
+					t.Fatalf("unexpected event %+v", e)
+				}
+			}
+			if len(events) != len(p.Servers()) {
+				t.Fatalf("got %d alerts for servers %v", len(events), p.Servers())
+			}
+		})
+	}
+}
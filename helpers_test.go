@@ -0,0 +1,112 @@
+package gopxgrid
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testLogger = FromSlog(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+// newTestConsumer returns a consumer trusting any certificate, cfg may be nil
+func newTestConsumer(t *testing.T, cfg *PxGridConfig) *PxGridConsumer {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = NewPxGridConfig()
+	}
+	cfg.SetNodeName("test").SetAuth("test", "password").SetInsecureTLS(true).SetLogger(testLogger)
+
+	c, err := NewPxGridConsumer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// newTestService returns a service with a node per URL, the nodes are named node0, node1 and so on
+func newTestService(c *PxGridConsumer, name string, urls ...string) *pxGridService {
+	s := &pxGridService{name: name, ctrl: c, log: c.cfg.Logger}
+	for i, u := range urls {
+		s.nodes = append(s.nodes, ServiceNode{
+			Name:       name,
+			NodeName:   "node" + strconv.Itoa(i),
+			Properties: map[string]any{"restBaseUrl": u},
+			Secret:     "secret",
+		})
+	}
+	return s
+}
+
+// newJSONServer returns a TLS server answering every request with the value
+func newJSONServer(t *testing.T, v any) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newISEServer returns a TLS server acting as the controller and as the only node of
+// the services of calls, which are keyed by "<service>/<call>"
+func newISEServer(t *testing.T, calls map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/pxgrid/control/ServiceLookup":
+			var req struct {
+				Name string `json:"name"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			res := ServiceLookupResponse{Services: []ServiceNode{}}
+			for key := range calls {
+				if strings.HasPrefix(key, req.Name+"/") {
+					res.Services = append(res.Services, ServiceNode{
+						Name:       req.Name,
+						NodeName:   "node0",
+						Properties: map[string]any{"restBaseUrl": srv.URL + "/" + req.Name},
+					})
+					break
+				}
+			}
+			json.NewEncoder(w).Encode(res)
+		case "/pxgrid/control/AccessSecret":
+			json.NewEncoder(w).Encode(map[string]string{"secret": "secret"})
+		default:
+			h, ok := calls[strings.TrimPrefix(r.URL.Path, "/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			h(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newISEConsumer returns a consumer using srv as the controller
+func newISEConsumer(t *testing.T, srv *httptest.Server, cfg *PxGridConfig) *PxGridConsumer {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = NewPxGridConfig()
+	}
+	u, _ := url.Parse(srv.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	p, _ := strconv.Atoi(port)
+	return newTestConsumer(t, cfg.AddHost(host, p))
+}
@@ -0,0 +1,55 @@
+package gopxgrid
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	// RESTCall describes a single REST request passing through the interceptor chain
+	RESTCall struct {
+		Method string
+		// Name is the name of the call, e.g. "AccountActivate" or "getSessions"
+		Name string
+		// Service is the name of the pxGrid service, empty for control calls
+		Service string
+		// Node is the pxGrid node name for service calls or the host for control calls
+		Node    string
+		URL     string
+		Payload any
+		// Header holds extra headers to be sent with the request
+		Header http.Header
+	}
+
+	// RESTInvoker performs the REST call, either directly or by calling the next interceptor
+	RESTInvoker func(ctx context.Context, call *RESTCall) (*Response, error)
+
+	// Interceptor wraps every control and service REST call.
+	// An interceptor may inspect or modify the call, short-circuit it by returning
+	// without calling next, or inspect the response returned by next.
+	Interceptor interface {
+		Intercept(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error)
+	}
+
+	// InterceptorFunc is an adapter to allow the use of ordinary functions as interceptors
+	InterceptorFunc func(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error)
+)
+
+// Intercept calls f(ctx, call, next)
+func (f InterceptorFunc) Intercept(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error) {
+	return f(ctx, call, next)
+}
+
+// chainInterceptors builds an invoker which runs the interceptors in order
+// with the first interceptor being the outermost one
+func chainInterceptors(interceptors []Interceptor, final RESTInvoker) RESTInvoker {
+	invoker := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, call *RESTCall) (*Response, error) {
+			return interceptor.Intercept(ctx, call, next)
+		}
+	}
+
+	return invoker
+}
@@ -0,0 +1,157 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var errIntercepted = errors.New("intercepted")
+
+// recordInterceptor appends "<name> <call>" before next and "<name> <call> done" after it
+func recordInterceptor(name string, l *sync.Mutex, events *[]string) Interceptor {
+	return InterceptorFunc(func(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error) {
+		l.Lock()
+		*events = append(*events, name+" "+call.Name)
+		l.Unlock()
+
+		res, err := next(ctx, call)
+
+		l.Lock()
+		*events = append(*events, name+" "+call.Name+" done")
+		l.Unlock()
+		return res, err
+	})
+}
+
+func TestInterceptors(t *testing.T) {
+	var (
+		hits   atomic.Int32
+		header atomic.Value
+	)
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		"svc/getThings": func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			header.Store(r.Header.Get("X-Request-Id"))
+			w.Write([]byte(`{"things":[]}`))
+		},
+	})
+
+	tests := []struct {
+		name         string
+		interceptors func(l *sync.Mutex, events *[]string) []Interceptor
+		wantEvents   []string
+		wantHeader   string
+		wantBody     string
+		wantHits     int32
+		wantErr      error
+	}{
+		{
+			name: "order",
+			interceptors: func(l *sync.Mutex, events *[]string) []Interceptor {
+				return []Interceptor{recordInterceptor("a", l, events), recordInterceptor("b", l, events)}
+			},
+			wantEvents: []string{
+				"a ServiceLookup", "b ServiceLookup", "b ServiceLookup done", "a ServiceLookup done",
+				"a AccessSecret", "b AccessSecret", "b AccessSecret done", "a AccessSecret done",
+				"a getThings", "b getThings", "b getThings done", "a getThings done",
+			},
+			wantBody: `{"things":[]}`,
+			wantHits: 1,
+		},
+		{
+			name: "short-circuit",
+			interceptors: func(l *sync.Mutex, events *[]string) []Interceptor {
+				return []Interceptor{
+					InterceptorFunc(func(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error) {
+						if call.Name == "getThings" {
+							return &Response{StatusCode: http.StatusOK, Body: "cached"}, nil
+						}
+						return next(ctx, call)
+					}),
+					recordInterceptor("inner", l, events),
+				}
+			},
+			wantEvents: []string{"inner ServiceLookup", "inner ServiceLookup done", "inner AccessSecret", "inner AccessSecret done"},
+			wantBody:   "cached",
+		},
+		{
+			name: "header",
+			interceptors: func(l *sync.Mutex, events *[]string) []Interceptor {
+				return []Interceptor{InterceptorFunc(func(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error) {
+					call.Header.Set("X-Request-Id", call.Service+"/"+call.Name)
+					return next(ctx, call)
+				})}
+			},
+			wantHeader: "svc/getThings",
+			wantBody:   `{"things":[]}`,
+			wantHits:   1,
+		},
+		{
+			name: "error",
+			interceptors: func(l *sync.Mutex, events *[]string) []Interceptor {
+				return []Interceptor{InterceptorFunc(func(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error) {
+					if call.Name == "getThings" {
+						return nil, fmt.Errorf("%s on %s: %w", call.Name, call.Node, errIntercepted)
+					}
+					return next(ctx, call)
+				})}
+			},
+			wantErr: errIntercepted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			header.Store("")
+
+			var (
+				l      sync.Mutex
+				events []string
+			)
+			cfg := NewPxGridConfig()
+			for _, i := range tt.interceptors(&l, &events) {
+				cfg.AddInterceptor(i)
+			}
+			c := newISEConsumer(t, srv, cfg)
+
+			res, err := c.Service("svc").AnyREST("getThings", map[string]any{}).Do(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Do() = %v, want %v", err, tt.wantErr)
+			}
+			if res.Body != tt.wantBody {
+				t.Fatalf("body = %q, want %q", res.Body, tt.wantBody)
+			}
+			if tt.wantEvents != nil && !slices.Equal(events, tt.wantEvents) {
+				t.Fatalf("events = %q, want %q", events, tt.wantEvents)
+			}
+			if got := header.Load().(string); got != tt.wantHeader {
+				t.Fatalf("header = %q, want %q", got, tt.wantHeader)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Fatalf("service called %d times, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestInterceptorSeesTransportError(t *testing.T) {
+	var seen error
+	cfg := NewPxGridConfig().AddInterceptor(InterceptorFunc(func(ctx context.Context, call *RESTCall, next RESTInvoker) (*Response, error) {
+		res, err := next(ctx, call)
+		seen = err
+		return res, err
+	}))
+	c := newTestConsumer(t, cfg)
+
+	// nothing listens on the port
+	_, err := c.RESTRequest(context.Background(), "https://127.0.0.1:1/pxgrid/control/ServiceLookup", map[string]any{}, RESTOptions{})
+	if err == nil || seen == nil || !errors.Is(err, seen) {
+		t.Fatalf("RESTRequest() = %v, interceptor saw %v, want the error of the request", err, seen)
+	}
+}
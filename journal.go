@@ -0,0 +1,551 @@
+package gopxgrid
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+)
+
+const (
+	// DefaultJournalSegmentSize is the size at which a new segment is started
+	DefaultJournalSegmentSize = 64 << 20
+
+	journalSegmentExt = ".seg"
+	journalOffsetsDir = "offsets"
+	// journalHeaderSize is the size of the record header: payload length,
+	// CRC-32C of the rest of the record, offset and timestamp
+	journalHeaderSize   = 4 + 4 + 8 + 8
+	journalMaxRecord    = 64 << 20
+	journalRetentionGap = time.Minute
+)
+
+var (
+	ErrJournalClosed  = errors.New("journal is closed")
+	ErrJournalCorrupt = errors.New("journal is corrupt")
+	ErrInvalidOffset  = errors.New("offset is not in the journal")
+	ErrInvalidName    = errors.New("invalid consumer name")
+
+	journalCRC = crc32.MakeTable(crc32.Castagnoli)
+)
+
+type (
+	// JournalConfig configures an on-disk journal of received messages
+	JournalConfig struct {
+		// Dir is the folder of the segments and the consumer offsets, it is created if missing
+		Dir string
+		// SegmentSize is the size at which a new segment file is started, DefaultJournalSegmentSize if 0
+		SegmentSize int64
+		// MaxSize removes the oldest segments once the journal is larger, no limit
+		// if 0. It is checked when a segment is started, the journal may exceed it
+		// by the active segment
+		MaxSize int64
+		// MaxAge removes segments without records younger than MaxAge, no limit if 0
+		MaxAge time.Duration
+		// Sync flushes every record to the disk before it is delivered, records
+		// survive a crash of the process but not of the host otherwise
+		Sync bool
+	}
+
+	// JournalRecord is a message stored in the journal
+	JournalRecord struct {
+		Offset  uint64
+		Time    time.Time
+		Topic   string
+		Message *stomp.Message
+	}
+
+	// Journal is a segmented append-only log of the received messages. Records
+	// get increasing offsets starting at 1, each one is checksummed. A torn
+	// write at the end of the journal is truncated when it is opened
+	Journal struct {
+		cfg JournalConfig
+		now func() time.Time
+
+		mu            sync.Mutex
+		segments      []journalSegment
+		active        *os.File
+		activeSize    int64
+		next          uint64
+		lastRetention time.Time
+		closed        bool
+	}
+
+	journalSegment struct {
+		base uint64
+		path string
+	}
+)
+
+// OpenJournal opens the journal in cfg.Dir, creating it if needed
+func OpenJournal(cfg JournalConfig) (*Journal, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("journal folder is required")
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DefaultJournalSegmentSize
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.Dir, journalOffsetsDir), 0o750); err != nil {
+		return nil, err
+	}
+
+	j := &Journal{cfg: cfg, now: time.Now}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	if err := j.applyRetention(); err != nil {
+		j.active.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	entries, err := os.ReadDir(j.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, journalSegmentExt) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, journalSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		j.segments = append(j.segments, journalSegment{base: base, path: filepath.Join(j.cfg.Dir, name)})
+	}
+	slices.SortFunc(j.segments, func(a, b journalSegment) int {
+		return cmp.Compare(a.base, b.base)
+	})
+
+	if len(j.segments) == 0 {
+		return j.roll(1)
+	}
+
+	last := j.segments[len(j.segments)-1]
+	f, err := os.OpenFile(last.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	// recover the end of the journal, records after the first invalid one are dropped
+	var (
+		size int64
+		next = last.base
+	)
+	r := bufio.NewReader(f)
+	for {
+		rec, n, err := readJournalRecord(r)
+		if err != nil || rec.Offset != next {
+			break
+		}
+		size += n
+		next++
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	j.active, j.activeSize, j.next = f, size, next
+	return nil
+}
+
+// roll starts a new segment with the base offset
+func (j *Journal) roll(base uint64) error {
+	path := filepath.Join(j.cfg.Dir, fmt.Sprintf("%020d%s", base, journalSegmentExt))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if j.active != nil {
+		if err := j.active.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		j.active.Close()
+	}
+
+	j.segments = append(j.segments, journalSegment{base: base, path: path})
+	j.active, j.activeSize, j.next = f, 0, base
+	return nil
+}
+
+// Append writes the message to the journal and returns its offset
+func (j *Journal) Append(topic string, msg *stomp.Message) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return 0, ErrJournalClosed
+	}
+
+	if j.activeSize >= j.cfg.SegmentSize {
+		if err := j.roll(j.next); err != nil {
+			return 0, err
+		}
+		if err := j.applyRetention(); err != nil {
+			return 0, err
+		}
+	} else if j.cfg.MaxAge > 0 && j.now().Sub(j.lastRetention) > journalRetentionGap {
+		if err := j.applyRetention(); err != nil {
+			return 0, err
+		}
+	}
+
+	offset := j.next
+	rec := encodeJournalRecord(offset, j.now(), topic, msg)
+	if _, err := j.active.Write(rec); err != nil {
+		// drop a partial write so that the segment stays readable
+		j.active.Truncate(j.activeSize)
+		j.active.Seek(j.activeSize, io.SeekStart)
+		return 0, err
+	}
+	if j.cfg.Sync {
+		if err := j.active.Sync(); err != nil {
+			return 0, err
+		}
+	}
+
+	j.activeSize += int64(len(rec))
+	j.next++
+	return offset, nil
+}
+
+// applyRetention removes the oldest segments beyond MaxSize or MaxAge, the active segment is kept
+func (j *Journal) applyRetention() error {
+	j.lastRetention = j.now()
+	if j.cfg.MaxSize <= 0 && j.cfg.MaxAge <= 0 {
+		return nil
+	}
+
+	sealed := j.segments[:len(j.segments)-1]
+	sizes := make([]int64, len(sealed))
+	expired := make([]bool, len(sealed))
+	total := j.activeSize
+	for i, s := range sealed {
+		st, err := os.Stat(s.path)
+		if err != nil {
+			return err
+		}
+		sizes[i] = st.Size()
+		total += sizes[i]
+		// the modification time is the time of the last record of a sealed segment
+		expired[i] = j.cfg.MaxAge > 0 && j.now().Sub(st.ModTime()) > j.cfg.MaxAge
+	}
+
+	removed := 0
+	for i, s := range sealed {
+		if !expired[i] && (j.cfg.MaxSize <= 0 || total <= j.cfg.MaxSize) {
+			break
+		}
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= sizes[i]
+		removed++
+	}
+	j.segments = slices.Delete(j.segments, 0, removed)
+	return nil
+}
+
+// FirstOffset returns the offset of the oldest record kept, it equals NextOffset if the journal is empty
+func (j *Journal) FirstOffset() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.segments[0].base
+}
+
+// NextOffset returns the offset of the next appended record
+func (j *Journal) NextOffset() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.next
+}
+
+// Scan calls fn with the records from the offset on, up to the last record
+// appended when Scan was called. Records removed by the retention meanwhile
+// are skipped. Scan stops at the first error of fn and returns it
+func (j *Journal) Scan(from uint64, fn func(*JournalRecord) error) error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return ErrJournalClosed
+	}
+	segments := slices.Clone(j.segments)
+	end := j.next
+	j.mu.Unlock()
+
+	if from >= end {
+		return nil
+	}
+	if from < segments[0].base {
+		return fmt.Errorf("%w: %d is older than %d", ErrInvalidOffset, from, segments[0].base)
+	}
+
+	for i, s := range segments {
+		if i+1 < len(segments) && segments[i+1].base <= from {
+			continue
+		}
+		last := end
+		if i+1 < len(segments) {
+			last = segments[i+1].base
+		}
+		if err := scanJournalSegment(s, from, last, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanJournalSegment(s journalSegment, from, end uint64, fn func(*JournalRecord) error) error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for next := s.base; next < end; next++ {
+		rec, _, err := readJournalRecord(r)
+		if err != nil {
+			return fmt.Errorf("%w: %s at offset %d: %w", ErrJournalCorrupt, filepath.Base(s.path), next, err)
+		}
+		if rec.Offset != next {
+			return fmt.Errorf("%w: %s has offset %d instead of %d", ErrJournalCorrupt, filepath.Base(s.path), rec.Offset, next)
+		}
+		if rec.Offset < from {
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OffsetAt returns the offset of the first record received at or after t,
+// NextOffset if there is none
+func (j *Journal) OffsetAt(t time.Time) (uint64, error) {
+	j.mu.Lock()
+	segments := slices.Clone(j.segments)
+	offset := j.next
+	j.mu.Unlock()
+
+	// start at the last segment beginning before t
+	from := segments[0].base
+	for i := len(segments) - 1; i > 0; i-- {
+		if first, ok := firstJournalRecord(segments[i]); ok && !first.Time.After(t) {
+			from = segments[i].base
+			break
+		}
+	}
+
+	errFound := errors.New("found")
+	err := j.Scan(from, func(rec *JournalRecord) error {
+		if rec.Time.Before(t) {
+			return nil
+		}
+		offset = rec.Offset
+		return errFound
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return 0, err
+	}
+	return offset, nil
+}
+
+func firstJournalRecord(s journalSegment) (*JournalRecord, bool) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	rec, _, err := readJournalRecord(bufio.NewReader(f))
+	return rec, err == nil
+}
+
+// CommitOffset stores the offset of the next record to be processed by the consumer
+func (j *Journal) CommitOffset(consumer string, offset uint64) error {
+	path, err := j.offsetPath(consumer)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(offset, 10)), 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CommittedOffset returns the offset stored by CommitOffset, FirstOffset if
+// nothing was committed. Offsets removed by the retention meanwhile are moved
+// to FirstOffset
+func (j *Journal) CommittedOffset(consumer string) (uint64, error) {
+	path, err := j.offsetPath(consumer)
+	if err != nil {
+		return 0, err
+	}
+
+	first := j.FirstOffset()
+	bts, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return first, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.ParseUint(strings.TrimSpace(string(bts)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: offset of %s: %w", ErrJournalCorrupt, consumer, err)
+	}
+	return max(offset, first), nil
+}
+
+func (j *Journal) offsetPath(consumer string) (string, error) {
+	if consumer == "" || consumer == "." || consumer == ".." || strings.ContainsAny(consumer, `/\`) {
+		return "", fmt.Errorf("%w %q", ErrInvalidName, consumer)
+	}
+	return filepath.Join(j.cfg.Dir, journalOffsetsDir, consumer), nil
+}
+
+// Close flushes and closes the journal
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+	return errors.Join(j.active.Sync(), j.active.Close())
+}
+
+// encodeJournalRecord returns the record: the header followed by the topic,
+// destination, content type, headers and body of the message
+func encodeJournalRecord(offset uint64, t time.Time, topic string, msg *stomp.Message) []byte {
+	b := make([]byte, journalHeaderSize, journalHeaderSize+len(msg.Body)+256)
+	binary.BigEndian.PutUint64(b[8:], offset)
+	binary.BigEndian.PutUint64(b[16:], uint64(t.UnixNano()))
+
+	b = appendJournalString(b, topic)
+	b = appendJournalString(b, msg.Destination)
+	b = appendJournalString(b, msg.ContentType)
+	n := 0
+	if msg.Header != nil {
+		n = msg.Header.Len()
+	}
+	b = binary.AppendUvarint(b, uint64(n))
+	for i := range n {
+		k, v := msg.Header.GetAt(i)
+		b = appendJournalString(b, k)
+		b = appendJournalString(b, v)
+	}
+	b = append(b, msg.Body...)
+
+	binary.BigEndian.PutUint32(b[0:], uint32(len(b)-journalHeaderSize))
+	binary.BigEndian.PutUint32(b[4:], crc32.Checksum(b[8:], journalCRC))
+	return b
+}
+
+func appendJournalString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// readJournalRecord reads the next record and returns its size in the segment
+func readJournalRecord(r io.Reader) (*JournalRecord, int64, error) {
+	var hdr [journalHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, 0, err
+	}
+	size := binary.BigEndian.Uint32(hdr[0:])
+	if size > journalMaxRecord {
+		return nil, 0, fmt.Errorf("record size %d exceeds the limit", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, err
+	}
+
+	crc := crc32.Update(crc32.Checksum(hdr[8:], journalCRC), journalCRC, payload)
+	if crc != binary.BigEndian.Uint32(hdr[4:]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+
+	rec := &JournalRecord{
+		Offset:  binary.BigEndian.Uint64(hdr[8:]),
+		Time:    time.Unix(0, int64(binary.BigEndian.Uint64(hdr[16:]))),
+		Message: &stomp.Message{Header: frame.NewHeader()},
+	}
+	d := journalDecoder{b: payload}
+	rec.Topic = d.string()
+	rec.Message.Destination = d.string()
+	rec.Message.ContentType = d.string()
+	n := d.uvarint()
+	for range min(n, uint64(len(payload))) {
+		rec.Message.Header.Add(d.string(), d.string())
+	}
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	rec.Message.Body = d.b
+	return rec, int64(journalHeaderSize + size), nil
+}
+
+type journalDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *journalDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errors.New("invalid record encoding")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *journalDecoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.b)) {
+		d.err = errors.New("invalid record encoding")
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
@@ -0,0 +1,482 @@
+package gopxgrid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func openTestJournal(t *testing.T, cfg JournalConfig) *Journal {
+	t.Helper()
+
+	if cfg.Dir == "" {
+		cfg.Dir = t.TempDir()
+	}
+	j, err := OpenJournal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func journalMessage(body string) *stomp.Message {
+	return &stomp.Message{
+		Destination: "/topic/com.cisco.ise.session",
+		ContentType: "application/json",
+		Header:      frame.NewHeader("message-id", body),
+		Body:        []byte(`"` + body + `"`),
+	}
+}
+
+func appendJournal(t *testing.T, j *Journal, topic string, bodies ...string) {
+	t.Helper()
+
+	for _, b := range bodies {
+		if _, err := j.Append(topic, journalMessage(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// scanJournal returns the message IDs of the records from the offset on
+func scanJournal(t *testing.T, j *Journal, from uint64) []string {
+	t.Helper()
+
+	var ids []string
+	err := j.Scan(from, func(rec *JournalRecord) error {
+		ids = append(ids, rec.Message.Header.Get("message-id"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func segmentPaths(t *testing.T, dir string) []string {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+journalSegmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestJournalAppendScan(t *testing.T) {
+	dir := t.TempDir()
+	// every record starts a new segment
+	j := openTestJournal(t, JournalConfig{Dir: dir, SegmentSize: 1})
+
+	for i, b := range []string{"a", "b", "c"} {
+		offset, err := j.Append("/topic/a", journalMessage(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != uint64(i+1) {
+			t.Fatalf("offset = %d, want %d", offset, i+1)
+		}
+	}
+	if n := len(segmentPaths(t, dir)); n != 3 {
+		t.Fatalf("%d segments, want 3", n)
+	}
+	j.Close()
+
+	j = openTestJournal(t, JournalConfig{Dir: dir, SegmentSize: 1})
+	if first, next := j.FirstOffset(), j.NextOffset(); first != 1 || next != 4 {
+		t.Fatalf("offsets = [%d, %d), want [1, 4)", first, next)
+	}
+
+	var recs []*JournalRecord
+	j.Scan(2, func(rec *JournalRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})
+	if len(recs) != 2 {
+		t.Fatalf("scanned %d records, want 2", len(recs))
+	}
+	rec := recs[0]
+	want := journalMessage("b")
+	if rec.Offset != 2 || rec.Topic != "/topic/a" || rec.Message.Destination != want.Destination ||
+		rec.Message.ContentType != want.ContentType || string(rec.Message.Body) != string(want.Body) ||
+		rec.Message.Header.Get("message-id") != "b" {
+		t.Fatalf("record = %+v, message = %+v", rec, rec.Message)
+	}
+
+	if _, err := j.Append("/topic/a", journalMessage("d")); err != nil {
+		t.Fatal(err)
+	}
+	if got := scanJournal(t, j, 1); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("records = %v", got)
+	}
+}
+
+func TestJournalRecoversTornTail(t *testing.T) {
+	tests := []struct {
+		name string
+		tear func(t *testing.T, path string)
+	}{
+		{
+			name: "partial record",
+			tear: func(t *testing.T, path string) {
+				st, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Truncate(path, st.Size()-3); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "partial header",
+			tear: func(t *testing.T, path string) {
+				st, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				size := int64(len(encodeJournalRecord(1, time.Time{}, "/topic/a", journalMessage("c"))))
+				if err := os.Truncate(path, st.Size()-size+journalHeaderSize/2); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "corrupt last record",
+			tear: func(t *testing.T, path string) {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b[len(b)-2] ^= 0xff
+				if err := os.WriteFile(path, b, 0o640); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			j := openTestJournal(t, JournalConfig{Dir: dir})
+			appendJournal(t, j, "/topic/a", "a", "b", "c")
+			j.Close()
+
+			tt.tear(t, segmentPaths(t, dir)[0])
+
+			j = openTestJournal(t, JournalConfig{Dir: dir})
+			if next := j.NextOffset(); next != 3 {
+				t.Fatalf("next offset = %d, want 3", next)
+			}
+			offset, err := j.Append("/topic/a", journalMessage("d"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if offset != 3 {
+				t.Fatalf("offset = %d, want the offset of the torn record", offset)
+			}
+			if got := scanJournal(t, j, 1); !slices.Equal(got, []string{"a", "b", "d"}) {
+				t.Fatalf("records = %v", got)
+			}
+		})
+	}
+}
+
+func TestJournalRejectsChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	j := openTestJournal(t, JournalConfig{Dir: dir, SegmentSize: 1})
+	appendJournal(t, j, "/topic/a", "a", "b", "c")
+
+	// corrupt the body of the record in the sealed middle segment
+	path := segmentPaths(t, dir)[1]
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-2] ^= 0xff
+	if err := os.WriteFile(path, b, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	err = j.Scan(1, func(rec *JournalRecord) error {
+		ids = append(ids, rec.Message.Header.Get("message-id"))
+		return nil
+	})
+	if !errors.Is(err, ErrJournalCorrupt) {
+		t.Fatalf("err = %v, want ErrJournalCorrupt", err)
+	}
+	if !slices.Equal(ids, []string{"a"}) {
+		t.Fatalf("records = %v, want the ones before the corrupt record", ids)
+	}
+}
+
+func TestJournalRetention(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		dir := t.TempDir()
+		size := int64(len(encodeJournalRecord(1, time.Time{}, "/topic/a", journalMessage("a"))))
+		j := openTestJournal(t, JournalConfig{Dir: dir, SegmentSize: 1, MaxSize: 3 * size})
+		appendJournal(t, j, "/topic/a", "a", "b", "c", "d", "e")
+
+		// the limit is checked when the segment of e is started, a is removed then
+		if first := j.FirstOffset(); first != 2 {
+			t.Fatalf("first offset = %d, want 2", first)
+		}
+		if n := len(segmentPaths(t, dir)); n != 4 {
+			t.Fatalf("%d segments, want 4", n)
+		}
+		if got := scanJournal(t, j, 2); !slices.Equal(got, []string{"b", "c", "d", "e"}) {
+			t.Fatalf("records = %v", got)
+		}
+		if err := j.Scan(1, func(*JournalRecord) error { return nil }); !errors.Is(err, ErrInvalidOffset) {
+			t.Fatalf("err = %v, want ErrInvalidOffset", err)
+		}
+
+		if err := j.CommitOffset("reader", 1); err != nil {
+			t.Fatal(err)
+		}
+		if offset, err := j.CommittedOffset("reader"); err != nil || offset != 2 {
+			t.Fatalf("committed offset = %d, %v, want the first offset", offset, err)
+		}
+	})
+
+	t.Run("age", func(t *testing.T) {
+		dir := t.TempDir()
+		j := openTestJournal(t, JournalConfig{Dir: dir, SegmentSize: 1, MaxAge: time.Hour})
+		appendJournal(t, j, "/topic/a", "a", "b")
+
+		now := time.Now().Add(2 * time.Hour)
+		j.now = func() time.Time { return now }
+		appendJournal(t, j, "/topic/a", "c")
+
+		// the segments of a and b expired, the active one of c is kept
+		if first := j.FirstOffset(); first != 3 {
+			t.Fatalf("first offset = %d, want 3", first)
+		}
+		if got := scanJournal(t, j, 3); !slices.Equal(got, []string{"c"}) {
+			t.Fatalf("records = %v", got)
+		}
+	})
+}
+
+func TestJournalOffsetAt(t *testing.T) {
+	for _, segmentSize := range []int64{1, DefaultJournalSegmentSize} {
+		t.Run(strconv.FormatInt(segmentSize, 10), func(t *testing.T) {
+			j := openTestJournal(t, JournalConfig{SegmentSize: segmentSize})
+			t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			now := t0
+			j.now = func() time.Time { return now }
+			for _, b := range []string{"a", "b", "c"} {
+				appendJournal(t, j, "/topic/a", b)
+				now = now.Add(time.Minute)
+			}
+
+			tests := []struct {
+				t    time.Time
+				want uint64
+			}{
+				{t: t0.Add(-time.Hour), want: 1},
+				{t: t0, want: 1},
+				{t: t0.Add(30 * time.Second), want: 2},
+				{t: t0.Add(time.Minute), want: 2},
+				{t: t0.Add(2 * time.Minute), want: 3},
+				{t: t0.Add(time.Hour), want: 4},
+			}
+			for _, tt := range tests {
+				got, err := j.OffsetAt(tt.t)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("OffsetAt(%v) = %d, want %d", tt.t, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestJournalCommitOffset(t *testing.T) {
+	j := openTestJournal(t, JournalConfig{})
+	appendJournal(t, j, "/topic/a", "a", "b")
+
+	if offset, err := j.CommittedOffset("reader"); err != nil || offset != 1 {
+		t.Fatalf("committed offset = %d, %v, want the first offset", offset, err)
+	}
+	if err := j.CommitOffset("reader", 3); err != nil {
+		t.Fatal(err)
+	}
+	if offset, err := j.CommittedOffset("reader"); err != nil || offset != 3 {
+		t.Fatalf("committed offset = %d, %v, want 3", offset, err)
+	}
+
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`} {
+		if err := j.CommitOffset(name, 1); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("CommitOffset(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+// translateAll runs translate over the live messages and returns all messages delivered to C
+func translateAll(t *testing.T, sub *Subscription[string], live ...*stomp.Message) []*Message[string] {
+	t.Helper()
+
+	sub.C = make(chan *Message[string], 16)
+	in := make(chan *stomp.Message, len(live))
+	for _, m := range live {
+		in <- m
+	}
+	close(in)
+	sub.translate(in, observers(nil), noop.NewTracerProvider().Tracer(""), testLogger)
+
+	var res []*Message[string]
+	for m := range sub.C {
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestSubscriptionReplay(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newJournal := func(t *testing.T) *Journal {
+		j := openTestJournal(t, JournalConfig{SegmentSize: 1})
+		now := t0
+		j.now = func() time.Time { return now }
+		for _, b := range []string{"a", "x", "b", "c"} {
+			topic := "/topic/a"
+			if b == "x" {
+				topic = "/topic/x"
+			}
+			appendJournal(t, j, topic, b)
+			now = now.Add(time.Minute)
+		}
+		return j
+	}
+
+	tests := []struct {
+		name        string
+		subscriber  func(s *subscriber[string]) Subscriber[string]
+		wantBodies  []string
+		wantOffsets []uint64
+	}{
+		{
+			name:        "from offset",
+			subscriber:  func(s *subscriber[string]) Subscriber[string] { return s.WithReplay(1) },
+			wantBodies:  []string{"a", "b", "c", "d"},
+			wantOffsets: []uint64{1, 3, 4, 5},
+		},
+		{
+			name:        "from later offset",
+			subscriber:  func(s *subscriber[string]) Subscriber[string] { return s.WithReplay(3) },
+			wantBodies:  []string{"b", "c", "d"},
+			wantOffsets: []uint64{3, 4, 5},
+		},
+		{
+			name:        "since",
+			subscriber:  func(s *subscriber[string]) Subscriber[string] { return s.WithReplaySince(t0.Add(90 * time.Second)) },
+			wantBodies:  []string{"b", "c", "d"},
+			wantOffsets: []uint64{3, 4, 5},
+		},
+		{
+			name:        "no replay",
+			subscriber:  func(s *subscriber[string]) Subscriber[string] { return s },
+			wantBodies:  []string{"d"},
+			wantOffsets: []uint64{5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newJournal(t)
+			s := &subscriber[string]{journal: j}
+			tt.subscriber(s)
+			replay, err := s.replayRange()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sub := &Subscription[string]{topic: "/topic/a", journal: j, replay: replay}
+			var (
+				bodies  []string
+				offsets []uint64
+			)
+			msgs := translateAll(t, sub, journalMessage("d"))
+			for i, m := range msgs {
+				// the live message is delivered after the replayed ones
+				if m.Err != nil || m.UnmarshalError != nil || m.Replayed != (i < len(msgs)-1) {
+					t.Fatalf("message %d = %+v", i, m)
+				}
+				bodies = append(bodies, m.Body)
+				offsets = append(offsets, m.Offset)
+			}
+			if !slices.Equal(bodies, tt.wantBodies) || !slices.Equal(offsets, tt.wantOffsets) {
+				t.Fatalf("delivered %v at %v, want %v at %v", bodies, offsets, tt.wantBodies, tt.wantOffsets)
+			}
+		})
+	}
+}
+
+func TestSubscriptionReplayEndsAtSubscribe(t *testing.T) {
+	j := openTestJournal(t, JournalConfig{})
+	appendJournal(t, j, "/topic/a", "a")
+
+	replay, err := (&subscriber[string]{journal: j}).WithReplay(1).(*subscriber[string]).replayRange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// journaled by another subscription of the topic sharing the journal
+	appendJournal(t, j, "/topic/a", "b")
+
+	sub := &Subscription[string]{topic: "/topic/a", journal: j, replay: replay}
+	var offsets []uint64
+	for _, m := range translateAll(t, sub, journalMessage("b")) {
+		offsets = append(offsets, m.Offset)
+	}
+	// b is delivered once, live
+	if !slices.Equal(offsets, []uint64{1, 3}) {
+		t.Fatalf("delivered offsets %v, want [1 3]", offsets)
+	}
+}
+
+func TestSubscriptionReplayErrors(t *testing.T) {
+	if _, err := (&subscriber[string]{}).WithReplay(1).(*subscriber[string]).replayRange(); !errors.Is(err, ErrNoJournal) {
+		t.Fatalf("replayRange() = %v, want ErrNoJournal", err)
+	}
+
+	j := openTestJournal(t, JournalConfig{})
+	appendJournal(t, j, "/topic/a", "a")
+	j.Close()
+
+	sub := &Subscription[string]{topic: "/topic/a", journal: j, replay: &journalReplay{from: 1, end: 2}}
+	msgs := translateAll(t, sub)
+	if len(msgs) != 1 || !msgs[0].Replayed || !errors.Is(msgs[0].Err, ErrJournalClosed) {
+		t.Fatalf("delivered %+v, want the error of the replay", msgs)
+	}
+}
+
+func TestTranslateReportsJournalError(t *testing.T) {
+	j := openTestJournal(t, JournalConfig{})
+	j.Close()
+
+	sub := &Subscription[string]{C: make(chan *Message[string], 1), topic: "/topic/a", journal: j}
+	in := make(chan *stomp.Message, 1)
+	in <- journalMessage("a")
+	close(in)
+	sub.translate(in, observers(nil), noop.NewTracerProvider().Tracer(""), testLogger)
+
+	m := <-sub.C
+	if !errors.Is(m.JournalError, ErrJournalClosed) || m.Offset != 0 {
+		t.Fatalf("journal error = %v at offset %d, want ErrJournalClosed", m.JournalError, m.Offset)
+	}
+	if m.Body != "a" {
+		t.Fatalf("body = %q, want the message delivered anyway", m.Body)
+	}
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type collector struct {
+	m *Metrics
+}
+
+// Collector returns a prometheus.Collector exposing the metrics
+func (m *Metrics) Collector() prometheus.Collector {
+	return &collector{m: m}
+}
+
+// Describe sends the descriptors of all families, which do not depend on
+// the collected samples
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, f := range c.m.Gather() {
+		ch <- familyDesc(f)
+	}
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for _, f := range c.m.Gather() {
+		desc := familyDesc(f)
+
+		for _, s := range f.Samples {
+			switch f.Type {
+			case TypeCounter:
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, s.Value, s.LabelValues...)
+			case TypeHistogram:
+				if s.Histogram == nil {
+					continue
+				}
+				buckets := make(map[float64]uint64, len(s.Histogram.Buckets))
+				for _, b := range s.Histogram.Buckets {
+					buckets[b.UpperBound] = b.Count
+				}
+				ch <- prometheus.MustNewConstHistogram(desc, s.Histogram.Count, s.Histogram.Sum, buckets, s.LabelValues...)
+			default:
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, s.Value, s.LabelValues...)
+			}
+		}
+	}
+}
+
+// familyDesc returns the descriptor of the family, counters get the _total suffix
+func familyDesc(f Family) *prometheus.Desc {
+	name := f.Name
+	if f.Type == TypeCounter {
+		name += "_total"
+	}
+	return prometheus.NewDesc(name, f.Help, f.LabelNames, nil)
+}
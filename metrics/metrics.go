@@ -0,0 +1,333 @@
+// Package metrics exports ISE system health and library internals
+// as a prometheus.Collector or in the OpenMetrics text format.
+package metrics
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gopxgrid "github.com/vkumov/go-pxgrid"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the REST latency histogram in seconds
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type (
+	MetricType string
+
+	// Family is a named group of samples sharing label names
+	Family struct {
+		Name       string
+		Help       string
+		Type       MetricType
+		LabelNames []string
+		Samples    []Sample
+	}
+
+	// Sample is a single value of a family, Histogram is set for histogram families only
+	Sample struct {
+		LabelValues []string
+		Value       float64
+		Histogram   *Histogram
+	}
+
+	Histogram struct {
+		Count uint64
+		Sum   float64
+		// Buckets hold cumulative counts per upper bound, +Inf excluded
+		Buckets []Bucket
+	}
+
+	Bucket struct {
+		UpperBound float64
+		Count      uint64
+	}
+
+	Option func(*Metrics)
+
+	// Metrics collects REST call statistics as an interceptor, websocket states and
+	// received messages as an observer, and system health from an optional HealthPoller
+	Metrics struct {
+		buckets []float64
+		health  *gopxgrid.HealthPoller
+		now     func() time.Time
+
+		rest   map[restKey]*restStats
+		ws     map[string]gopxgrid.WebSocketState
+		topics map[string]*topicStats
+
+		l sync.Mutex
+	}
+
+	restKey struct {
+		service, call, node string
+	}
+
+	restStats struct {
+		statuses map[string]uint64
+		count    uint64
+		sum      float64
+		buckets  []uint64
+	}
+
+	topicStats struct {
+		received        uint64
+		unmarshalErrors uint64
+	}
+
+	healthGauge struct {
+		name, help string
+		value      func(*gopxgrid.SysHealth) float64
+	}
+
+	performanceGauge struct {
+		name, help string
+		value      func(*gopxgrid.SysPerformance) float64
+	}
+)
+
+const (
+	TypeCounter   MetricType = "counter"
+	TypeGauge     MetricType = "gauge"
+	TypeHistogram MetricType = "histogram"
+)
+
+var (
+	_ gopxgrid.Interceptor = (*Metrics)(nil)
+	_ gopxgrid.Observer    = (*Metrics)(nil)
+)
+
+var healthGauges = []healthGauge{
+	{"pxgrid_ise_cpu_usage", "CPU usage of the ISE server in percent", func(h *gopxgrid.SysHealth) float64 { return h.CPUUsage }},
+	{"pxgrid_ise_memory_usage", "Memory usage of the ISE server in percent", func(h *gopxgrid.SysHealth) float64 { return h.MemoryUsage }},
+	{"pxgrid_ise_disk_usage_root", "Usage of the root partition of the ISE server in percent", func(h *gopxgrid.SysHealth) float64 { return h.DiskUsageRoot }},
+	{"pxgrid_ise_disk_usage_opt", "Usage of the opt partition of the ISE server in percent", func(h *gopxgrid.SysHealth) float64 { return h.DiskUsageOpt }},
+	{"pxgrid_ise_io_wait", "IO wait of the ISE server", func(h *gopxgrid.SysHealth) float64 { return h.IOWait }},
+	{"pxgrid_ise_load_average", "Load average of the ISE server", func(h *gopxgrid.SysHealth) float64 { return h.LoadAverage }},
+	{"pxgrid_ise_network_sent", "Network traffic sent by the ISE server", func(h *gopxgrid.SysHealth) float64 { return h.NetworkSent }},
+	{"pxgrid_ise_network_received", "Network traffic received by the ISE server", func(h *gopxgrid.SysHealth) float64 { return h.NetworkReceived }},
+}
+
+var performanceGauges = []performanceGauge{
+	{"pxgrid_ise_radius_rate", "RADIUS request rate of the ISE server", func(p *gopxgrid.SysPerformance) float64 { return p.RADIUSRate }},
+	{"pxgrid_ise_radius_count", "RADIUS request count of the ISE server", func(p *gopxgrid.SysPerformance) float64 { return p.RADIUSCount }},
+	{"pxgrid_ise_radius_latency", "RADIUS latency of the ISE server", func(p *gopxgrid.SysPerformance) float64 { return p.RADIUSLatency }},
+}
+
+// WithHealthPoller publishes the latest samples of the poller as gauges per server
+func WithHealthPoller(poller *gopxgrid.HealthPoller) Option {
+	return func(m *Metrics) {
+		m.health = poller
+	}
+}
+
+// WithLatencyBuckets sets the upper bounds of the REST latency histogram in seconds
+func WithLatencyBuckets(buckets ...float64) Option {
+	return func(m *Metrics) {
+		m.buckets = slices.Sorted(slices.Values(buckets))
+	}
+}
+
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		buckets: DefaultLatencyBuckets,
+		now:     time.Now,
+		rest:    make(map[restKey]*restStats),
+		ws:      make(map[string]gopxgrid.WebSocketState),
+		topics:  make(map[string]*topicStats),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Instrument registers the metrics as interceptor and observer of the config
+func (m *Metrics) Instrument(cfg *gopxgrid.PxGridConfig) *gopxgrid.PxGridConfig {
+	return cfg.AddInterceptor(m).AddObserver(m)
+}
+
+// Intercept counts the REST call and observes its latency
+func (m *Metrics) Intercept(ctx context.Context, call *gopxgrid.RESTCall, next gopxgrid.RESTInvoker) (*gopxgrid.Response, error) {
+	start := m.now()
+	resp, err := next(ctx, call)
+	elapsed := m.now().Sub(start).Seconds()
+
+	status := "error"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	key := restKey{service: call.Service, call: call.Name, node: call.Node}
+	s, ok := m.rest[key]
+	if !ok {
+		s = &restStats{statuses: make(map[string]uint64), buckets: make([]uint64, len(m.buckets))}
+		m.rest[key] = s
+	}
+	s.statuses[status]++
+	s.count++
+	s.sum += elapsed
+	if i, _ := slices.BinarySearch(m.buckets, elapsed); i < len(m.buckets) {
+		s.buckets[i]++
+	}
+
+	return resp, err
+}
+
+func (m *Metrics) WebSocketStateChanged(wsURL string, state gopxgrid.WebSocketState) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.ws[wsURL] = state
+}
+
+func (m *Metrics) MessageReceived(topic string, unmarshalErr error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	s, ok := m.topics[topic]
+	if !ok {
+		s = &topicStats{}
+		m.topics[topic] = s
+	}
+	s.received++
+	if unmarshalErr != nil {
+		s.unmarshalErrors++
+	}
+}
+
+// Gather returns the current state of all metrics
+func (m *Metrics) Gather() []Family {
+	var families []Family
+
+	m.l.Lock()
+	families = append(families, m.restFamilies()...)
+	families = append(families, m.wsFamily())
+	families = append(families, m.topicFamilies()...)
+	m.l.Unlock()
+
+	if m.health != nil {
+		families = append(families, m.healthFamilies()...)
+	}
+
+	for i := range families {
+		slices.SortFunc(families[i].Samples, func(a, b Sample) int {
+			return slices.Compare(a.LabelValues, b.LabelValues)
+		})
+	}
+	slices.SortFunc(families, func(a, b Family) int { return strings.Compare(a.Name, b.Name) })
+	return families
+}
+
+func (m *Metrics) restFamilies() []Family {
+	requests := Family{
+		Name:       "pxgrid_rest_requests",
+		Help:       "REST calls by service, call, node and HTTP status, status is error if no response was received",
+		Type:       TypeCounter,
+		LabelNames: []string{"service", "call", "node", "status"},
+	}
+	latency := Family{
+		Name:       "pxgrid_rest_request_duration_seconds",
+		Help:       "Latency of REST calls by service, call and node",
+		Type:       TypeHistogram,
+		LabelNames: []string{"service", "call", "node"},
+	}
+
+	for key, s := range m.rest {
+		for status, n := range s.statuses {
+			requests.Samples = append(requests.Samples, Sample{
+				LabelValues: []string{key.service, key.call, key.node, status},
+				Value:       float64(n),
+			})
+		}
+
+		h := &Histogram{Count: s.count, Sum: s.sum, Buckets: make([]Bucket, len(m.buckets))}
+		var cumulative uint64
+		for i, ub := range m.buckets {
+			cumulative += s.buckets[i]
+			h.Buckets[i] = Bucket{UpperBound: ub, Count: cumulative}
+		}
+		latency.Samples = append(latency.Samples, Sample{
+			LabelValues: []string{key.service, key.call, key.node},
+			Histogram:   h,
+		})
+	}
+
+	return []Family{requests, latency}
+}
+
+func (m *Metrics) wsFamily() Family {
+	f := Family{
+		Name:       "pxgrid_websocket_state",
+		Help:       "State of the websocket connection of a pubsub endpoint, 1 for the current state",
+		Type:       TypeGauge,
+		LabelNames: []string{"endpoint", "state"},
+	}
+
+	states := []gopxgrid.WebSocketState{gopxgrid.WebSocketConnecting, gopxgrid.WebSocketConnected, gopxgrid.WebSocketDisconnected}
+	for url, current := range m.ws {
+		for _, state := range states {
+			v := 0.0
+			if state == current {
+				v = 1
+			}
+			f.Samples = append(f.Samples, Sample{LabelValues: []string{url, string(state)}, Value: v})
+		}
+	}
+	return f
+}
+
+func (m *Metrics) topicFamilies() []Family {
+	received := Family{
+		Name:       "pxgrid_messages_received",
+		Help:       "Messages received per topic",
+		Type:       TypeCounter,
+		LabelNames: []string{"topic"},
+	}
+	unmarshalErrors := Family{
+		Name:       "pxgrid_message_unmarshal_errors",
+		Help:       "Messages per topic whose body could not be unmarshalled",
+		Type:       TypeCounter,
+		LabelNames: []string{"topic"},
+	}
+
+	for topic, s := range m.topics {
+		received.Samples = append(received.Samples, Sample{LabelValues: []string{topic}, Value: float64(s.received)})
+		unmarshalErrors.Samples = append(unmarshalErrors.Samples, Sample{LabelValues: []string{topic}, Value: float64(s.unmarshalErrors)})
+	}
+	return []Family{received, unmarshalErrors}
+}
+
+func (m *Metrics) healthFamilies() []Family {
+	families := make([]Family, 0, len(healthGauges)+len(performanceGauges))
+	for _, g := range healthGauges {
+		families = append(families, Family{Name: g.name, Help: g.help, Type: TypeGauge, LabelNames: []string{"server"}})
+	}
+	for _, g := range performanceGauges {
+		families = append(families, Family{Name: g.name, Help: g.help, Type: TypeGauge, LabelNames: []string{"server"}})
+	}
+
+	for _, server := range m.health.Servers() {
+		healths, perfs := m.health.History(server)
+		if len(healths) > 0 {
+			h := healths[len(healths)-1]
+			for i, g := range healthGauges {
+				families[i].Samples = append(families[i].Samples, Sample{LabelValues: []string{server}, Value: g.value(&h)})
+			}
+		}
+		if len(perfs) > 0 {
+			p := perfs[len(perfs)-1]
+			for i, g := range performanceGauges {
+				families[len(healthGauges)+i].Samples = append(families[len(healthGauges)+i].Samples, Sample{LabelValues: []string{server}, Value: g.value(&p)})
+			}
+		}
+	}
+	return families
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	gopxgrid "github.com/vkumov/go-pxgrid"
+)
+
+const testWSURL = "wss://ise1.example.com:8910/pxgrid/ise/pubsub"
+
+// newTestMetrics returns metrics fed with a fixed set of calls, states and messages,
+// the latency of a call is the duration its stub takes on a fake clock
+func newTestMetrics(t *testing.T) *Metrics {
+	t.Helper()
+
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New(WithLatencyBuckets(0.5, 0.1))
+	m.now = func() time.Time { return clock }
+
+	calls := []struct {
+		name    string
+		elapsed time.Duration
+		status  int
+	}{
+		{name: "getSessions", elapsed: 50 * time.Millisecond, status: 200},
+		{name: "getSessions", elapsed: 250 * time.Millisecond, status: 200},
+		{name: "getSessions", elapsed: time.Second, status: 503},
+		{name: "getSessions", elapsed: 2 * time.Second},
+	}
+	for _, c := range calls {
+		call := &gopxgrid.RESTCall{Service: gopxgrid.SessionDirectoryServiceName, Name: c.name, Node: "ise1"}
+		m.Intercept(context.Background(), call, func(ctx context.Context, call *gopxgrid.RESTCall) (*gopxgrid.Response, error) {
+			clock = clock.Add(c.elapsed)
+			if c.status == 0 {
+				return nil, errors.New("connection refused")
+			}
+			return &gopxgrid.Response{StatusCode: c.status}, nil
+		})
+	}
+
+	m.WebSocketStateChanged(testWSURL, gopxgrid.WebSocketConnecting)
+	m.WebSocketStateChanged(testWSURL, gopxgrid.WebSocketConnected)
+
+	m.MessageReceived("/topic/com.cisco.ise.session", nil)
+	m.MessageReceived("/topic/com.cisco.ise.session", errors.New("bad json"))
+	m.MessageReceived(`/topic/"quoted"`, nil)
+	return m
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestMetrics(t).WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "openmetrics.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Fatalf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTextEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New().WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// families without samples are still described
+	if got := buf.String(); !strings.HasPrefix(got, "# TYPE pxgrid_message_unmarshal_errors counter\n") || !strings.HasSuffix(got, "# EOF\n") {
+		t.Fatalf("WriteText() =\n%s", got)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	const want = `
+# HELP pxgrid_message_unmarshal_errors_total Messages per topic whose body could not be unmarshalled
+# TYPE pxgrid_message_unmarshal_errors_total counter
+pxgrid_message_unmarshal_errors_total{topic="/topic/\"quoted\""} 0
+pxgrid_message_unmarshal_errors_total{topic="/topic/com.cisco.ise.session"} 1
+# HELP pxgrid_messages_received_total Messages received per topic
+# TYPE pxgrid_messages_received_total counter
+pxgrid_messages_received_total{topic="/topic/\"quoted\""} 1
+pxgrid_messages_received_total{topic="/topic/com.cisco.ise.session"} 2
+# HELP pxgrid_rest_request_duration_seconds Latency of REST calls by service, call and node
+# TYPE pxgrid_rest_request_duration_seconds histogram
+pxgrid_rest_request_duration_seconds_bucket{call="getSessions",node="ise1",service="com.cisco.ise.session",le="0.1"} 1
+pxgrid_rest_request_duration_seconds_bucket{call="getSessions",node="ise1",service="com.cisco.ise.session",le="0.5"} 2
+pxgrid_rest_request_duration_seconds_bucket{call="getSessions",node="ise1",service="com.cisco.ise.session",le="+Inf"} 4
+pxgrid_rest_request_duration_seconds_sum{call="getSessions",node="ise1",service="com.cisco.ise.session"} 3.3
+pxgrid_rest_request_duration_seconds_count{call="getSessions",node="ise1",service="com.cisco.ise.session"} 4
+# HELP pxgrid_rest_requests_total REST calls by service, call, node and HTTP status, status is error if no response was received
+# TYPE pxgrid_rest_requests_total counter
+pxgrid_rest_requests_total{call="getSessions",node="ise1",service="com.cisco.ise.session",status="200"} 2
+pxgrid_rest_requests_total{call="getSessions",node="ise1",service="com.cisco.ise.session",status="503"} 1
+pxgrid_rest_requests_total{call="getSessions",node="ise1",service="com.cisco.ise.session",status="error"} 1
+# HELP pxgrid_websocket_state State of the websocket connection of a pubsub endpoint, 1 for the current state
+# TYPE pxgrid_websocket_state gauge
+pxgrid_websocket_state{endpoint="wss://ise1.example.com:8910/pxgrid/ise/pubsub",state="connected"} 1
+pxgrid_websocket_state{endpoint="wss://ise1.example.com:8910/pxgrid/ise/pubsub",state="connecting"} 0
+pxgrid_websocket_state{endpoint="wss://ise1.example.com:8910/pxgrid/ise/pubsub",state="disconnected"} 0
+`
+	if err := testutil.CollectAndCompare(newTestMetrics(t).Collector(), strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectorDescribe(t *testing.T) {
+	c := New().Collector()
+
+	ch := make(chan *prometheus.Desc, 16)
+	c.Describe(ch)
+	close(ch)
+
+	var descs []string
+	for d := range ch {
+		descs = append(descs, d.String())
+	}
+	if len(descs) != 5 {
+		t.Fatalf("Describe() sent %d descriptors, want 5:\n%s", len(descs), strings.Join(descs, "\n"))
+	}
+
+	// the pedantic registry fails if collected metrics were not described
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(newTestMetrics(t).Collector()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Fatal(err)
+	}
+}
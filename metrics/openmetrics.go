@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the OpenMetrics text format
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteText writes all metrics to w in the OpenMetrics text format
+func (m *Metrics) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range m.Gather() {
+		writeFamily(bw, f)
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+// Handler serves the metrics in the OpenMetrics text format
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		m.WriteText(w)
+	})
+}
+
+func writeFamily(w *bufio.Writer, f Family) {
+	w.WriteString("# TYPE " + f.Name + " " + string(f.Type) + "\n")
+	w.WriteString("# HELP " + f.Name + " " + f.Help + "\n")
+
+	for _, s := range f.Samples {
+		switch f.Type {
+		case TypeCounter:
+			writeSample(w, f.Name+"_total", f.LabelNames, s.LabelValues, "", "", s.Value)
+		case TypeHistogram:
+			if s.Histogram == nil {
+				continue
+			}
+			for _, b := range s.Histogram.Buckets {
+				writeSample(w, f.Name+"_bucket", f.LabelNames, s.LabelValues, "le", formatFloat(b.UpperBound), float64(b.Count))
+			}
+			writeSample(w, f.Name+"_bucket", f.LabelNames, s.LabelValues, "le", "+Inf", float64(s.Histogram.Count))
+			writeSample(w, f.Name+"_sum", f.LabelNames, s.LabelValues, "", "", s.Histogram.Sum)
+			writeSample(w, f.Name+"_count", f.LabelNames, s.LabelValues, "", "", float64(s.Histogram.Count))
+		default:
+			writeSample(w, f.Name, f.LabelNames, s.LabelValues, "", "", s.Value)
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, ln := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, ln, labelValues[i])
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	labelValueEscaper.WriteString(w, value)
+	w.WriteByte('"')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
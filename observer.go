@@ -0,0 +1,35 @@
+package gopxgrid
+
+type (
+	WebSocketState string
+
+	// Observer is notified about the state of websocket connections and about received messages.
+	// Methods are called synchronously and must not block.
+	Observer interface {
+		// WebSocketStateChanged is called when the connection of a PubSubEndpoint changes its state
+		WebSocketStateChanged(wsURL string, state WebSocketState)
+		// MessageReceived is called for every message received on the topic,
+		// unmarshalErr is set if the body could not be unmarshalled
+		MessageReceived(topic string, unmarshalErr error)
+	}
+
+	observers []Observer
+)
+
+const (
+	WebSocketConnecting   WebSocketState = "connecting"
+	WebSocketConnected    WebSocketState = "connected"
+	WebSocketDisconnected WebSocketState = "disconnected"
+)
+
+func (o observers) WebSocketStateChanged(wsURL string, state WebSocketState) {
+	for _, obs := range o {
+		obs.WebSocketStateChanged(wsURL, state)
+	}
+}
+
+func (o observers) MessageReceived(topic string, unmarshalErr error) {
+	for _, obs := range o {
+		obs.MessageReceived(topic, unmarshalErr)
+	}
+}
@@ -0,0 +1,131 @@
+package gopxgrid
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const spkiPinPrefix = "sha256/"
+
+// PinMismatchError is returned when no certificate presented by a pxGrid node
+// matches the configured pins. Pin is the SPKI pin of the node certificate
+type PinMismatchError struct {
+	Host    string
+	Subject string
+	Pin     string
+}
+
+var ErrInvalidPin = errors.New("invalid SPKI pin")
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("certificate %q of %s has unexpected public key %s", e.Subject, e.Host, e.Pin)
+}
+
+// SPKIPin returns the pin of the certificate public key in the "sha256/<base64>" form
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParseSPKIPin parses a SHA-256 pin of a public key, "sha256/<base64>",
+// plain base64 and hex forms are accepted
+func ParseSPKIPin(pin string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	s := strings.TrimPrefix(strings.TrimSpace(pin), spkiPinPrefix)
+	bts, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(bts) != sha256.Size {
+		bts, err = hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	}
+	if err != nil || len(bts) != sha256.Size {
+		return sum, fmt.Errorf("%w %q", ErrInvalidPin, pin)
+	}
+
+	copy(sum[:], bts)
+	return sum, nil
+}
+
+func (c *TLSConfig) pinsOf(host string) []string {
+	if pins, ok := c.HostPins[host]; ok {
+		return pins
+	}
+	return c.Pins
+}
+
+func (c *TLSConfig) validatePins() error {
+	var errs []error
+	for _, pin := range c.Pins {
+		if _, err := ParseSPKIPin(pin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for host, pins := range c.HostPins {
+		for _, pin := range pins {
+			if _, err := ParseSPKIPin(pin); err != nil {
+				errs = append(errs, fmt.Errorf("host %s: %w", host, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyPins returns the tls.Config.VerifyConnection check of the host pins,
+// nil if nothing is pinned. The server name is used if host is empty, it is
+// empty for IP addresses though. The check runs after the CA validation and
+// matches the certificates of the verified chains. With InsecureTLS nothing
+// is verified, so only the leaf certificate is matched as the other
+// certificates sent by the peer do not have to belong to it
+func (c *TLSConfig) verifyPins(host string) func(tls.ConnectionState) error {
+	if len(c.Pins) == 0 && len(c.HostPins) == 0 {
+		return nil
+	}
+
+	return func(cs tls.ConnectionState) error {
+		host := host
+		if host == "" {
+			host = cs.ServerName
+		}
+		pins := c.pinsOf(host)
+		if len(pins) == 0 {
+			return nil
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("no certificate presented by %s", host)
+		}
+
+		candidates := cs.PeerCertificates[:1]
+		if !c.InsecureTLS {
+			candidates = nil
+			for _, chain := range cs.VerifiedChains {
+				candidates = append(candidates, chain...)
+			}
+		}
+
+		for _, pin := range pins {
+			want, err := ParseSPKIPin(pin)
+			if err != nil {
+				return err
+			}
+			for _, cert := range candidates {
+				got := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if subtle.ConstantTimeCompare(got[:], want[:]) == 1 {
+					return nil
+				}
+			}
+		}
+
+		leaf := cs.PeerCertificates[0]
+		return &PinMismatchError{
+			Host:    host,
+			Subject: leaf.Subject.String(),
+			Pin:     SPKIPin(leaf),
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package gopxgrid
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestParseSPKIPin(t *testing.T) {
+	sum := sha256.Sum256([]byte("key"))
+	tests := []struct {
+		name    string
+		pin     string
+		wantErr bool
+	}{
+		{name: "prefixed", pin: "sha256/" + b64(sum[:])},
+		{name: "base64", pin: b64(sum[:])},
+		{name: "hex", pin: hex.EncodeToString(sum[:])},
+		{name: "hex with colons", pin: strings.ToUpper(colons(hex.EncodeToString(sum[:])))},
+		{name: "short", pin: "sha256/AAAA", wantErr: true},
+		{name: "garbage", pin: "not a pin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSPKIPin(tt.pin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSPKIPin() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != sum {
+				t.Fatalf("ParseSPKIPin() = %x, want %x", got, sum)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPin) {
+				t.Fatalf("ParseSPKIPin() = %v, want ErrInvalidPin", err)
+			}
+		})
+	}
+}
+
+func TestVerifyPins(t *testing.T) {
+	ca, caKey := newTestCert(t, "ca", nil, nil)
+	leaf, _ := newTestCert(t, "leaf", ca, caKey)
+	// extra is sent by the peer but is not part of the verified chain
+	extra, _ := newTestCert(t, "extra", nil, nil)
+
+	peer := []*x509.Certificate{leaf, extra, ca}
+	verified := [][]*x509.Certificate{{leaf, ca}}
+
+	tests := []struct {
+		name     string
+		insecure bool
+		pins     []string
+		hostPins map[string][]string
+		wantErr  bool
+	}{
+		{name: "leaf", pins: []string{SPKIPin(leaf)}},
+		{name: "ca of the verified chain", pins: []string{SPKIPin(ca)}},
+		{name: "unverified certificate", pins: []string{SPKIPin(extra)}, wantErr: true},
+		{name: "one of several pins", pins: []string{SPKIPin(extra), SPKIPin(leaf)}},
+		{name: "insecure leaf", insecure: true, pins: []string{SPKIPin(leaf)}},
+		{name: "insecure ca", insecure: true, pins: []string{SPKIPin(ca)}, wantErr: true},
+		{name: "host pins replace pins", pins: []string{SPKIPin(leaf)}, hostPins: map[string][]string{"ise": {SPKIPin(extra)}}, wantErr: true},
+		{name: "pins of other hosts", hostPins: map[string][]string{"other": {SPKIPin(extra)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &TLSConfig{InsecureTLS: tt.insecure, Pins: tt.pins, HostPins: tt.hostPins}
+			cs := tls.ConnectionState{ServerName: "ise", PeerCertificates: peer}
+			if !tt.insecure {
+				cs.VerifiedChains = verified
+			}
+
+			err := c.verifyPins("")(cs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyPins() = %v, wantErr %v", err, tt.wantErr)
+			}
+			var mismatch *PinMismatchError
+			if err != nil && (!errors.As(err, &mismatch) || mismatch.Pin != SPKIPin(leaf) || mismatch.Host != "ise") {
+				t.Fatalf("verifyPins() = %v, want a mismatch of the leaf of ise", err)
+			}
+		})
+	}
+
+	if (&TLSConfig{}).verifyPins("ise") != nil {
+		t.Fatal("verifyPins() is set without pins")
+	}
+}
+
+func TestHostPinsConcurrentRequests(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	// the hosts reach the same server, only the pins of 127.0.0.1 match
+	cfg := NewPxGridConfig().
+		AddHostPin("127.0.0.1", SPKIPin(srv.Certificate())).
+		AddHostPin("localhost", "sha256/"+b64(make([]byte, sha256.Size)))
+	c := newTestConsumer(t, cfg)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		host := "127.0.0.1"
+		if i%2 == 1 {
+			host = "localhost"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.svc.NewRequest(context.Background()).Post("https://"+host+port+"/", map[string]any{})
+			var mismatch *PinMismatchError
+			if host == "127.0.0.1" && err != nil {
+				t.Errorf("request to %s: %v", host, err)
+			}
+			if host == "localhost" && !errors.As(err, &mismatch) {
+				t.Errorf("request to %s = %v, want a pin mismatch", host, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func b64(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func colons(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(s[i : i+2])
+	}
+	return b.String()
+}
@@ -0,0 +1,235 @@
+package gopxgrid
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyConfig configures the proxy of REST and websocket connections. Host
+// names are resolved by the proxy, the DNS config applies to direct
+// connections and to the proxy address only
+type ProxyConfig struct {
+	// URL is the proxy of all hosts, http://host:port for HTTP CONNECT or
+	// socks5://host:port, credentials are taken from the user info. It applies
+	// to localhost and loopback addresses too. The HTTPS_PROXY and NO_PROXY
+	// environment variables are used if URL is empty, those never apply to loopback hosts
+	URL string
+	// Bypass are the hosts connected directly in the NO_PROXY format: host
+	// names, .domain suffixes, IP addresses and CIDRs, optionally with a port
+	Bypass []string
+	// Direct disables proxies, the environment variables are ignored too
+	Direct bool
+}
+
+var ErrProxyConnect = errors.New("proxy CONNECT failed")
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+func parseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, http and socks5 are supported", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", s)
+	}
+	return u, nil
+}
+
+func (c *ProxyConfig) validate() error {
+	if c.Direct || c.URL == "" {
+		return nil
+	}
+	_, err := parseProxyURL(c.URL)
+	return err
+}
+
+// proxyFunc returns the proxy of a target host:port, nil if it is dialed directly
+func (c *ProxyConfig) proxyFunc() func(addr string) (*url.URL, error) {
+	if c.Direct {
+		return func(string) (*url.URL, error) { return nil, nil }
+	}
+
+	// the explicit proxy applies to loopback targets too, httpproxy always
+	// connects to those directly
+	if c.URL != "" {
+		return func(addr string) (*url.URL, error) {
+			if proxyBypassed(c.Bypass, addr) {
+				return nil, nil
+			}
+			return parseProxyURL(c.URL)
+		}
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	if len(c.Bypass) > 0 {
+		cfg.NoProxy = strings.Join(append([]string{cfg.NoProxy}, c.Bypass...), ",")
+	}
+
+	fn := cfg.ProxyFunc()
+	return func(addr string) (*url.URL, error) {
+		u, err := fn(&url.URL{Scheme: "https", Host: addr})
+		if err != nil || u == nil {
+			return nil, err
+		}
+		return parseProxyURL(u.String())
+	}
+}
+
+// proxyBypassed matches the host:port with the NO_PROXY format entries: "*",
+// CIDRs, IP addresses and host names which match their subdomains too, a
+// leading dot matches the subdomains only. An entry with a port matches that port only
+func proxyBypassed(bypass []string, addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	ip, ipErr := netip.ParseAddr(host)
+	ip = ip.Unmap()
+
+	for _, e := range bypass {
+		e = strings.ToLower(strings.TrimSpace(e))
+		switch {
+		case e == "":
+			continue
+		case e == "*":
+			return true
+		}
+		if prefix, err := netip.ParsePrefix(e); err == nil {
+			if ipErr == nil && prefix.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		eHost, ePort := e, ""
+		if h, p, err := net.SplitHostPort(e); err == nil {
+			eHost, ePort = h, p
+		}
+		if ePort != "" && ePort != port {
+			continue
+		}
+		eHost = strings.TrimSuffix(strings.Trim(eHost, "[]"), ".")
+		if eIP, err := netip.ParseAddr(eHost); err == nil {
+			if ipErr == nil && eIP.Unmap() == ip {
+				return true
+			}
+			continue
+		}
+
+		if strings.HasPrefix(eHost, "*.") {
+			eHost = eHost[1:]
+		}
+		if strings.HasPrefix(eHost, ".") {
+			if strings.HasSuffix(host, eHost) {
+				return true
+			}
+			continue
+		}
+		if host == eHost || strings.HasSuffix(host, "."+eHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialProxy connects to addr through the proxy, the proxy itself is dialed with direct
+func dialProxy(ctx context.Context, proxyURL *url.URL, direct dialFunc, network, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "8080"
+		if proxyURL.Scheme != "http" {
+			port = "1080"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	if proxyURL.Scheme != "http" {
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		d, err := proxy.SOCKS5("tcp", proxyAddr, auth, direct)
+		if err != nil {
+			return nil, err
+		}
+		return d.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	}
+
+	conn, err := direct(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := httpConnect(ctx, conn, proxyURL, addr); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func httpConnect(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	defer conn.SetDeadline(time.Time{})
+	// unblock the handshake if the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		creds := proxyURL.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("%w: %w", ErrProxyConnect, err)
+	}
+
+	// a proxy does not send data before the TLS handshake of the client, so
+	// nothing read ahead by the buffered reader is lost
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %w", ErrProxyConnect, err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s to %s: %s", ErrProxyConnect, proxyURL.Redacted(), addr, res.Status)
+	}
+	return nil
+}
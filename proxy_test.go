@@ -0,0 +1,255 @@
+package gopxgrid
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// testProxy is an HTTP CONNECT or SOCKS5 proxy requiring the user "user" with the password "secret"
+type testProxy struct {
+	url string
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func (p *testProxy) dialed() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+func newTestProxy(t *testing.T, scheme string) *testProxy {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	p := &testProxy{url: scheme + "://user:secret@" + ln.Addr().String()}
+	serve := p.serveConnect
+	if scheme == "socks5" {
+		serve = p.serveSOCKS5
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *testProxy) relay(conn net.Conn, r io.Reader, target string) {
+	p.mu.Lock()
+	p.targets = append(p.targets, target)
+	p.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	go func() {
+		io.Copy(upstream, r)
+		upstream.Close()
+	}()
+	io.Copy(conn, upstream)
+}
+
+func (p *testProxy) serveConnect(conn net.Conn) {
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	if req.Method != http.MethodConnect || req.Header.Get("Proxy-Authorization") != auth {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return
+	}
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	p.relay(conn, br, req.Host)
+}
+
+func (p *testProxy) serveSOCKS5(conn net.Conn) {
+	defer conn.Close()
+
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return nil
+		}
+		return b
+	}
+
+	// greeting, the username and password method is required
+	hdr := read(2)
+	if hdr == nil || hdr[0] != 5 || read(int(hdr[1])) == nil {
+		return
+	}
+	conn.Write([]byte{5, 2})
+
+	// RFC 1929 authentication
+	ver := read(2)
+	if ver == nil {
+		return
+	}
+	user := read(int(ver[1]))
+	plen := read(1)
+	if user == nil || plen == nil {
+		return
+	}
+	pass := read(int(plen[0]))
+	if string(user) != "user" || string(pass) != "secret" {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+
+	req := read(4)
+	if req == nil || req[1] != 1 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		host = net.IP(read(4)).String()
+	case 4:
+		host = net.IP(read(16)).String()
+	case 3:
+		host = string(read(int(read(1)[0])))
+	default:
+		return
+	}
+	port := binary.BigEndian.Uint16(read(2))
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	p.relay(conn, conn, net.JoinHostPort(host, strconv.Itoa(int(port))))
+}
+
+func TestProxyFunc(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "skip.example.com")
+
+	tests := []struct {
+		name string
+		cfg  ProxyConfig
+		addr string
+		want string
+	}{
+		{name: "direct", cfg: ProxyConfig{Direct: true}, addr: "ise.example.com:8910"},
+		{name: "environment", addr: "ise.example.com:8910", want: "http://env-proxy:3128"},
+		{name: "environment no proxy", addr: "skip.example.com:8910"},
+		{name: "environment loopback", addr: "127.0.0.1:8910"},
+		{name: "environment bypass", cfg: ProxyConfig{Bypass: []string{"ise.example.com"}}, addr: "ise.example.com:8910"},
+		{name: "url", cfg: ProxyConfig{URL: "socks5://proxy:1080"}, addr: "ise.example.com:8910", want: "socks5://proxy:1080"},
+		{name: "url ignores environment", cfg: ProxyConfig{URL: "http://proxy:8080"}, addr: "skip.example.com:8910", want: "http://proxy:8080"},
+		{name: "url localhost", cfg: ProxyConfig{URL: "http://proxy:8080"}, addr: "localhost:8910", want: "http://proxy:8080"},
+		{name: "url loopback", cfg: ProxyConfig{URL: "http://proxy:8080"}, addr: "127.0.0.1:8910", want: "http://proxy:8080"},
+		{name: "url ipv6 loopback", cfg: ProxyConfig{URL: "http://proxy:8080"}, addr: "[::1]:8910", want: "http://proxy:8080"},
+		{name: "bypass all", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"*"}}, addr: "ise.example.com:8910"},
+		{name: "bypass domain", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"example.com"}}, addr: "ise.example.com:8910"},
+		{name: "bypass domain itself", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"example.com"}}, addr: "example.com:8910"},
+		{name: "bypass suffix", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{".example.com"}}, addr: "example.com:8910", want: "http://proxy:8080"},
+		{name: "bypass no partial label", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"ample.com"}}, addr: "example.com:8910", want: "http://proxy:8080"},
+		{name: "bypass port", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"ise.example.com:8910"}}, addr: "ise.example.com:8910"},
+		{name: "bypass other port", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"ise.example.com:443"}}, addr: "ise.example.com:8910", want: "http://proxy:8080"},
+		{name: "bypass ip", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"127.0.0.1"}}, addr: "127.0.0.1:8910"},
+		{name: "bypass ipv6", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"::1"}}, addr: "[::1]:8910"},
+		{name: "bypass cidr", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"10.0.0.0/8"}}, addr: "10.1.2.3:8910"},
+		{name: "bypass cidr miss", cfg: ProxyConfig{URL: "http://proxy:8080", Bypass: []string{"10.0.0.0/8"}}, addr: "192.0.2.1:8910", want: "http://proxy:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := tt.cfg.proxyFunc()(tt.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if u != nil {
+				got = u.String()
+			}
+			if got != tt.want {
+				t.Fatalf("proxy of %s = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyConnections(t *testing.T) {
+	srv := newISEServer(t, nil)
+	u, _ := url.Parse(srv.URL)
+
+	tests := []struct {
+		name   string
+		scheme string
+		direct bool
+	}{
+		{name: "direct", scheme: "http", direct: true},
+		{name: "http connect", scheme: "http"},
+		{name: "socks5", scheme: "socks5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProxy(t, tt.scheme)
+			cfg := NewPxGridConfig()
+			if tt.direct {
+				t.Setenv("HTTPS_PROXY", p.url)
+				cfg.Proxy = ProxyConfig{Direct: true}
+			} else {
+				cfg.SetProxy(p.url)
+			}
+			c := newISEConsumer(t, srv, cfg)
+
+			if _, err := c.ServiceLookup(context.Background(), "com.cisco.ise.session"); err != nil {
+				t.Fatal(err)
+			}
+
+			targets := p.dialed()
+			if tt.direct {
+				if len(targets) != 0 {
+					t.Fatalf("proxy dialed %v, want a direct connection", targets)
+				}
+				return
+			}
+			if len(targets) == 0 || targets[0] != u.Host {
+				t.Fatalf("proxy dialed %v, want %s", targets, u.Host)
+			}
+		})
+	}
+}
+
+func TestProxyConnectAuthRequired(t *testing.T) {
+	p := newTestProxy(t, "http")
+	proxyURL, err := url.Parse(p.url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyURL.User = url.UserPassword("user", "wrong")
+
+	var d net.Dialer
+	_, err = dialProxy(context.Background(), proxyURL, d.DialContext, "tcp", "192.0.2.1:8910")
+	if !errors.Is(err, ErrProxyConnect) {
+		t.Fatalf("err = %v, want ErrProxyConnect", err)
+	}
+	if len(p.dialed()) != 0 {
+		t.Fatal("proxy connected without credentials")
+	}
+}
@@ -0,0 +1,93 @@
+package gopxgrid
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampLayout is the layout of timestamps sent to and received from ISE,
+// e.g. 2024-03-01T15:15:21.000-08:00 as in the pxGrid session directory
+// examples. UTC is written as +00:00, not as Z
+const TimestampLayout = "2006-01-02T15:04:05.000-07:00"
+
+// timestampLayouts are the layouts accepted when parsing ISE timestamps,
+// fractional seconds are accepted by all of them
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05",
+}
+
+// FormatTimestamp formats t the way ISE expects timestamps, zero time is formatted as empty string
+func FormatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(TimestampLayout)
+}
+
+// PxGridTime is a timestamp received from ISE.
+// It keeps the original text next to the parsed time, the text is available via Raw.
+type PxGridTime struct {
+	Time time.Time
+
+	raw string
+}
+
+// NewPxGridTime wraps t into PxGridTime
+func NewPxGridTime(t time.Time) PxGridTime {
+	return PxGridTime{Time: t}
+}
+
+// ParsePxGridTime parses an ISE timestamp, with or without milliseconds and
+// with or without the time zone offset. Timestamps without offset are in UTC.
+// If the text could not be parsed the time is zero and the text is still available via Raw.
+func ParsePxGridTime(s string) (PxGridTime, error) {
+	if s == "" {
+		return PxGridTime{}, nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return PxGridTime{Time: t, raw: s}, nil
+		}
+	}
+
+	return PxGridTime{raw: s}, fmt.Errorf("unknown timestamp format: %q", s)
+}
+
+// Raw returns the original text of the timestamp
+func (t PxGridTime) Raw() string {
+	return t.raw
+}
+
+// IsZero reports whether the timestamp is empty or could not be parsed
+func (t PxGridTime) IsZero() bool {
+	return t.Time.IsZero()
+}
+
+// String returns the original text if available, the formatted time otherwise
+func (t PxGridTime) String() string {
+	if t.raw != "" {
+		return t.raw
+	}
+	return FormatTimestamp(t.Time)
+}
+
+// MarshalText returns the original text if available, the formatted time otherwise.
+// Zero time is marshaled as empty string
+func (t PxGridTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText parses the timestamp with ParsePxGridTime, empty text is zero time.
+// A timestamp in an unknown format does not fail the decoding of the whole
+// record, the time is zero and the text is kept in Raw
+func (t *PxGridTime) UnmarshalText(b []byte) error {
+	*t, _ = ParsePxGridTime(string(b))
+	return nil
+}
@@ -0,0 +1,88 @@
+package gopxgrid
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPxGridTimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantRaw string
+		wantErr bool
+	}{
+		{name: "offset", in: `"2024-03-01T15:15:21.005-08:00"`, want: time.Date(2024, 3, 1, 23, 15, 21, 5e6, time.UTC), wantRaw: "2024-03-01T15:15:21.005-08:00"},
+		{name: "utc", in: `"2024-03-01T15:15:21Z"`, want: time.Date(2024, 3, 1, 15, 15, 21, 0, time.UTC)},
+		{name: "no colon in offset", in: `"2024-03-01T15:15:21.5+0100"`, want: time.Date(2024, 3, 1, 14, 15, 21, 5e8, time.UTC)},
+		{name: "no offset", in: `"2024-03-01 15:15:21"`, want: time.Date(2024, 3, 1, 15, 15, 21, 0, time.UTC)},
+		{name: "empty", in: `""`},
+		{name: "null", in: `null`},
+		{name: "unknown format", in: `"yesterday"`, wantRaw: "yesterday"},
+		{name: "number", in: `1709305721000`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				Timestamp PxGridTime `json:"timestamp"`
+			}
+			err := json.Unmarshal([]byte(`{"timestamp":`+tt.in+`}`), &v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !v.Timestamp.Time.Equal(tt.want) {
+				t.Fatalf("time = %v, want %v", v.Timestamp.Time, tt.want)
+			}
+			if tt.wantRaw != "" && v.Timestamp.Raw() != tt.wantRaw {
+				t.Fatalf("raw = %q, want %q", v.Timestamp.Raw(), tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestSessionUnknownTimestamp(t *testing.T) {
+	var s Session
+	err := json.Unmarshal([]byte(`{"timestamp":"2024-03-01T15:15:21.005-08:00","state":"STARTED","macAddress":"00:11:22:33:44:55","endpointCheckTime":"01/03/2024 15:15"}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MacAddress != "00:11:22:33:44:55" || s.Timestamp.IsZero() {
+		t.Fatalf("session = %+v, want the other fields decoded", s)
+	}
+	if !s.EndpointCheckTime.IsZero() || s.EndpointCheckTime.Raw() != "01/03/2024 15:15" {
+		t.Fatalf("endpointCheckTime = %v (raw %q), want zero time with the raw text", s.EndpointCheckTime.Time, s.EndpointCheckTime.Raw())
+	}
+	if s.EndpointCheckTime.String() != "01/03/2024 15:15" {
+		t.Fatalf("String() = %q, want the raw text", s.EndpointCheckTime.String())
+	}
+}
+
+func TestPxGridTimeMarshalJSON(t *testing.T) {
+	parsed, err := ParsePxGridTime("2024-03-01 15:15:21")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   PxGridTime
+		want string
+	}{
+		{name: "zero", in: PxGridTime{}, want: `""`},
+		{name: "raw kept", in: parsed, want: `"2024-03-01 15:15:21"`},
+		{name: "formatted", in: NewPxGridTime(time.Date(2024, 3, 1, 15, 15, 21, 0, time.UTC)), want: `"2024-03-01T15:15:21.000+00:00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Fatalf("Marshal() = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package gopxgrid
 import (
 	"context"
 	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -37,6 +39,7 @@ type PxGridService interface {
 
 type GenericRESTCaller interface {
 	AnyREST(call string, payload map[string]any) CallFinalizer[any]
+	AnyRESTWithMethod(method, call string, payload any) CallFinalizer[any]
 }
 
 var _ PxGridService = (*pxGridService)(nil)
@@ -55,7 +58,7 @@ func (s *pxGridService) Name() string {
 
 // Lookup retrieves the service nodes from the controller
 func (s *pxGridService) Lookup(ctx context.Context) error {
-	s.log.Debug("Looking up service", "service", s.name)
+	s.log.DebugContext(ctx, "Looking up service", "service", s.name)
 	r, err := s.ctrl.ServiceLookup(ctx, s.name)
 	if err != nil {
 		return err
@@ -67,7 +70,7 @@ func (s *pxGridService) Lookup(ctx context.Context) error {
 
 // CheckNodes ensures that the service has nodes
 func (s *pxGridService) CheckNodes(ctx context.Context) error {
-	s.log.Debug("Checking nodes for service", "service", s.name)
+	s.log.DebugContext(ctx, "Checking nodes for service", "service", s.name)
 	if len(s.nodes) == 0 {
 		err := s.Lookup(ctx)
 		if err != nil {
@@ -75,7 +78,7 @@ func (s *pxGridService) CheckNodes(ctx context.Context) error {
 		}
 	}
 
-	s.log.Debug("Nodes found for service", "service", s.name, "nodes", len(s.nodes))
+	s.log.DebugContext(ctx, "Nodes found for service", "service", s.name, "nodes", len(s.nodes))
 	if len(s.nodes) == 0 {
 		return ErrServiceUnavailable
 	}
@@ -85,7 +88,7 @@ func (s *pxGridService) CheckNodes(ctx context.Context) error {
 
 // UpdateNodeSecret retrieves the secret for a node by index
 func (s *pxGridService) UpdateNodeSecret(ctx context.Context, idx int) error {
-	s.log.Debug("Updating secret for node", "service", s.name, "node", idx)
+	s.log.DebugContext(ctx, "Updating secret for node", "service", s.name, "node", idx)
 	if idx < 0 || idx >= len(s.nodes) {
 		return fmt.Errorf("invalid node index %d", idx)
 	}
@@ -101,7 +104,7 @@ func (s *pxGridService) UpdateNodeSecret(ctx context.Context, idx int) error {
 
 // UpdateNodeSecretByName retrieves the secret for a node by name
 func (s *pxGridService) UpdateNodeSecretByName(ctx context.Context, nodeName string) error {
-	s.log.Debug("Updating secret for node", "service", s.name, "node", nodeName)
+	s.log.DebugContext(ctx, "Updating secret for node", "service", s.name, "node", nodeName)
 	idx, err := s.FindNodeIndexByName(nodeName)
 	if err != nil {
 		return err
@@ -197,11 +200,19 @@ func (s *pxGridService) On(topicProperty string) Subscriber[any] {
 	return newSubscriber[any](s, topicProperty, nil)
 }
 
+// AnyREST calls a REST endpoint of the service using POST, the call is not
+// retried as it may change data
 func (s *pxGridService) AnyREST(call string, payload map[string]any) CallFinalizer[any] {
 	return newCall[any](s, call, payload, simpleResultMapper[any])
 }
 
-func (s *pxGridService) overAll(ctx context.Context, call string, payload any, result any,
+// AnyRESTWithMethod calls a REST endpoint of the service using the given HTTP method,
+// the call is retried if the method is idempotent, e.g. GET
+func (s *pxGridService) AnyRESTWithMethod(method, call string, payload any) CallFinalizer[any] {
+	return newMethodCall[any](s, method, call, payload, simpleResultMapper[any])
+}
+
+func (s *pxGridService) overAll(ctx context.Context, call string, payload any, ops RESTOptions,
 	pickNode ...ServiceNodePickerFactory,
 ) (*Response, error) {
 	n := s.orDefaultFactory(pickNode...)(s.nodes)
@@ -225,11 +236,27 @@ func (s *pxGridService) overAll(ctx context.Context, call string, payload any, r
 			continue
 		}
 
-		res, err := s.ctrl.RESTRequest(ctx, ensureTrailingSlash(restBaseURL)+call, payload, RESTOptions{
-			overridePassword: node.Secret,
-			result:           result,
-		})
+		// a node without tokens before the deadline is skipped like a failed one
+		if err := s.ctrl.limits.WaitNode(ctx, node.NodeName); err != nil {
+			s.log.DebugContext(ctx, "Node rate limited", "call", call, "node", node.NodeName, "error", err)
+			if !more || ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		ops.overridePassword = node.Secret
+		ops.callName = call
+		ops.service = s.name
+		ops.node = node.NodeName
+
+		spanCtx, span := s.ctrl.tracer.Start(ctx, s.name+"/"+call, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrService.String(s.name), attrCall.String(call), attrNode.String(node.NodeName)))
+		res, err := s.ctrl.RESTRequest(spanCtx, ensureTrailingSlash(restBaseURL)+call, payload, ops)
+		setResponseAttributes(span, res)
+		endSpan(span, err)
 		if err != nil {
+			s.log.DebugContext(ctx, "Request to node failed", "call", call, "node", node.NodeName, "error", err)
 			if !more {
 				return nil, err
 			}
@@ -242,7 +269,7 @@ func (s *pxGridService) overAll(ctx context.Context, call string, payload any, r
 	return nil, fmt.Errorf("all nodes failed to %s", call)
 }
 
-func (s *pxGridService) call(ctx context.Context, call string, payload any, result any,
+func (s *pxGridService) send(ctx context.Context, call string, payload any, ops RESTOptions,
 	pickNode ...ServiceNodePickerFactory,
 ) (*Response, error) {
 	err := s.CheckNodes(ctx)
@@ -250,7 +277,11 @@ func (s *pxGridService) call(ctx context.Context, call string, payload any, resu
 		return nil, err
 	}
 
-	res, err := s.overAll(ctx, call, payload, result, pickNode...)
+	if err := s.ctrl.limits.WaitService(ctx, s.name); err != nil {
+		return nil, err
+	}
+
+	res, err := s.overAll(ctx, call, payload, ops, pickNode...)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +140,7 @@ func (a *pxGridANC) GetPolicies() CallFinalizer[*[]ANCPolicy] {
 		Policies []ANCPolicy `json:"policies"`
 	}
 
-	return newCall[*[]ANCPolicy](
+	return query(newCallWithResult[*[]ANCPolicy, response](
 		&a.pxGridService,
 		"getPolicies",
 		map[string]any{},
@@ -150,7 +150,7 @@ func (a *pxGridANC) GetPolicies() CallFinalizer[*[]ANCPolicy] {
 			}
 			return &r.Result.(*response).Policies, nil
 		},
-	)
+	))
 }
 
 func (a *pxGridANC) GetPolicyByName(name string) CallFinalizer[*ANCPolicy] {
@@ -158,12 +158,12 @@ func (a *pxGridANC) GetPolicyByName(name string) CallFinalizer[*ANCPolicy] {
 		return newFailedCall[*ANCPolicy](ErrInvalidInput)
 	}
 
-	return newCall[*ANCPolicy](
+	return query(newCall[*ANCPolicy](
 		&a.pxGridService,
 		"getPolicyByName",
 		map[string]any{"name": name},
 		simpleResultMapper[*ANCPolicy],
-	)
+	))
 }
 
 func (a *pxGridANC) CreatePolicy(policy ANCPolicy) NoResultCallFinalizer {
@@ -202,7 +202,7 @@ func (a *pxGridANC) GetEndpoints() CallFinalizer[*[]ANCEndpoint] {
 		Endpoints []ANCEndpoint `json:"endpoints"`
 	}
 
-	return newCall[*[]ANCEndpoint](
+	return query(newCallWithResult[*[]ANCEndpoint, response](
 		&a.pxGridService,
 		"getEndpoints",
 		map[string]any{},
@@ -212,7 +212,7 @@ func (a *pxGridANC) GetEndpoints() CallFinalizer[*[]ANCEndpoint] {
 			}
 			return &r.Result.(*response).Endpoints, nil
 		},
-	)
+	))
 }
 
 func (a *pxGridANC) GetEndpointPolicies() CallFinalizer[*[]ANCEndpoint] {
@@ -220,7 +220,7 @@ func (a *pxGridANC) GetEndpointPolicies() CallFinalizer[*[]ANCEndpoint] {
 		Endpoints []ANCEndpoint `json:"endpoints"`
 	}
 
-	return newCall[*[]ANCEndpoint](
+	return query(newCallWithResult[*[]ANCEndpoint, response](
 		&a.pxGridService,
 		"getEndpointPolicies",
 		map[string]any{},
@@ -230,7 +230,7 @@ func (a *pxGridANC) GetEndpointPolicies() CallFinalizer[*[]ANCEndpoint] {
 			}
 			return &r.Result.(*response).Endpoints, nil
 		},
-	)
+	))
 }
 
 func (a *pxGridANC) GetEndpointByMAC(mac string) CallFinalizer[*ANCEndpoint] {
@@ -238,12 +238,12 @@ func (a *pxGridANC) GetEndpointByMAC(mac string) CallFinalizer[*ANCEndpoint] {
 		return newFailedCall[*ANCEndpoint](ErrInvalidInput)
 	}
 
-	return newCall[*ANCEndpoint](
+	return query(newCall[*ANCEndpoint](
 		&a.pxGridService,
 		"getEndpointByMAC",
 		map[string]any{"macAddress": mac},
 		simpleResultMapper[*ANCEndpoint],
-	)
+	))
 }
 
 func (a *pxGridANC) GetEndpointByNasIPAddress(mac, nasIP string) CallFinalizer[*ANCEndpoint] {
@@ -256,12 +256,12 @@ func (a *pxGridANC) GetEndpointByNasIPAddress(mac, nasIP string) CallFinalizer[*
 		"nasIpAddress": nasIP,
 	}
 
-	return newCall[*ANCEndpoint](
+	return query(newCall[*ANCEndpoint](
 		&a.pxGridService,
 		"getEndpointByNasIpAddress",
 		payload,
 		simpleResultMapper[*ANCEndpoint],
-	)
+	))
 }
 
 func (a *pxGridANC) ApplyEndpointByIPAddress(ip, policyName string) CallFinalizer[*ANCOperationStatus] {
@@ -341,12 +341,12 @@ func (a *pxGridANC) GetOperationStatus(operationID string) CallFinalizer[*ANCOpe
 		return newFailedCall[*ANCOperationStatus](ErrInvalidInput)
 	}
 
-	return newCall[*ANCOperationStatus](
+	return query(newCall[*ANCOperationStatus](
 		&a.pxGridService,
 		"getOperationStatus",
 		map[string]any{"operationId": operationID},
 		simpleResultMapper[*ANCOperationStatus],
-	)
+	))
 }
 
 func (a *pxGridANC) ApplyEndpointPolicy(request ANCApplyPolicyRequest) CallFinalizer[*ANCOperationStatus] {
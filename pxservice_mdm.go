@@ -6,22 +6,22 @@ import (
 
 type (
 	MDMEndpoint struct {
-		MACAddress    string `json:"macAddress"`
-		OSVersion     string `json:"osVersion"`
-		Registered    bool   `json:"registered"`
-		Compliant     bool   `json:"compliant"`
-		DiskEncrypted bool   `json:"diskEncrypted"`
-		JailBroken    bool   `json:"jailBroken"`
-		PinLocked     bool   `json:"pinLocked"`
-		Model         string `json:"model"`
-		Manufacturer  string `json:"manufacturer"`
-		IMEI          string `json:"imei"`
-		MEID          string `json:"meid"`
-		UDID          string `json:"udid"`
-		SerialNumber  string `json:"serialNumber"`
-		Location      string `json:"location"`
-		DeviceManager string `json:"deviceManager"`
-		LastSyncTime  string `json:"lastSyncTime"`
+		MACAddress    string     `json:"macAddress"`
+		OSVersion     string     `json:"osVersion"`
+		Registered    bool       `json:"registered"`
+		Compliant     bool       `json:"compliant"`
+		DiskEncrypted bool       `json:"diskEncrypted"`
+		JailBroken    bool       `json:"jailBroken"`
+		PinLocked     bool       `json:"pinLocked"`
+		Model         string     `json:"model"`
+		Manufacturer  string     `json:"manufacturer"`
+		IMEI          string     `json:"imei"`
+		MEID          string     `json:"meid"`
+		UDID          string     `json:"udid"`
+		SerialNumber  string     `json:"serialNumber"`
+		Location      string     `json:"location"`
+		DeviceManager string     `json:"deviceManager"`
+		LastSyncTime  PxGridTime `json:"lastSyncTime"`
 	}
 
 	MDMEndpointType string
@@ -101,7 +101,7 @@ func (s *pxGridMDM) GetEndpoints(filter *MDMEndpoint) CallFinalizer[*[]MDMEndpoi
 		Endpoints []MDMEndpoint `json:"endpoints"`
 	}
 
-	return newCall[*[]MDMEndpoint](
+	return query(newCallWithResult[*[]MDMEndpoint, response](
 		&s.pxGridService,
 		"getEndpoints",
 		payload,
@@ -111,7 +111,7 @@ func (s *pxGridMDM) GetEndpoints(filter *MDMEndpoint) CallFinalizer[*[]MDMEndpoi
 			}
 			return &r.Result.(*response).Endpoints, nil
 		},
-	)
+	))
 }
 
 // GetEndpointByMacAddress retrieves an endpoint by its MAC address
@@ -120,12 +120,12 @@ func (s *pxGridMDM) GetEndpointByMacAddress(macAddress string) CallFinalizer[*MD
 		return newFailedCall[*MDMEndpoint](ErrInvalidInput)
 	}
 
-	return newCall[*MDMEndpoint](
+	return query(newCall[*MDMEndpoint](
 		&s.pxGridService,
 		"getEndpointByMacAddress",
 		map[string]any{"macAddress": macAddress},
 		simpleResultMapper[*MDMEndpoint],
-	)
+	))
 }
 
 // GetEndpointsByType retrieves the endpoints by type
@@ -136,7 +136,7 @@ func (s *pxGridMDM) GetEndpointsByType(endpointType MDMEndpointType) CallFinaliz
 		Endpoints []MDMEndpoint `json:"endpoints"`
 	}
 
-	return newCall[*[]MDMEndpoint](
+	return query(newCallWithResult[*[]MDMEndpoint, response](
 		&s.pxGridService,
 		"getEndpointsByType",
 		payload,
@@ -146,7 +146,7 @@ func (s *pxGridMDM) GetEndpointsByType(endpointType MDMEndpointType) CallFinaliz
 			}
 			return &r.Result.(*response).Endpoints, nil
 		},
-	)
+	))
 }
 
 // GetEndpointsByOsType retrieves the endpoints by OS type
@@ -157,7 +157,7 @@ func (s *pxGridMDM) GetEndpointsByOsType(osType MDMOSType) CallFinalizer[*[]MDME
 		Endpoints []MDMEndpoint `json:"endpoints"`
 	}
 
-	return newCall[*[]MDMEndpoint](
+	return query(newCallWithResult[*[]MDMEndpoint, response](
 		&s.pxGridService,
 		"getEndpointsByOsType",
 		payload,
@@ -167,7 +167,7 @@ func (s *pxGridMDM) GetEndpointsByOsType(osType MDMOSType) CallFinalizer[*[]MDME
 			}
 			return &r.Result.(*response).Endpoints, nil
 		},
-	)
+	))
 }
 
 func (s *pxGridMDM) Properties() MDMPropsProvider {
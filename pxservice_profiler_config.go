@@ -73,7 +73,7 @@ func (s *pxGridProfilerConfiguration) GetProfiles() CallFinalizer[*[]Profile] {
 		Profiles []Profile `json:"profiles"`
 	}
 
-	return newCall[*[]Profile](
+	return query(newCallWithResult[*[]Profile, response](
 		&s.pxGridService,
 		"getProfiles",
 		map[string]any{},
@@ -83,7 +83,7 @@ func (s *pxGridProfilerConfiguration) GetProfiles() CallFinalizer[*[]Profile] {
 			}
 			return &r.Result.(*response).Profiles, nil
 		},
-	)
+	))
 }
 
 func (s *pxGridProfilerConfiguration) Properties() ProfilerConfigurationPropsProvider {
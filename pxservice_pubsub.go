@@ -6,12 +6,14 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-stomp/stomp/v3"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -44,6 +46,8 @@ type (
 		readerBuffer []byte
 		writeBuffer  []byte
 		log          Logger
+		observer     Observer
+		tracer       trace.Tracer
 
 		l sync.RWMutex
 	}
@@ -82,7 +86,7 @@ func (p *pxGridPubSub) Subscribe(ctx context.Context, picker ServiceNodePickerFa
 		if err != nil {
 			return nil, err
 		}
-		p.log.Debug("PubSub Subscribe", "node", node.NodeName, "secret", node.Secret, "topic", topic)
+		p.log.DebugContext(ctx, "PubSub Subscribe", "node", node.NodeName, "topic", topic)
 
 		ep, err := p.getEndpoint(node)
 		if err != nil {
@@ -91,7 +95,7 @@ func (p *pxGridPubSub) Subscribe(ctx context.Context, picker ServiceNodePickerFa
 			}
 			continue
 		}
-		p.log.Debug("Got WS Endpoint", "wsURL", ep.wsURL)
+		p.log.DebugContext(ctx, "Got WS Endpoint", "wsURL", ep.wsURL)
 
 		err = ep.connect(ctx)
 		if err != nil {
@@ -107,10 +111,15 @@ func (p *pxGridPubSub) Subscribe(ctx context.Context, picker ServiceNodePickerFa
 
 func (p *pxGridPubSub) createEndpoint(wsURL, secret string) *PubSubEndpoint {
 	p.log.Debug("Create WS PubSub Endpoint", "wsURL", wsURL, "nodeName", p.ctrl.cfg.NodeName)
+	var host string
+	if u, err := url.Parse(wsURL); err == nil {
+		host = u.Hostname()
+	}
+
 	ep := &PubSubEndpoint{
 		dialer: websocket.Dialer{
-			TLSClientConfig: p.ctrl.ClientTLSConfig(),
-			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig:  p.ctrl.svc.clientTLSConfig(host),
+			HandshakeTimeout: p.ctrl.cfg.Timeout,
 			NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 				return p.ctrl.DialContext(ctx, network, addr)
 			},
@@ -119,6 +128,8 @@ func (p *pxGridPubSub) createEndpoint(wsURL, secret string) *PubSubEndpoint {
 		nodeName: p.ctrl.cfg.NodeName,
 		secret:   secret,
 		log:      p.log.With("wsURL", wsURL),
+		observer: observers(p.ctrl.cfg.Observers),
+		tracer:   p.ctrl.tracer,
 	}
 
 	return ep
@@ -164,6 +175,7 @@ func (e *PubSubEndpoint) pinger(ch <-chan time.Time) {
 		err := e.ws.WriteControl(websocket.PingMessage, []byte(""), time.Time{})
 		if err != nil {
 			e.log.Error("Ping failed", "error", err)
+			e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 			return
 		}
 	}
@@ -209,39 +221,52 @@ func (e *PubSubEndpoint) checkConnection() (bool, error) {
 
 func (e *PubSubEndpoint) connect(ctx context.Context) (err error) {
 	exists, err := e.checkConnection()
-	e.log.Debug("Connection check", "exists", exists, "error", err)
+	e.log.DebugContext(ctx, "Connection check", "exists", exists, "error", err)
 	if exists {
 		if err == nil {
-			e.log.Debug("Connection is still open")
+			e.log.DebugContext(ctx, "Connection is still open")
 			return nil
 		}
 
-		e.log.Warn("Half-open connection, closing", "error", err)
+		e.log.WarnContext(ctx, "Half-open connection, closing", "error", err)
 
 		e.l.Lock()
 		e.ws.Close()
 		e.ws = nil
 		e.stomp = nil
 		e.l.Unlock()
+		e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 	}
 
 	e.l.Lock()
 	defer e.l.Unlock()
-	e.log.Debug("WebSocket dial")
-	e.ws, _, err = e.dialer.DialContext(ctx, e.wsURL, e.getAuthHeaders())
+	e.log.DebugContext(ctx, "WebSocket dial")
+	e.observer.WebSocketStateChanged(e.wsURL, WebSocketConnecting)
+	dialCtx, span := e.tracer.Start(ctx, "pxgrid.websocket.dial", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrWSURL.String(e.wsURL)))
+	var resp *http.Response
+	e.ws, resp, err = e.dialer.DialContext(dialCtx, e.wsURL, e.getAuthHeaders())
+	if resp != nil {
+		span.SetAttributes(attrStatusCode.Int(resp.StatusCode))
+	}
+	endSpan(span, err)
 	if err != nil {
+		e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 		return err
 	}
 
-	e.log.Debug("STOMP connect")
+	e.log.DebugContext(ctx, "STOMP connect")
+	_, span = e.tracer.Start(ctx, "pxgrid.stomp.connect", trace.WithAttributes(attrWSURL.String(e.wsURL)))
 	e.stomp, err = stomp.Connect(e,
 		stomp.ConnOpt.HeartBeat(0, 0),
 		stomp.ConnOpt.Logger(fromLogger(e.log)))
+	endSpan(span, err)
 	if err != nil {
+		e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 		return errors.Join(err, e.ws.Close())
 	}
 
-	e.log.Debug("STOMP connected, setting up ping/pong")
+	e.log.DebugContext(ctx, "STOMP connected, setting up ping/pong")
 	e.ws.SetPongHandler(func(string) error {
 		e.log.Debug("Received pong")
 		e.ws.SetReadDeadline(time.Now().Add(pongWait))
@@ -251,12 +276,14 @@ func (e *PubSubEndpoint) connect(ctx context.Context) (err error) {
 	e.ticker = time.NewTicker(pingPeriod)
 	go e.pinger(e.ticker.C)
 
+	e.observer.WebSocketStateChanged(e.wsURL, WebSocketConnected)
 	return
 }
 
 func (e *PubSubEndpoint) Disconnect() error {
 	e.log.Debug("Disconnecting")
 	e.ticker.Stop()
+	defer e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 	return e.ws.Close()
 }
 
@@ -265,6 +292,7 @@ func (e *PubSubEndpoint) Read(p []byte) (int, error) {
 	if len(e.readerBuffer) == 0 {
 		_, msg, err := e.ws.ReadMessage()
 		if err != nil {
+			e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 			return 0, err
 		}
 		e.readerBuffer = msg
@@ -287,5 +315,6 @@ func (e *PubSubEndpoint) Write(p []byte) (int, error) {
 }
 
 func (e *PubSubEndpoint) Close() error {
+	defer e.observer.WebSocketStateChanged(e.wsURL, WebSocketDisconnected)
 	return e.ws.Close()
 }
@@ -2,48 +2,49 @@ package gopxgrid
 
 import (
 	"fmt"
+	"time"
 )
 
 type (
 	Failure struct {
-		ID                       string   `json:"id"`
-		Timestamp                string   `json:"timestamp"`
-		FailureReason            string   `json:"failureReason"`
-		UserName                 string   `json:"userName"`
-		ServerName               string   `json:"serverName"`
-		CallingStationID         string   `json:"callingStationId"`
-		AuditSessionID           string   `json:"auditSessionId"`
-		NASIPAddress             string   `json:"nasIpAddress"`
-		NASPortID                string   `json:"nasPortId"`
-		NASPortType              string   `json:"nasPortType"`
-		IPAddresses              []string `json:"ipAddresses"`
-		MACAddress               string   `json:"macAddress"`
-		MessageCode              int      `json:"messageCode"`
-		DestinationIPAddress     string   `json:"destinationIpAddress"`
-		UserType                 string   `json:"userType"`
-		AccessService            string   `json:"accessService"`
-		IdentityStore            string   `json:"identityStore"`
-		IdentityGroup            string   `json:"identityGroup"`
-		AuthenticationMethod     string   `json:"authenticationMethod"`
-		AuthenticationProtocol   string   `json:"authenticationProtocol"`
-		ServiceType              string   `json:"serviceType"`
-		NetworkDeviceName        string   `json:"networkDeviceName"`
-		DeviceType               string   `json:"deviceType"`
-		Location                 string   `json:"location"`
-		SelectedAznProfiles      string   `json:"selectedAznProfiles"`
-		PostureStatus            string   `json:"postureStatus"`
-		CTSSecurityGroup         string   `json:"ctsSecurityGroup"`
-		Response                 string   `json:"response"`
-		ResponseTime             int      `json:"responseTime"`
-		ExecutionSteps           string   `json:"executionSteps"`
-		CredentialCheck          string   `json:"credentialCheck"`
-		EndpointProfile          string   `json:"endpointProfile"`
-		MDMServerName            string   `json:"mdmServerName"`
-		PolicySetName            string   `json:"policySetName"`
-		AuthorizationRule        string   `json:"authorizationRule"`
-		MSEResponseTime          string   `json:"mseResponseTime"`
-		MSEServerName            string   `json:"mseServerName"`
-		OriginalCallingStationID string   `json:"originalCallingStationId"`
+		ID                       string     `json:"id"`
+		Timestamp                PxGridTime `json:"timestamp"`
+		FailureReason            string     `json:"failureReason"`
+		UserName                 string     `json:"userName"`
+		ServerName               string     `json:"serverName"`
+		CallingStationID         string     `json:"callingStationId"`
+		AuditSessionID           string     `json:"auditSessionId"`
+		NASIPAddress             string     `json:"nasIpAddress"`
+		NASPortID                string     `json:"nasPortId"`
+		NASPortType              string     `json:"nasPortType"`
+		IPAddresses              []string   `json:"ipAddresses"`
+		MACAddress               string     `json:"macAddress"`
+		MessageCode              int        `json:"messageCode"`
+		DestinationIPAddress     string     `json:"destinationIpAddress"`
+		UserType                 string     `json:"userType"`
+		AccessService            string     `json:"accessService"`
+		IdentityStore            string     `json:"identityStore"`
+		IdentityGroup            string     `json:"identityGroup"`
+		AuthenticationMethod     string     `json:"authenticationMethod"`
+		AuthenticationProtocol   string     `json:"authenticationProtocol"`
+		ServiceType              string     `json:"serviceType"`
+		NetworkDeviceName        string     `json:"networkDeviceName"`
+		DeviceType               string     `json:"deviceType"`
+		Location                 string     `json:"location"`
+		SelectedAznProfiles      string     `json:"selectedAznProfiles"`
+		PostureStatus            string     `json:"postureStatus"`
+		CTSSecurityGroup         string     `json:"ctsSecurityGroup"`
+		Response                 string     `json:"response"`
+		ResponseTime             int        `json:"responseTime"`
+		ExecutionSteps           string     `json:"executionSteps"`
+		CredentialCheck          string     `json:"credentialCheck"`
+		EndpointProfile          string     `json:"endpointProfile"`
+		MDMServerName            string     `json:"mdmServerName"`
+		PolicySetName            string     `json:"policySetName"`
+		AuthorizationRule        string     `json:"authorizationRule"`
+		MSEResponseTime          string     `json:"mseResponseTime"`
+		MSEServerName            string     `json:"mseServerName"`
+		OriginalCallingStationID string     `json:"originalCallingStationId"`
 	}
 
 	FailureTopicMessage struct {
@@ -65,6 +66,7 @@ type (
 
 	RadiusFailureRest interface {
 		GetFailures() CallFinalizer[*[]Failure]
+		GetFailuresSince(startTimestamp time.Time) CallFinalizer[*[]Failure]
 		GetFailureByID(id string) CallFinalizer[*Failure]
 	}
 
@@ -105,21 +107,31 @@ func (r *pxGridRadiusFailure) Rest() RadiusFailureRest {
 
 // GetFailures retrieves the list of failures from the radius failure service
 func (r *pxGridRadiusFailure) GetFailures() CallFinalizer[*[]Failure] {
+	return r.GetFailuresSince(time.Time{})
+}
+
+// GetFailuresSince retrieves the failures since startTimestamp, zero time means all failures
+func (r *pxGridRadiusFailure) GetFailuresSince(startTimestamp time.Time) CallFinalizer[*[]Failure] {
+	payload := map[string]any{}
+	if !startTimestamp.IsZero() {
+		payload["startTimestamp"] = FormatTimestamp(startTimestamp)
+	}
+
 	type response struct {
 		Failures []Failure `json:"failures"`
 	}
 
-	return newCall[*[]Failure](
+	return query(newCallWithResult[*[]Failure, response](
 		&r.pxGridService,
 		"getFailures",
-		map[string]any{},
+		payload,
 		func(r *Response) (*[]Failure, error) {
 			if r.StatusCode > 299 {
 				return nil, fmt.Errorf("unexpected status code: %d", r.StatusCode)
 			}
 			return &r.Result.(*response).Failures, nil
 		},
-	)
+	))
 }
 
 // GetFailureByID retrieves a failure by its ID
@@ -128,12 +140,12 @@ func (r *pxGridRadiusFailure) GetFailureByID(id string) CallFinalizer[*Failure]
 		return newFailedCall[*Failure](ErrInvalidInput)
 	}
 
-	return newCall[*Failure](
+	return query(newCall[*Failure](
 		&r.pxGridService,
 		"getFailureById",
 		map[string]any{"id": id},
 		simpleResultMapper[*Failure],
-	)
+	))
 }
 
 func (r *pxGridRadiusFailure) Properties() RadiusFailurePropsProvider {
@@ -2,13 +2,14 @@ package gopxgrid
 
 import (
 	"fmt"
+	"time"
 )
 
 type (
 	SessionState string
 
 	Session struct {
-		Timestamp                string       `json:"timestamp"`
+		Timestamp                PxGridTime   `json:"timestamp"`
 		State                    SessionState `json:"state"`
 		MacAddress               string       `json:"macAddress"`
 		IPAddresses              []string     `json:"ipAddresses"`
@@ -40,7 +41,7 @@ type (
 		ADHostQualifiedName      string       `json:"adHostQualifiedName"`
 		Providers                []string     `json:"providers"`
 		EndpointCheckResult      string       `json:"endpointCheckResult"`
-		EndpointCheckTime        string       `json:"endpointCheckTime"`
+		EndpointCheckTime        PxGridTime   `json:"endpointCheckTime"`
 		IdentitySourcePortStart  string       `json:"identitySourcePortStart"`
 		IdentitySourcePortEnd    string       `json:"identitySourcePortEnd"`
 		IdentitySourcePortFirst  string       `json:"identitySourcePortFirst"`
@@ -68,7 +69,7 @@ type (
 		MDMSerialNumber          string       `json:"mdmSerialNumber"`
 		MDMLocation              string       `json:"mdmLocation"`
 		MDMDeviceManager         string       `json:"mdmDeviceManager"`
-		MDMLastSyncTime          string       `json:"mdmLastSyncTime"`
+		MDMLastSyncTime          PxGridTime   `json:"mdmLastSyncTime"`
 		VirtualNetwork           string       `json:"virtualNetwork"`
 	}
 
@@ -106,11 +107,18 @@ type (
 
 	SessionDirectoryRest interface {
 		GetSessions(startTimestamp string, filter any) CallFinalizer[*[]Session]
+		GetSessionsSince(startTimestamp time.Time, filter *SessionFilter) CallFinalizer[*[]Session]
 		GetSessionsForRecovery(startTimestamp, endTimestamp string) CallFinalizer[*[]Session]
+		GetSessionsForRecoveryBetween(startTimestamp, endTimestamp time.Time) CallFinalizer[*[]Session]
 		GetSessionByIPAddress(ipAddress string) CallFinalizer[*Session]
 		GetSessionByMacAddress(macAddress string) CallFinalizer[*Session]
 		GetUserGroups(filter any) CallFinalizer[*[]Group]
 		GetUserGroupByUserName(userName string) CallFinalizer[*[]Group]
+
+		StreamSessions(startTimestamp string, filter any) IterCallFinalizer[Session]
+		StreamSessionsSince(startTimestamp time.Time, filter *SessionFilter) IterCallFinalizer[Session]
+		StreamSessionsForRecovery(startTimestamp, endTimestamp string) IterCallFinalizer[Session]
+		StreamSessionsForRecoveryBetween(startTimestamp, endTimestamp time.Time) IterCallFinalizer[Session]
 	}
 
 	SessionDirectory interface {
@@ -161,22 +169,39 @@ func (s *pxGridSessionDirectory) Rest() SessionDirectoryRest {
 	return s
 }
 
-// GetSessions retrieves the sessions from the session directory service
-func (s *pxGridSessionDirectory) GetSessions(startTimestamp string, filter any) CallFinalizer[*[]Session] {
+func sessionsPayload(startTimestamp string, filter any) (map[string]any, error) {
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
 	payload := map[string]any{}
 	if startTimestamp != "" {
 		payload["startTimestamp"] = startTimestamp
 	}
 
+	if f, ok := filter.(*SessionFilter); ok && f == nil {
+		return payload, nil
+	}
 	if filter != nil {
 		payload["filter"] = filter
 	}
 
+	return payload, nil
+}
+
+// GetSessions retrieves the sessions from the session directory service.
+// Filters implementing Validate, e.g. *SessionFilter, are validated before the request is sent
+func (s *pxGridSessionDirectory) GetSessions(startTimestamp string, filter any) CallFinalizer[*[]Session] {
+	payload, err := sessionsPayload(startTimestamp, filter)
+	if err != nil {
+		return newFailedCall[*[]Session](err)
+	}
+
 	type response struct {
 		Sessions []Session `json:"sessions"`
 	}
 
-	return newCall[*[]Session](
+	return query(newCallWithResult[*[]Session, response](
 		&s.pxGridService,
 		"getSessions",
 		payload,
@@ -186,7 +211,12 @@ func (s *pxGridSessionDirectory) GetSessions(startTimestamp string, filter any)
 			}
 			return &r.Result.(*response).Sessions, nil
 		},
-	)
+	))
+}
+
+// GetSessionsSince retrieves the sessions changed since startTimestamp, zero time means all sessions
+func (s *pxGridSessionDirectory) GetSessionsSince(startTimestamp time.Time, filter *SessionFilter) CallFinalizer[*[]Session] {
+	return s.GetSessions(FormatTimestamp(startTimestamp), filter)
 }
 
 // GetSessionsForRecovery retrieves the sessions for recovery from the session directory service
@@ -204,7 +234,7 @@ func (s *pxGridSessionDirectory) GetSessionsForRecovery(startTimestamp, endTimes
 		Sessions []Session `json:"sessions"`
 	}
 
-	return newCall[*[]Session](
+	return query(newCallWithResult[*[]Session, response](
 		&s.pxGridService,
 		"getSessionsForRecovery",
 		payload,
@@ -214,7 +244,50 @@ func (s *pxGridSessionDirectory) GetSessionsForRecovery(startTimestamp, endTimes
 			}
 			return &r.Result.(*response).Sessions, nil
 		},
-	)
+	))
+}
+
+// GetSessionsForRecoveryBetween retrieves the sessions for recovery, zero time leaves the bound open
+func (s *pxGridSessionDirectory) GetSessionsForRecoveryBetween(startTimestamp, endTimestamp time.Time) CallFinalizer[*[]Session] {
+	return s.GetSessionsForRecovery(FormatTimestamp(startTimestamp), FormatTimestamp(endTimestamp))
+}
+
+// StreamSessions retrieves the sessions from the session directory service,
+// decoding them one by one from the response body. PxGridConfig.Timeout
+// limits the wait for the headers and the idle time between reads of the
+// body, not the stream as a whole
+func (s *pxGridSessionDirectory) StreamSessions(startTimestamp string, filter any) IterCallFinalizer[Session] {
+	payload, err := sessionsPayload(startTimestamp, filter)
+	if err != nil {
+		return newFailedStreamCall[Session](err)
+	}
+
+	return query(newStreamCall[Session](&s.pxGridService, "getSessions", payload, "sessions"))
+}
+
+// StreamSessionsSince streams the sessions changed since startTimestamp, zero time means all sessions
+func (s *pxGridSessionDirectory) StreamSessionsSince(startTimestamp time.Time, filter *SessionFilter) IterCallFinalizer[Session] {
+	return s.StreamSessions(FormatTimestamp(startTimestamp), filter)
+}
+
+// StreamSessionsForRecovery retrieves the sessions for recovery from the session directory service,
+// decoding them one by one from the response body
+func (s *pxGridSessionDirectory) StreamSessionsForRecovery(startTimestamp, endTimestamp string) IterCallFinalizer[Session] {
+	payload := map[string]any{}
+
+	if startTimestamp != "" {
+		payload["startTimestamp"] = startTimestamp
+	}
+	if endTimestamp != "" {
+		payload["endTimestamp"] = endTimestamp
+	}
+
+	return query(newStreamCall[Session](&s.pxGridService, "getSessionsForRecovery", payload, "sessions"))
+}
+
+// StreamSessionsForRecoveryBetween streams the sessions for recovery, zero time leaves the bound open
+func (s *pxGridSessionDirectory) StreamSessionsForRecoveryBetween(startTimestamp, endTimestamp time.Time) IterCallFinalizer[Session] {
+	return s.StreamSessionsForRecovery(FormatTimestamp(startTimestamp), FormatTimestamp(endTimestamp))
 }
 
 // GetSessionByIPAddress retrieves a session by its IP address
@@ -223,12 +296,12 @@ func (s *pxGridSessionDirectory) GetSessionByIPAddress(ipAddress string) CallFin
 		return newFailedCall[*Session](ErrInvalidInput)
 	}
 
-	return newCall[*Session](
+	return query(newCall[*Session](
 		&s.pxGridService,
 		"getSessionByIPAddress",
 		map[string]any{"ipAddress": ipAddress},
 		simpleResultMapper[*Session],
-	)
+	))
 }
 
 // GetSessionByMacAddress retrieves a session by its MAC address
@@ -237,17 +310,25 @@ func (s *pxGridSessionDirectory) GetSessionByMacAddress(macAddress string) CallF
 		return newFailedCall[*Session](ErrInvalidInput)
 	}
 
-	return newCall[*Session](
+	return query(newCall[*Session](
 		&s.pxGridService,
 		"getSessionByMacAddress",
 		map[string]any{"macAddress": macAddress},
 		simpleResultMapper[*Session],
-	)
+	))
 }
 
-// GetUserGroups retrieves the user groups from the session directory service
+// GetUserGroups retrieves the user groups from the session directory service.
+// Filters implementing Validate, e.g. *UserGroupFilter, are validated before the request is sent
 func (s *pxGridSessionDirectory) GetUserGroups(filter any) CallFinalizer[*[]Group] {
+	if err := validateFilter(filter); err != nil {
+		return newFailedCall[*[]Group](err)
+	}
+
 	payload := map[string]any{}
+	if f, ok := filter.(*UserGroupFilter); ok && f == nil {
+		filter = nil
+	}
 	if filter != nil {
 		payload["filter"] = filter
 	}
@@ -256,7 +337,7 @@ func (s *pxGridSessionDirectory) GetUserGroups(filter any) CallFinalizer[*[]Grou
 		Groups []Group `json:"userGroups"`
 	}
 
-	return newCall[*[]Group](
+	return query(newCallWithResult[*[]Group, response](
 		&s.pxGridService,
 		"getUserGroups",
 		payload,
@@ -266,7 +347,7 @@ func (s *pxGridSessionDirectory) GetUserGroups(filter any) CallFinalizer[*[]Grou
 			}
 			return &r.Result.(*response).Groups, nil
 		},
-	)
+	))
 }
 
 // GetUserGroupByUserName retrieves a user group by its user name
@@ -279,7 +360,7 @@ func (s *pxGridSessionDirectory) GetUserGroupByUserName(userName string) CallFin
 		Groups []Group `json:"groups"`
 	}
 
-	return newCall[*[]Group](
+	return query(newCallWithResult[*[]Group, response](
 		&s.pxGridService,
 		"getUserGroupByUserName",
 		map[string]any{"userName": userName},
@@ -292,7 +373,7 @@ func (s *pxGridSessionDirectory) GetUserGroupByUserName(userName string) CallFin
 			}
 			return &r.Result.(*response).Groups, nil
 		},
-	)
+	))
 }
 
 func (s *pxGridSessionDirectory) Properties() SessionDirectoryPropsProvider {
@@ -1,27 +1,30 @@
 package gopxgrid
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type (
 	SysHealth struct {
-		Timestamp       string  `json:"timestamp"`
-		ServerName      string  `json:"serverName"`
-		IOWait          float64 `json:"ioWait"`
-		CPUUsage        float64 `json:"cpuUsage"`
-		MemoryUsage     float64 `json:"memoryUsage"`
-		DiskUsageRoot   float64 `json:"diskUsageRoot"`
-		DiskUsageOpt    float64 `json:"diskUsageOpt"`
-		LoadAverage     float64 `json:"loadAverage"`
-		NetworkSent     float64 `json:"networkSent"`
-		NetworkReceived float64 `json:"networkReceived"`
+		Timestamp       PxGridTime `json:"timestamp"`
+		ServerName      string     `json:"serverName"`
+		IOWait          float64    `json:"ioWait"`
+		CPUUsage        float64    `json:"cpuUsage"`
+		MemoryUsage     float64    `json:"memoryUsage"`
+		DiskUsageRoot   float64    `json:"diskUsageRoot"`
+		DiskUsageOpt    float64    `json:"diskUsageOpt"`
+		LoadAverage     float64    `json:"loadAverage"`
+		NetworkSent     float64    `json:"networkSent"`
+		NetworkReceived float64    `json:"networkReceived"`
 	}
 
 	SysPerformance struct {
-		Timestamp     string  `json:"timestamp"`
-		ServerName    string  `json:"serverName"`
-		RADIUSRate    float64 `json:"radiusRate"`
-		RADIUSCount   float64 `json:"radiusCount"`
-		RADIUSLatency float64 `json:"radiusLatency"`
+		Timestamp     PxGridTime `json:"timestamp"`
+		ServerName    string     `json:"serverName"`
+		RADIUSRate    float64    `json:"radiusRate"`
+		RADIUSCount   float64    `json:"radiusCount"`
+		RADIUSLatency float64    `json:"radiusLatency"`
 	}
 
 	SystemHealthPropsProvider interface {
@@ -31,6 +34,8 @@ type (
 	SystemHealthRest interface {
 		GetHealths(nodeName string, startTimestamp string) CallFinalizer[*[]SysHealth]
 		GetPerformances(nodeName string, startTimestamp string) CallFinalizer[*[]SysPerformance]
+		GetHealthsSince(nodeName string, startTimestamp time.Time) CallFinalizer[*[]SysHealth]
+		GetPerformancesSince(nodeName string, startTimestamp time.Time) CallFinalizer[*[]SysPerformance]
 	}
 
 	SystemHealth interface {
@@ -77,7 +82,7 @@ func (s *pxGridSystemHealth) GetHealths(nodeName string, startTimestamp string)
 		Healths []SysHealth `json:"healths"`
 	}
 
-	return newCall[*[]SysHealth](
+	return query(newCallWithResult[*[]SysHealth, response](
 		&s.pxGridService,
 		"getHealths",
 		payload,
@@ -87,7 +92,7 @@ func (s *pxGridSystemHealth) GetHealths(nodeName string, startTimestamp string)
 			}
 			return &r.Result.(*response).Healths, nil
 		},
-	)
+	))
 }
 
 func (s *pxGridSystemHealth) GetPerformances(nodeName string, startTimestamp string) CallFinalizer[*[]SysPerformance] {
@@ -103,7 +108,7 @@ func (s *pxGridSystemHealth) GetPerformances(nodeName string, startTimestamp str
 		Performances []SysPerformance `json:"performances"`
 	}
 
-	return newCall[*[]SysPerformance](
+	return query(newCallWithResult[*[]SysPerformance, response](
 		&s.pxGridService,
 		"getPerformances",
 		payload,
@@ -113,7 +118,17 @@ func (s *pxGridSystemHealth) GetPerformances(nodeName string, startTimestamp str
 			}
 			return &r.Result.(*response).Performances, nil
 		},
-	)
+	))
+}
+
+// GetHealthsSince retrieves the health samples taken after startTimestamp
+func (s *pxGridSystemHealth) GetHealthsSince(nodeName string, startTimestamp time.Time) CallFinalizer[*[]SysHealth] {
+	return s.GetHealths(nodeName, FormatTimestamp(startTimestamp))
+}
+
+// GetPerformancesSince retrieves the performance samples taken after startTimestamp
+func (s *pxGridSystemHealth) GetPerformancesSince(nodeName string, startTimestamp time.Time) CallFinalizer[*[]SysPerformance] {
+	return s.GetPerformances(nodeName, FormatTimestamp(startTimestamp))
 }
 
 func (s *pxGridSystemHealth) Properties() SystemHealthPropsProvider {
@@ -13,7 +13,7 @@ type (
 	PolicyDownloadStatus string
 
 	PolicyDownload struct {
-		Timestamp       string               `json:"timestamp"`
+		Timestamp       PxGridTime           `json:"timestamp"`
 		ServerName      string               `json:"serverName"`
 		Status          PolicyDownloadStatus `json:"status"`
 		FailureReason   string               `json:"failureReason"`
@@ -2,6 +2,7 @@ package gopxgrid
 
 import (
 	"fmt"
+	"time"
 )
 
 type (
@@ -23,42 +24,42 @@ type (
 	}
 
 	EgressPolicy struct {
-		ID                         string   `json:"id"`
-		Name                       string   `json:"name"`
-		MatrixId                   string   `json:"matrixId"`
-		Status                     string   `json:"status"`
-		Description                string   `json:"description"`
-		SourceSecurityGroupID      string   `json:"sourceSecurityGroupId"`
-		DestinationSecurityGroupID string   `json:"destinationSecurityGroupId"`
-		SGACLIDs                   []string `json:"sgaclIds"`
-		Timestamp                  string   `json:"timestamp"`
+		ID                         string     `json:"id"`
+		Name                       string     `json:"name"`
+		MatrixId                   string     `json:"matrixId"`
+		Status                     string     `json:"status"`
+		Description                string     `json:"description"`
+		SourceSecurityGroupID      string     `json:"sourceSecurityGroupId"`
+		DestinationSecurityGroupID string     `json:"destinationSecurityGroupId"`
+		SGACLIDs                   []string   `json:"sgaclIds"`
+		Timestamp                  PxGridTime `json:"timestamp"`
 	}
 
 	VirtualNetwork struct {
-		ID                   string `json:"id"`
-		Name                 string `json:"name"`
-		AdditionalAttributes string `json:"additionalAttributes"`
-		Timestamp            string `json:"timestamp"`
+		ID                   string     `json:"id"`
+		Name                 string     `json:"name"`
+		AdditionalAttributes string     `json:"additionalAttributes"`
+		Timestamp            PxGridTime `json:"timestamp"`
 	}
 
 	SecurityGroupACL struct {
-		ID              string `json:"id"`
-		IsDeleted       bool   `json:"isDeleted"`
-		Name            string `json:"name"`
-		Description     string `json:"description"`
-		IPVersion       string `json:"ipVersion"`
-		ACL             string `json:"acl"`
-		ModelledContent any    `json:"modelledContent"`
-		GenerationID    string `json:"generationId"`
-		Timestamp       string `json:"timestamp"`
+		ID              string     `json:"id"`
+		IsDeleted       bool       `json:"isDeleted"`
+		Name            string     `json:"name"`
+		Description     string     `json:"description"`
+		IPVersion       string     `json:"ipVersion"`
+		ACL             string     `json:"acl"`
+		ModelledContent any        `json:"modelledContent"`
+		GenerationID    string     `json:"generationId"`
+		Timestamp       PxGridTime `json:"timestamp"`
 	}
 
 	SecurityGroup struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Tag         int    `json:"tag"`
-		Timestamp   string `json:"timestamp"`
+		ID          string     `json:"id"`
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Tag         int        `json:"tag"`
+		Timestamp   PxGridTime `json:"timestamp"`
 	}
 
 	GetSecurityGroupsResponse struct {
@@ -92,44 +93,44 @@ type (
 	}
 
 	SecurityGroupACLTopicMessage struct {
-		ID              string `json:"id"`
-		Name            string `json:"name"`
-		Description     string `json:"description"`
-		IPVersion       string `json:"ipVersion"`
-		ACL             string `json:"acl"`
-		ModelledContent any    `json:"modelledContent"`
-		GenerationID    string `json:"generationId"`
-		IsReadOnly      bool   `json:"isReadOnly"`
-		Sequence        int    `json:"sequence"`
-		Deleted         bool   `json:"deleted"`
-		Timestamp       string `json:"timestamp"`
+		ID              string     `json:"id"`
+		Name            string     `json:"name"`
+		Description     string     `json:"description"`
+		IPVersion       string     `json:"ipVersion"`
+		ACL             string     `json:"acl"`
+		ModelledContent any        `json:"modelledContent"`
+		GenerationID    string     `json:"generationId"`
+		IsReadOnly      bool       `json:"isReadOnly"`
+		Sequence        int        `json:"sequence"`
+		Deleted         bool       `json:"deleted"`
+		Timestamp       PxGridTime `json:"timestamp"`
 	}
 
 	SecurityGroupVNVlanTopicMessage any
 
 	VirtualNetworkTopicMessage struct {
-		ID                   string `json:"id"`
-		Name                 string `json:"name"`
-		AdditionalAttributes string `json:"additionalAttributes"`
-		Sequence             int    `json:"sequence"`
-		Deleted              bool   `json:"deleted"`
-		Timestamp            string `json:"timestamp"`
+		ID                   string     `json:"id"`
+		Name                 string     `json:"name"`
+		AdditionalAttributes string     `json:"additionalAttributes"`
+		Sequence             int        `json:"sequence"`
+		Deleted              bool       `json:"deleted"`
+		Timestamp            PxGridTime `json:"timestamp"`
 	}
 
 	EgressPolicyTopicMessage struct {
-		ID                 string   `json:"id"`
-		Name               string   `json:"name"`
-		Description        string   `json:"description"`
-		SourceSGTID        string   `json:"sourceSgtId"`
-		SourceSGTName      string   `json:"sourceSgtName"`
-		DestinationSGTID   string   `json:"destinationSgtId"`
-		DestinationSGTName string   `json:"destinationSgtName"`
-		MatrixCellStatus   string   `json:"matrixCellStatus"`
-		SGACLIDs           []string `json:"sgaclIds"`
-		DefaultRule        string   `json:"defaultRule"`
-		Sequence           int      `json:"sequence"`
-		Deleted            bool     `json:"deleted"`
-		Timestamp          string   `json:"timestamp"`
+		ID                 string     `json:"id"`
+		Name               string     `json:"name"`
+		Description        string     `json:"description"`
+		SourceSGTID        string     `json:"sourceSgtId"`
+		SourceSGTName      string     `json:"sourceSgtName"`
+		DestinationSGTID   string     `json:"destinationSgtId"`
+		DestinationSGTName string     `json:"destinationSgtName"`
+		MatrixCellStatus   string     `json:"matrixCellStatus"`
+		SGACLIDs           []string   `json:"sgaclIds"`
+		DefaultRule        string     `json:"defaultRule"`
+		Sequence           int        `json:"sequence"`
+		Deleted            bool       `json:"deleted"`
+		Timestamp          PxGridTime `json:"timestamp"`
 	}
 
 	TrustSecConfigurationTopic string
@@ -148,6 +149,11 @@ type (
 		GetVirtualNetwork(filters ...TrustSecConfigurationRequestFilter) CallFinalizer[*GetVirtualNetworksResponse]
 		GetEgressPolicies(filters ...TrustSecEgressPoliciesRequestFilter) CallFinalizer[*GetEgressPoliciesResponse]
 		GetEgressMatrices() CallFinalizer[*[]EgressMatrix]
+
+		IterSecurityGroups(pageSize int, filters ...TrustSecConfigurationRequestFilter) IterCallFinalizer[TrustSecRecord[SecurityGroup]]
+		IterSecurityGroupACLs(pageSize int, filters ...TrustSecConfigurationRequestFilter) IterCallFinalizer[TrustSecRecord[SecurityGroupACL]]
+		IterVirtualNetworks(pageSize int, filters ...TrustSecConfigurationRequestFilter) IterCallFinalizer[TrustSecRecord[VirtualNetwork]]
+		IterEgressPolicies(pageSize int, filters ...TrustSecEgressPoliciesRequestFilter) IterCallFinalizer[TrustSecRecord[EgressPolicy]]
 	}
 
 	TrustSecConfiguration interface {
@@ -263,6 +269,14 @@ func WithEndTimestamp(endTimestamp string) TrustSecConfigurationRequestFilter {
 	}
 }
 
+func WithStartTime(startTimestamp time.Time) TrustSecConfigurationRequestFilter {
+	return WithStartTimestamp(FormatTimestamp(startTimestamp))
+}
+
+func WithEndTime(endTimestamp time.Time) TrustSecConfigurationRequestFilter {
+	return WithEndTimestamp(FormatTimestamp(endTimestamp))
+}
+
 func applyTrustSecConfigFilters(f *trustSecConfigurationRequestFilter, filters []TrustSecConfigurationRequestFilter) {
 	for _, filter := range filters {
 		filter(f)
@@ -279,12 +293,12 @@ func (t *pxGridTrustSecConfiguration) GetSecurityGroups(filters ...TrustSecConfi
 		payload = map[string]any{}
 	}
 
-	return newCall[*GetSecurityGroupsResponse](
+	return query(newCall[*GetSecurityGroupsResponse](
 		&t.pxGridService,
 		"getSecurityGroups",
 		payload,
 		simpleResultMapper[*GetSecurityGroupsResponse],
-	)
+	))
 }
 
 func (t *pxGridTrustSecConfiguration) GetSecurityGroupACLs(filters ...TrustSecConfigurationRequestFilter) CallFinalizer[*GetSecurityGroupACLsResponse] {
@@ -297,12 +311,12 @@ func (t *pxGridTrustSecConfiguration) GetSecurityGroupACLs(filters ...TrustSecCo
 		payload = map[string]any{}
 	}
 
-	return newCall[*GetSecurityGroupACLsResponse](
+	return query(newCall[*GetSecurityGroupACLsResponse](
 		&t.pxGridService,
 		"getSecurityGroupAcls",
 		payload,
 		simpleResultMapper[*GetSecurityGroupACLsResponse],
-	)
+	))
 }
 
 func (t *pxGridTrustSecConfiguration) GetVirtualNetwork(filters ...TrustSecConfigurationRequestFilter) CallFinalizer[*GetVirtualNetworksResponse] {
@@ -315,12 +329,12 @@ func (t *pxGridTrustSecConfiguration) GetVirtualNetwork(filters ...TrustSecConfi
 		payload = map[string]any{}
 	}
 
-	return newCall[*GetVirtualNetworksResponse](
+	return query(newCall[*GetVirtualNetworksResponse](
 		&t.pxGridService,
 		"getVirtualNetwork",
 		payload,
 		simpleResultMapper[*GetVirtualNetworksResponse],
-	)
+	))
 }
 
 type (
@@ -368,6 +382,14 @@ func WithEgressPolicyMatrixID(matrixID string) TrustSecEgressPoliciesRequestFilt
 	}
 }
 
+func WithEgressPolicyStartTime(startTimestamp time.Time) TrustSecEgressPoliciesRequestFilter {
+	return WithEgressPolicyStartTimestamp(FormatTimestamp(startTimestamp))
+}
+
+func WithEgressPolicyEndTime(endTimestamp time.Time) TrustSecEgressPoliciesRequestFilter {
+	return WithEgressPolicyEndTimestamp(FormatTimestamp(endTimestamp))
+}
+
 func applyTrustSecEgressPoliciesFilters(f *trustSecEgressPoliciesRequestFilter, filters []TrustSecEgressPoliciesRequestFilter) {
 	for _, filter := range filters {
 		filter(f)
@@ -384,12 +406,12 @@ func (t *pxGridTrustSecConfiguration) GetEgressPolicies(filters ...TrustSecEgres
 		payload = map[string]any{}
 	}
 
-	return newCall[*GetEgressPoliciesResponse](
+	return query(newCall[*GetEgressPoliciesResponse](
 		&t.pxGridService,
 		"getEgressPolicies",
 		payload,
 		simpleResultMapper[*GetEgressPoliciesResponse],
-	)
+	))
 }
 
 func (t *pxGridTrustSecConfiguration) GetEgressMatrices() CallFinalizer[*[]EgressMatrix] {
@@ -397,7 +419,7 @@ func (t *pxGridTrustSecConfiguration) GetEgressMatrices() CallFinalizer[*[]Egres
 		EgressMatrices []EgressMatrix `json:"egressMatrices"`
 	}
 
-	return newCall[*[]EgressMatrix](
+	return query(newCallWithResult[*[]EgressMatrix, response](
 		&t.pxGridService,
 		"getEgressMatrices",
 		map[string]any{},
@@ -410,7 +432,7 @@ func (t *pxGridTrustSecConfiguration) GetEgressMatrices() CallFinalizer[*[]Egres
 			}
 			return &r.Result.(*response).EgressMatrices, nil
 		},
-	)
+	))
 }
 
 func (t *pxGridTrustSecConfiguration) OnSecurityGroupTopic() Subscriber[SecurityGroupTopicMessage] {
@@ -0,0 +1,201 @@
+package gopxgrid
+
+import (
+	"context"
+	"iter"
+)
+
+// DefaultTrustSecPageSize is the page size used by the TrustSec configuration
+// iterators when a non-positive page size is given
+const DefaultTrustSecPageSize = 500
+
+type (
+	// TrustSecRecord is a record returned by the TrustSec configuration iterators,
+	// Deleted is set for records listed as deleted by ISE
+	TrustSecRecord[T any] struct {
+		Record  T
+		Deleted bool
+	}
+
+	trustSecPage[T any] struct {
+		total   int
+		records []T
+		deleted []T
+	}
+
+	trustSecPageFetcher[T any] func(ctx context.Context, startIndex, recordCount int,
+		pickNode ...ServiceNodePickerFactory) (trustSecPage[T], error)
+
+	trustSecPager[T any] struct {
+		svc        *pxGridService
+		startIndex int
+		pageSize   int
+		fetch      trustSecPageFetcher[T]
+	}
+)
+
+func newTrustSecPager[T any](svc *pxGridService, startIndex *int, pageSize int, fetch trustSecPageFetcher[T]) IterCallFinalizer[TrustSecRecord[T]] {
+	if pageSize <= 0 {
+		pageSize = DefaultTrustSecPageSize
+	}
+
+	p := &trustSecPager[T]{
+		svc:      svc,
+		pageSize: pageSize,
+		fetch:    fetch,
+	}
+	if startIndex != nil {
+		p.startIndex = *startIndex
+	}
+
+	return p
+}
+
+func (p *trustSecPager[T]) Do(ctx context.Context) iter.Seq2[TrustSecRecord[T], error] {
+	return p.seq(ctx)
+}
+
+func (p *trustSecPager[T]) DoOnNode(ctx context.Context, node int) iter.Seq2[TrustSecRecord[T], error] {
+	return p.seq(ctx, IndexNodePicker(node))
+}
+
+func (p *trustSecPager[T]) DoOnNodeByName(ctx context.Context, nodeName string) iter.Seq2[TrustSecRecord[T], error] {
+	idx, err := p.svc.FindNodeIndexByName(nodeName)
+	if err != nil {
+		return errorSeq[TrustSecRecord[T]](err)
+	}
+
+	return p.seq(ctx, IndexNodePicker(idx))
+}
+
+func (p *trustSecPager[T]) DoOnNodes(ctx context.Context, nodes ...int) iter.Seq2[TrustSecRecord[T], error] {
+	return p.seq(ctx, IndexNodePicker(nodes...))
+}
+
+func (p *trustSecPager[T]) seq(ctx context.Context, pickNode ...ServiceNodePickerFactory) iter.Seq2[TrustSecRecord[T], error] {
+	return func(yield func(TrustSecRecord[T], error) bool) {
+		start := p.startIndex
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(TrustSecRecord[T]{}, err)
+				return
+			}
+
+			page, err := p.fetch(ctx, start, p.pageSize, pickNode...)
+			if err != nil {
+				yield(TrustSecRecord[T]{}, err)
+				return
+			}
+
+			for _, r := range page.records {
+				if !yield(TrustSecRecord[T]{Record: r}, nil) {
+					return
+				}
+			}
+			for _, r := range page.deleted {
+				if !yield(TrustSecRecord[T]{Record: r, Deleted: true}, nil) {
+					return
+				}
+			}
+
+			// recordCount limits the records of a page, deleted records are listed besides them.
+			// ISE may cap the page below recordCount, so a short page is the last one only
+			// without a total count
+			start += len(page.records)
+			if len(page.records) == 0 {
+				return
+			}
+			if page.total > 0 {
+				if start >= page.total {
+					return
+				}
+			} else if len(page.records) < p.pageSize {
+				return
+			}
+		}
+	}
+}
+
+func fetchTrustSecPage[R any, T any](ctx context.Context, svc *pxGridService, call string, payload any,
+	convert func(*R) trustSecPage[T], pickNode ...ServiceNodePickerFactory,
+) (trustSecPage[T], error) {
+	// the pages are queries
+	res, err := svc.send(ctx, call, payload, RESTOptions{result: new(R), idempotent: true}, pickNode...)
+	if err != nil {
+		return trustSecPage[T]{}, err
+	}
+
+	r, err := simpleResultMapper[*R](res)
+	if err != nil || r == nil {
+		return trustSecPage[T]{}, err
+	}
+
+	return convert(r), nil
+}
+
+func (t *pxGridTrustSecConfiguration) pagedFilter(filters []TrustSecConfigurationRequestFilter) trustSecConfigurationRequestFilter {
+	f := trustSecConfigurationRequestFilter{}
+	applyTrustSecConfigFilters(&f, filters)
+	return f
+}
+
+// IterSecurityGroups walks all pages of security groups
+func (t *pxGridTrustSecConfiguration) IterSecurityGroups(pageSize int, filters ...TrustSecConfigurationRequestFilter) IterCallFinalizer[TrustSecRecord[SecurityGroup]] {
+	base := t.pagedFilter(filters)
+
+	return newTrustSecPager(&t.pxGridService, base.StartIndex, pageSize,
+		func(ctx context.Context, startIndex, recordCount int, pickNode ...ServiceNodePickerFactory) (trustSecPage[SecurityGroup], error) {
+			f := base
+			f.StartIndex, f.RecordCount = &startIndex, &recordCount
+			return fetchTrustSecPage(ctx, &t.pxGridService, "getSecurityGroups", &f,
+				func(r *GetSecurityGroupsResponse) trustSecPage[SecurityGroup] {
+					return trustSecPage[SecurityGroup]{total: r.TotalCount, records: r.SecurityGroups, deleted: r.DeletedSecurityGroups}
+				}, pickNode...)
+		})
+}
+
+// IterSecurityGroupACLs walks all pages of security group ACLs
+func (t *pxGridTrustSecConfiguration) IterSecurityGroupACLs(pageSize int, filters ...TrustSecConfigurationRequestFilter) IterCallFinalizer[TrustSecRecord[SecurityGroupACL]] {
+	base := t.pagedFilter(filters)
+
+	return newTrustSecPager(&t.pxGridService, base.StartIndex, pageSize,
+		func(ctx context.Context, startIndex, recordCount int, pickNode ...ServiceNodePickerFactory) (trustSecPage[SecurityGroupACL], error) {
+			f := base
+			f.StartIndex, f.RecordCount = &startIndex, &recordCount
+			return fetchTrustSecPage(ctx, &t.pxGridService, "getSecurityGroupAcls", &f,
+				func(r *GetSecurityGroupACLsResponse) trustSecPage[SecurityGroupACL] {
+					return trustSecPage[SecurityGroupACL]{total: r.TotalCount, records: r.SecurityGroupACLs, deleted: r.DeleteSecurityGroupACLs}
+				}, pickNode...)
+		})
+}
+
+// IterVirtualNetworks walks all pages of virtual networks
+func (t *pxGridTrustSecConfiguration) IterVirtualNetworks(pageSize int, filters ...TrustSecConfigurationRequestFilter) IterCallFinalizer[TrustSecRecord[VirtualNetwork]] {
+	base := t.pagedFilter(filters)
+
+	return newTrustSecPager(&t.pxGridService, base.StartIndex, pageSize,
+		func(ctx context.Context, startIndex, recordCount int, pickNode ...ServiceNodePickerFactory) (trustSecPage[VirtualNetwork], error) {
+			f := base
+			f.StartIndex, f.RecordCount = &startIndex, &recordCount
+			return fetchTrustSecPage(ctx, &t.pxGridService, "getVirtualNetwork", &f,
+				func(r *GetVirtualNetworksResponse) trustSecPage[VirtualNetwork] {
+					return trustSecPage[VirtualNetwork]{total: r.TotalCount, records: r.VirtualNetworks, deleted: r.DeletedVirtualNetworks}
+				}, pickNode...)
+		})
+}
+
+// IterEgressPolicies walks all pages of egress policies
+func (t *pxGridTrustSecConfiguration) IterEgressPolicies(pageSize int, filters ...TrustSecEgressPoliciesRequestFilter) IterCallFinalizer[TrustSecRecord[EgressPolicy]] {
+	base := trustSecEgressPoliciesRequestFilter{}
+	applyTrustSecEgressPoliciesFilters(&base, filters)
+
+	return newTrustSecPager(&t.pxGridService, base.StartIndex, pageSize,
+		func(ctx context.Context, startIndex, recordCount int, pickNode ...ServiceNodePickerFactory) (trustSecPage[EgressPolicy], error) {
+			f := base
+			f.StartIndex, f.RecordCount = &startIndex, &recordCount
+			return fetchTrustSecPage(ctx, &t.pxGridService, "getEgressPolicies", &f,
+				func(r *GetEgressPoliciesResponse) trustSecPage[EgressPolicy] {
+					return trustSecPage[EgressPolicy]{total: r.TotalCount, records: r.EgressPolicies, deleted: r.DeletedEgressPolicies}
+				}, pickNode...)
+		})
+}
@@ -0,0 +1,120 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestTrustSecPager(t *testing.T) {
+	tests := []struct {
+		name       string
+		pageSize   int
+		pages      []trustSecPage[int]
+		want       []int
+		wantDel    []int
+		wantStarts []int
+	}{
+		{
+			name:     "short last page",
+			pageSize: 2,
+			pages: []trustSecPage[int]{
+				{records: []int{1, 2}},
+				{records: []int{3}},
+			},
+			want:       []int{1, 2, 3},
+			wantStarts: []int{0, 2},
+		},
+		{
+			name:     "deleted records do not fill a page",
+			pageSize: 2,
+			pages: []trustSecPage[int]{
+				{records: []int{1, 2}, deleted: []int{9}},
+				{records: []int{3}, deleted: []int{8}},
+				{records: []int{4, 5}},
+			},
+			want:       []int{1, 2, 3},
+			wantDel:    []int{9, 8},
+			wantStarts: []int{0, 2},
+		},
+		{
+			name:     "only deleted records",
+			pageSize: 2,
+			pages: []trustSecPage[int]{
+				{deleted: []int{7, 8, 9}},
+			},
+			wantDel:    []int{7, 8, 9},
+			wantStarts: []int{0},
+		},
+		{
+			name:     "total count",
+			pageSize: 2,
+			pages: []trustSecPage[int]{
+				{total: 4, records: []int{1, 2}},
+				{total: 4, records: []int{3, 4}},
+				{total: 4, records: []int{5}},
+			},
+			want:       []int{1, 2, 3, 4},
+			wantStarts: []int{0, 2},
+		},
+		{
+			name:     "server caps pages below the page size",
+			pageSize: 5,
+			pages: []trustSecPage[int]{
+				{total: 5, records: []int{1, 2}},
+				{total: 5, records: []int{3, 4}},
+				{total: 5, records: []int{5}},
+				{total: 5, records: []int{6}},
+			},
+			want:       []int{1, 2, 3, 4, 5},
+			wantStarts: []int{0, 2, 4},
+		},
+		{
+			name:     "empty page before the total",
+			pageSize: 2,
+			pages: []trustSecPage[int]{
+				{total: 10, records: []int{1, 2}},
+				{total: 10},
+			},
+			want:       []int{1, 2},
+			wantStarts: []int{0, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches := 0
+			var starts []int
+			p := newTrustSecPager[int](nil, nil, tt.pageSize,
+				func(_ context.Context, startIndex, recordCount int, _ ...ServiceNodePickerFactory) (trustSecPage[int], error) {
+					if recordCount != tt.pageSize {
+						t.Fatalf("recordCount = %d, want %d", recordCount, tt.pageSize)
+					}
+					starts = append(starts, startIndex)
+					fetches++
+					if fetches > len(tt.pages) {
+						return trustSecPage[int]{}, errors.New("no more pages")
+					}
+					return tt.pages[fetches-1], nil
+				})
+
+			var got, deleted []int
+			for r, err := range p.Do(context.Background()) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r.Deleted {
+					deleted = append(deleted, r.Record)
+				} else {
+					got = append(got, r.Record)
+				}
+			}
+			if !slices.Equal(got, tt.want) || !slices.Equal(deleted, tt.wantDel) {
+				t.Fatalf("got %v deleted %v, want %v deleted %v", got, deleted, tt.want, tt.wantDel)
+			}
+			if !slices.Equal(starts, tt.wantStarts) {
+				t.Fatalf("pages started at %v, want %v", starts, tt.wantStarts)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package gopxgrid
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
+	"strconv"
 )
 
 type (
@@ -13,6 +16,14 @@ type (
 		VPN          string `json:"vpn"`
 	}
 
+	// SXPBindingFilter is a server side filter of TrustSecSXPRest.GetBindings
+	SXPBindingFilter struct {
+		IPPrefix string `json:"ipPrefix,omitempty"`
+		Tag      string `json:"tag,omitempty"`
+		VPN      string `json:"vpn,omitempty"`
+		Source   string `json:"source,omitempty"`
+	}
+
 	TrustSecSXPBindingTopicMessage struct {
 		OperationType OperationType      `json:"operation"`
 		Binding       TrustSecSXPBinding `json:"binding"`
@@ -32,6 +43,9 @@ type (
 
 	TrustSecSXPRest interface {
 		GetBindings(filter any) CallFinalizer[*[]TrustSecSXPBinding]
+		GetVPNBindings(vpn string) CallFinalizer[*[]TrustSecSXPBinding]
+		StreamBindings(filter any) IterCallFinalizer[TrustSecSXPBinding]
+		StreamVPNBindings(vpn string) IterCallFinalizer[TrustSecSXPBinding]
 	}
 
 	TrustSecSXP interface {
@@ -85,17 +99,56 @@ func (t *pxGridTrustSecSXP) BindingTopic() (string, error) {
 	return t.nodes.GetPropertyString(string(TrustSecSXPTopicBinding))
 }
 
-func (t *pxGridTrustSecSXP) GetBindings(filter any) CallFinalizer[*[]TrustSecSXPBinding] {
+// Validate checks the values of the filter before they are sent to ISE
+func (f *SXPBindingFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	var errs []error
+	if f.IPPrefix != "" {
+		if _, err := netip.ParsePrefix(f.IPPrefix); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ipPrefix %q is not a CIDR", ErrInvalidInput, f.IPPrefix))
+		}
+	}
+	if f.Tag != "" {
+		if tag, err := strconv.Atoi(f.Tag); err != nil || tag < 0 || tag > 65535 {
+			errs = append(errs, fmt.Errorf("%w: tag %q is not a number between 0 and 65535", ErrInvalidInput, f.Tag))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func bindingsPayload(filter any) (map[string]any, error) {
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
 	payload := map[string]any{}
+	if f, ok := filter.(*SXPBindingFilter); ok && f == nil {
+		return payload, nil
+	}
 	if filter != nil {
 		payload["filter"] = filter
 	}
 
+	return payload, nil
+}
+
+// GetBindings retrieves the bindings.
+// Filters implementing Validate, e.g. *SXPBindingFilter, are validated before the request is sent
+func (t *pxGridTrustSecSXP) GetBindings(filter any) CallFinalizer[*[]TrustSecSXPBinding] {
+	payload, err := bindingsPayload(filter)
+	if err != nil {
+		return newFailedCall[*[]TrustSecSXPBinding](err)
+	}
+
 	type response struct {
 		Bindings []TrustSecSXPBinding `json:"bindings"`
 	}
 
-	return newCall[*[]TrustSecSXPBinding](
+	return query(newCallWithResult[*[]TrustSecSXPBinding, response](
 		&t.pxGridService,
 		"getBindings",
 		payload,
@@ -108,7 +161,36 @@ func (t *pxGridTrustSecSXP) GetBindings(filter any) CallFinalizer[*[]TrustSecSXP
 			}
 			return &r.Result.(*response).Bindings, nil
 		},
-	)
+	))
+}
+
+// GetVPNBindings retrieves the full binding table of a VPN
+func (t *pxGridTrustSecSXP) GetVPNBindings(vpn string) CallFinalizer[*[]TrustSecSXPBinding] {
+	if vpn == "" {
+		return newFailedCall[*[]TrustSecSXPBinding](ErrInvalidInput)
+	}
+
+	return t.GetBindings(&SXPBindingFilter{VPN: vpn})
+}
+
+// StreamBindings retrieves the bindings, decoding them one by one from the response body.
+// PxGridConfig.Timeout limits the idle time between reads, not the stream as a whole
+func (t *pxGridTrustSecSXP) StreamBindings(filter any) IterCallFinalizer[TrustSecSXPBinding] {
+	payload, err := bindingsPayload(filter)
+	if err != nil {
+		return newFailedStreamCall[TrustSecSXPBinding](err)
+	}
+
+	return query(newStreamCall[TrustSecSXPBinding](&t.pxGridService, "getBindings", payload, "bindings"))
+}
+
+// StreamVPNBindings streams the full binding table of a VPN
+func (t *pxGridTrustSecSXP) StreamVPNBindings(vpn string) IterCallFinalizer[TrustSecSXPBinding] {
+	if vpn == "" {
+		return newFailedStreamCall[TrustSecSXPBinding](ErrInvalidInput)
+	}
+
+	return t.StreamBindings(&SXPBindingFilter{VPN: vpn})
 }
 
 func (t *pxGridTrustSecSXP) OnBindingTopic() Subscriber[TrustSecSXPBindingTopicMessage] {
@@ -0,0 +1,89 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSXPBindingFilterValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   *SXPBindingFilter
+		wantErrs []string
+	}{
+		{name: "nil"},
+		{name: "empty", filter: &SXPBindingFilter{}},
+		{name: "all fields", filter: &SXPBindingFilter{IPPrefix: "10.0.0.0/8", Tag: "65535", VPN: "corp", Source: "10.1.1.1"}},
+		{name: "ipv6 prefix", filter: &SXPBindingFilter{IPPrefix: "2001:db8::/32", Tag: "0"}},
+		{name: "address without length", filter: &SXPBindingFilter{IPPrefix: "10.0.0.1"}, wantErrs: []string{"ipPrefix"}},
+		{name: "bad prefix", filter: &SXPBindingFilter{IPPrefix: "10.0.0.0/33"}, wantErrs: []string{"ipPrefix"}},
+		{name: "tag too large", filter: &SXPBindingFilter{Tag: "65536"}, wantErrs: []string{"tag"}},
+		{name: "negative tag", filter: &SXPBindingFilter{Tag: "-1"}, wantErrs: []string{"tag"}},
+		{name: "tag not a number", filter: &SXPBindingFilter{Tag: "0x10"}, wantErrs: []string{"tag"}},
+		{name: "combined", filter: &SXPBindingFilter{IPPrefix: "corp", Tag: "70000", VPN: "corp"}, wantErrs: []string{"ipPrefix", "tag"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if (err != nil) != (len(tt.wantErrs) > 0) {
+				t.Fatalf("Validate() = %v, want errors of %v", err, tt.wantErrs)
+			}
+			if err == nil {
+				return
+			}
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("Validate() = %v, want ErrInvalidInput", err)
+			}
+			// every invalid field is reported
+			for _, field := range tt.wantErrs {
+				if !strings.Contains(err.Error(), field) {
+					t.Fatalf("Validate() = %v, want an error of %s", err, field)
+				}
+			}
+		})
+	}
+}
+
+func TestGetBindingsRequestBody(t *testing.T) {
+	var body atomic.Value
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		TrustSecSXPServiceName + "/getBindings": func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body.Store(string(b))
+			w.Write([]byte(`{"bindings":[]}`))
+		},
+	})
+	c := newISEConsumer(t, srv, nil)
+
+	tests := []struct {
+		name    string
+		filter  any
+		want    string
+		wantErr bool
+	}{
+		{name: "no filter", want: `{}`},
+		{name: "nil filter", filter: (*SXPBindingFilter)(nil), want: `{}`},
+		{name: "empty filter", filter: &SXPBindingFilter{}, want: `{"filter":{}}`},
+		{name: "combined", filter: &SXPBindingFilter{IPPrefix: "10.0.0.0/8", Tag: "10", VPN: "corp"}, want: `{"filter":{"ipPrefix":"10.0.0.0/8","tag":"10","vpn":"corp"}}`},
+		{name: "vpn", filter: &SXPBindingFilter{VPN: "guest"}, want: `{"filter":{"vpn":"guest"}}`},
+		{name: "untyped", filter: map[string]string{"source": "10.1.1.1"}, want: `{"filter":{"source":"10.1.1.1"}}`},
+		{name: "invalid", filter: &SXPBindingFilter{Tag: "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body.Store("")
+			_, err := c.TrustSecSXP().Rest().GetBindings(tt.filter).Do(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBindings() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := body.Load().(string); got != tt.want {
+				t.Fatalf("request body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package gopxgrid
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultFailureWindow      = 15 * time.Minute
+	DefaultFailureBucket      = time.Minute
+	DefaultFailureBaseline    = time.Hour
+	DefaultFailureSpikeFactor = 3.0
+	DefaultFailureSpikeMin    = 10
+)
+
+type (
+	// FailureDimension is an attribute of Failure the aggregator counts by
+	FailureDimension string
+
+	// FailureAggregatorConfig configures FailureAggregator, zero values are replaced by the defaults
+	FailureAggregatorConfig struct {
+		// Window is the rolling window counts and top-N queries are computed over
+		Window time.Duration
+		// Bucket is the granularity of the window
+		Bucket time.Duration
+		// Baseline is the period preceding the window spikes are detected against
+		Baseline time.Duration
+		// SpikeFactor is the ratio of the window count to the expected count considered a spike
+		SpikeFactor float64
+		// SpikeMin is the minimal window count considered a spike
+		SpikeMin int
+	}
+
+	FailureCount struct {
+		Key   string `json:"key"`
+		Count int    `json:"count"`
+	}
+
+	// FailureSpike is a key whose failure count in the window exceeds its baseline.
+	// Dimension is empty for the spike of the total count.
+	FailureSpike struct {
+		Dimension FailureDimension `json:"dimension,omitempty"`
+		Key       string           `json:"key,omitempty"`
+		Count     int              `json:"count"`
+		// Expected is the count expected in the window according to the baseline
+		Expected float64 `json:"expected"`
+		Ratio    float64 `json:"ratio"`
+	}
+
+	// FailureSnapshot is the state of the aggregator at a point in time, ready to be exported as JSON
+	FailureSnapshot struct {
+		At     time.Time                           `json:"at"`
+		From   time.Time                           `json:"from"`
+		To     time.Time                           `json:"to"`
+		Total  int                                 `json:"total"`
+		Top    map[FailureDimension][]FailureCount `json:"top"`
+		Spikes []FailureSpike                      `json:"spikes"`
+	}
+
+	// FailureAggregator keeps rolling time window counts of RADIUS failures
+	FailureAggregator struct {
+		cfg     FailureAggregatorConfig
+		buckets map[int64]*failureBucket
+		latest  int64
+		// started is the creation time, spikes are detected once the baseline
+		// preceding the window was observed
+		started time.Time
+
+		l sync.RWMutex
+	}
+
+	failureBucket struct {
+		total  int
+		counts map[FailureDimension]map[string]int
+	}
+)
+
+const (
+	FailureDimensionReason           FailureDimension = "failureReason"
+	FailureDimensionNASIPAddress     FailureDimension = "nasIpAddress"
+	FailureDimensionNetworkDevice    FailureDimension = "networkDeviceName"
+	FailureDimensionUserName         FailureDimension = "userName"
+	FailureDimensionCallingStationID FailureDimension = "callingStationId"
+)
+
+// FailureDimensions lists all dimensions counted by FailureAggregator
+var FailureDimensions = []FailureDimension{
+	FailureDimensionReason,
+	FailureDimensionNASIPAddress,
+	FailureDimensionNetworkDevice,
+	FailureDimensionUserName,
+	FailureDimensionCallingStationID,
+}
+
+func (d FailureDimension) value(f *Failure) string {
+	switch d {
+	case FailureDimensionReason:
+		return f.FailureReason
+	case FailureDimensionNASIPAddress:
+		return f.NASIPAddress
+	case FailureDimensionNetworkDevice:
+		return f.NetworkDeviceName
+	case FailureDimensionUserName:
+		return f.UserName
+	case FailureDimensionCallingStationID:
+		return f.CallingStationID
+	}
+	return ""
+}
+
+func NewFailureAggregator(cfg FailureAggregatorConfig) *FailureAggregator {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultFailureWindow
+	}
+	if cfg.Bucket <= 0 {
+		cfg.Bucket = DefaultFailureBucket
+	}
+	if cfg.Bucket > cfg.Window {
+		cfg.Bucket = cfg.Window
+	}
+	if cfg.Baseline <= 0 {
+		cfg.Baseline = DefaultFailureBaseline
+	}
+	if cfg.SpikeFactor <= 0 {
+		cfg.SpikeFactor = DefaultFailureSpikeFactor
+	}
+	if cfg.SpikeMin <= 0 {
+		cfg.SpikeMin = DefaultFailureSpikeMin
+	}
+
+	return &FailureAggregator{
+		cfg:     cfg,
+		buckets: make(map[int64]*failureBucket),
+		started: time.Now(),
+	}
+}
+
+func (a *FailureAggregator) bucketIndex(t time.Time) int64 {
+	return t.UnixNano() / int64(a.cfg.Bucket)
+}
+
+func (a *FailureAggregator) windowBuckets() int64 {
+	return int64((a.cfg.Window + a.cfg.Bucket - 1) / a.cfg.Bucket)
+}
+
+func (a *FailureAggregator) baselineBuckets() int64 {
+	return int64((a.cfg.Baseline + a.cfg.Bucket - 1) / a.cfg.Bucket)
+}
+
+// prune drops buckets older than the window and the baseline, must be called with the lock held
+func (a *FailureAggregator) prune(current int64) {
+	oldest := current - a.windowBuckets() - a.baselineBuckets()
+	for idx := range a.buckets {
+		if idx <= oldest {
+			delete(a.buckets, idx)
+		}
+	}
+}
+
+// Add counts the failures. Failures without timestamp are counted at the current time,
+// failures older than the window and the baseline are ignored.
+func (a *FailureAggregator) Add(failures ...Failure) {
+	now := time.Now()
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	current := a.bucketIndex(now)
+	oldest := current - a.windowBuckets() - a.baselineBuckets()
+	for i := range failures {
+		f := &failures[i]
+		ts := f.Timestamp.Time
+		if ts.IsZero() || ts.After(now) {
+			ts = now
+		}
+
+		idx := a.bucketIndex(ts)
+		if idx <= oldest {
+			continue
+		}
+
+		b, ok := a.buckets[idx]
+		if !ok {
+			b = &failureBucket{counts: make(map[FailureDimension]map[string]int, len(FailureDimensions))}
+			a.buckets[idx] = b
+		}
+
+		b.total++
+		for _, d := range FailureDimensions {
+			v := d.value(f)
+			if v == "" {
+				continue
+			}
+			if b.counts[d] == nil {
+				b.counts[d] = make(map[string]int)
+			}
+			b.counts[d][v]++
+		}
+	}
+
+	if current > a.latest {
+		a.latest = current
+		a.prune(current)
+	}
+}
+
+// Apply counts the failures of a failure topic message
+func (a *FailureAggregator) Apply(msg FailureTopicMessage) {
+	a.Add(msg.Failures...)
+}
+
+// Follow counts failures of the failure topic subscription until the context
+// is done or the subscription is closed. Messages which failed to be read
+// are passed to onError if it is not nil.
+func (a *FailureAggregator) Follow(ctx context.Context, sub *Subscription[FailureTopicMessage], onError func(error)) error {
+	return follow(ctx, sub, func(msg FailureTopicMessage) error {
+		a.Apply(msg)
+		return nil
+	}, onError)
+}
+
+// sum adds up the buckets in (from, to], must be called with the read lock held
+func (a *FailureAggregator) sum(from, to int64) (int, map[FailureDimension]map[string]int) {
+	total := 0
+	counts := make(map[FailureDimension]map[string]int, len(FailureDimensions))
+	for idx, b := range a.buckets {
+		if idx <= from || idx > to {
+			continue
+		}
+		total += b.total
+		for d, keys := range b.counts {
+			if counts[d] == nil {
+				counts[d] = make(map[string]int, len(keys))
+			}
+			for k, n := range keys {
+				counts[d][k] += n
+			}
+		}
+	}
+	return total, counts
+}
+
+func (a *FailureAggregator) window(now time.Time) (from, to int64) {
+	to = a.bucketIndex(now)
+	return to - a.windowBuckets(), to
+}
+
+// Total returns the number of failures in the window
+func (a *FailureAggregator) Total() int {
+	a.l.RLock()
+	defer a.l.RUnlock()
+
+	total, _ := a.sum(a.window(time.Now()))
+	return total
+}
+
+// Top returns the n keys of the dimension with most failures in the window,
+// all keys if n is not positive
+func (a *FailureAggregator) Top(d FailureDimension, n int) []FailureCount {
+	a.l.RLock()
+	defer a.l.RUnlock()
+
+	_, counts := a.sum(a.window(time.Now()))
+	return topFailures(counts[d], n)
+}
+
+func topFailures(counts map[string]int, n int) []FailureCount {
+	res := make([]FailureCount, 0, len(counts))
+	for k, c := range counts {
+		res = append(res, FailureCount{Key: k, Count: c})
+	}
+	slices.SortFunc(res, func(a, b FailureCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Key, b.Key))
+	})
+	if n > 0 && len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+// Spikes returns the total and the keys whose count in the window is at least SpikeMin
+// and SpikeFactor times the count expected from the baseline. Keys not seen during
+// the baseline are expected to have a single failure per window. No spikes are
+// returned until the aggregator exists for the window and the baseline.
+func (a *FailureAggregator) Spikes() []FailureSpike {
+	a.l.RLock()
+	defer a.l.RUnlock()
+
+	return a.spikes(time.Now())
+}
+
+func (a *FailureAggregator) spikes(now time.Time) []FailureSpike {
+	// the baseline of a new aggregator is empty, every key would be a spike
+	if now.Sub(a.started) < a.cfg.Window+a.cfg.Baseline {
+		return nil
+	}
+
+	from, to := a.window(now)
+	total, counts := a.sum(from, to)
+	baseTotal, baseCounts := a.sum(from-a.baselineBuckets(), from)
+	scale := float64(a.windowBuckets()) / float64(a.baselineBuckets())
+
+	var res []FailureSpike
+	check := func(d FailureDimension, key string, count, base int) {
+		if count < a.cfg.SpikeMin {
+			return
+		}
+		expected := float64(base) * scale
+		ratio := float64(count) / max(expected, 1)
+		if ratio >= a.cfg.SpikeFactor {
+			res = append(res, FailureSpike{Dimension: d, Key: key, Count: count, Expected: expected, Ratio: ratio})
+		}
+	}
+
+	check("", "", total, baseTotal)
+	for _, d := range FailureDimensions {
+		for k, c := range counts[d] {
+			check(d, k, c, baseCounts[d][k])
+		}
+	}
+
+	slices.SortFunc(res, func(a, b FailureSpike) int {
+		return cmp.Or(cmp.Compare(b.Ratio, a.Ratio), strings.Compare(string(a.Dimension), string(b.Dimension)), strings.Compare(a.Key, b.Key))
+	})
+	return res
+}
+
+// Snapshot returns the total, the top n keys of every dimension and the spikes of the window
+func (a *FailureAggregator) Snapshot(n int) FailureSnapshot {
+	now := time.Now()
+
+	a.l.RLock()
+	defer a.l.RUnlock()
+
+	from, to := a.window(now)
+	total, counts := a.sum(from, to)
+	snap := FailureSnapshot{
+		At:     now,
+		From:   time.Unix(0, (from+1)*int64(a.cfg.Bucket)),
+		To:     now,
+		Total:  total,
+		Top:    make(map[FailureDimension][]FailureCount, len(FailureDimensions)),
+		Spikes: a.spikes(now),
+	}
+	for _, d := range FailureDimensions {
+		snap.Top[d] = topFailures(counts[d], n)
+	}
+	if snap.Spikes == nil {
+		snap.Spikes = []FailureSpike{}
+	}
+
+	return snap
+}
+
+// Report calls fn with a snapshot of the top n keys every interval until the context is done
+func (a *FailureAggregator) Report(ctx context.Context, interval time.Duration, n int, fn func(FailureSnapshot)) error {
+	if interval <= 0 {
+		return fmt.Errorf("%w: report interval must be positive", ErrInvalidInput)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			fn(a.Snapshot(n))
+		}
+	}
+}
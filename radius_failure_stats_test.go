@@ -0,0 +1,93 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func failuresAt(t time.Time, n int, user string) []Failure {
+	res := make([]Failure, n)
+	for i := range res {
+		res[i] = Failure{Timestamp: NewPxGridTime(t), FailureReason: "bad password", UserName: user}
+	}
+	return res
+}
+
+func TestFailureAggregatorTop(t *testing.T) {
+	a := NewFailureAggregator(FailureAggregatorConfig{})
+	now := time.Now()
+	a.Add(failuresAt(now, 3, "alice")...)
+	a.Add(failuresAt(now.Add(-time.Minute), 5, "bob")...)
+	a.Add(failuresAt(now.Add(-2*DefaultFailureWindow), 7, "carol")...)
+
+	if got := a.Total(); got != 8 {
+		t.Fatalf("Total() = %d, want 8", got)
+	}
+	top := a.Top(FailureDimensionUserName, 1)
+	if len(top) != 1 || top[0] != (FailureCount{Key: "bob", Count: 5}) {
+		t.Fatalf("Top() = %v, want bob with 5", top)
+	}
+}
+
+func TestFailureAggregatorSpikes(t *testing.T) {
+	cfg := FailureAggregatorConfig{Window: 10 * time.Minute, Bucket: time.Minute, Baseline: 30 * time.Minute, SpikeFactor: 3, SpikeMin: 10}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		age     time.Duration
+		base    int
+		window  int
+		wantKey bool
+	}{
+		{name: "cold start", age: 20 * time.Minute, window: 50},
+		{name: "new key", age: time.Hour, window: 50, wantKey: true},
+		{name: "below minimum", age: time.Hour, window: 9},
+		{name: "steady", age: time.Hour, base: 60, window: 20},
+		{name: "tripled", age: time.Hour, base: 30, window: 30, wantKey: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewFailureAggregator(cfg)
+			a.started = now.Add(-tt.age)
+			a.Add(failuresAt(now.Add(-cfg.Window-5*time.Minute), tt.base, "alice")...)
+			a.Add(failuresAt(now, tt.window, "alice")...)
+
+			found := false
+			for _, s := range a.Spikes() {
+				if s.Dimension == FailureDimensionUserName && s.Key == "alice" {
+					found = true
+				}
+			}
+			if found != tt.wantKey {
+				t.Fatalf("spike of alice = %v, want %v in %v", found, tt.wantKey, a.Spikes())
+			}
+		})
+	}
+}
+
+func TestFailureAggregatorReport(t *testing.T) {
+	a := NewFailureAggregator(FailureAggregatorConfig{})
+	for _, interval := range []time.Duration{0, -time.Second} {
+		t.Run(fmt.Sprint(interval), func(t *testing.T) {
+			err := a.Report(context.Background(), interval, 5, func(FailureSnapshot) {})
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("Report() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reports := 0
+	err := a.Report(ctx, time.Millisecond, 5, func(FailureSnapshot) {
+		if reports++; reports == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) || reports != 2 {
+		t.Fatalf("Report() = %v after %d reports", err, reports)
+	}
+}
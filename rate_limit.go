@@ -0,0 +1,204 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrRateLimited = errors.New("rate limit wait exceeds deadline")
+
+type (
+	// RateLimit describes a token bucket
+	RateLimit struct {
+		// Rate is the number of calls allowed per second, zero or negative disables the limit
+		Rate float64
+		// Burst is the maximum number of calls allowed at once, defaults to 1
+		Burst int
+	}
+
+	// RateLimitConfig holds token bucket limits by service name and by node name
+	RateLimitConfig struct {
+		Services map[string]RateLimit
+		Nodes    map[string]RateLimit
+	}
+
+	// RateLimitCounters holds counters of a single token bucket
+	RateLimitCounters struct {
+		// Allowed is the number of calls which passed without waiting
+		Allowed uint64
+		// Throttled is the number of calls which had to wait for a token
+		Throttled uint64
+		// Rejected is the number of calls which failed because of the context
+		Rejected uint64
+		// Waited is the total time spent waiting for tokens
+		Waited time.Duration
+	}
+
+	// RateLimitStats holds counters of all configured token buckets
+	RateLimitStats struct {
+		Services map[string]RateLimitCounters
+		Nodes    map[string]RateLimitCounters
+	}
+
+	tokenBucket struct {
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+
+		allowed   atomic.Uint64
+		throttled atomic.Uint64
+		rejected  atomic.Uint64
+		waited    atomic.Int64
+
+		l sync.Mutex
+	}
+
+	rateLimiter struct {
+		services map[string]*tokenBucket
+		nodes    map[string]*tokenBucket
+	}
+)
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+func (b *tokenBucket) release() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Wait blocks until a token is available, the context is done or
+// the context deadline is known to expire before a token becomes available
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	b.l.Lock()
+	now := time.Now()
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		b.l.Unlock()
+		b.allowed.Add(1)
+		return nil
+	}
+
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		b.tokens++
+		b.l.Unlock()
+		b.rejected.Add(1)
+		return fmt.Errorf("%w: need to wait %s", ErrRateLimited, delay)
+	}
+	b.l.Unlock()
+
+	b.throttled.Add(1)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		b.waited.Add(int64(delay))
+		return nil
+	case <-ctx.Done():
+		b.release()
+		b.rejected.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (b *tokenBucket) Counters() RateLimitCounters {
+	return RateLimitCounters{
+		Allowed:   b.allowed.Load(),
+		Throttled: b.throttled.Load(),
+		Rejected:  b.rejected.Load(),
+		Waited:    time.Duration(b.waited.Load()),
+	}
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	l := &rateLimiter{
+		services: make(map[string]*tokenBucket),
+		nodes:    make(map[string]*tokenBucket),
+	}
+
+	for name, limit := range cfg.Services {
+		if b := newTokenBucket(limit); b != nil {
+			l.services[name] = b
+		}
+	}
+	for name, limit := range cfg.Nodes {
+		if b := newTokenBucket(limit); b != nil {
+			l.nodes[name] = b
+		}
+	}
+
+	return l
+}
+
+// WaitService waits for a token of the service bucket if one is configured
+func (l *rateLimiter) WaitService(ctx context.Context, service string) error {
+	if b, ok := l.services[service]; ok {
+		return b.Wait(ctx)
+	}
+	return nil
+}
+
+// WaitNode waits for a token of the node bucket if one is configured
+func (l *rateLimiter) WaitNode(ctx context.Context, node string) error {
+	if b, ok := l.nodes[node]; ok {
+		return b.Wait(ctx)
+	}
+	return nil
+}
+
+func (l *rateLimiter) Stats() RateLimitStats {
+	stats := RateLimitStats{
+		Services: make(map[string]RateLimitCounters, len(l.services)),
+		Nodes:    make(map[string]RateLimitCounters, len(l.nodes)),
+	}
+
+	for name, b := range l.services {
+		stats.Services[name] = b.Counters()
+	}
+	for name, b := range l.nodes {
+		stats.Nodes[name] = b.Counters()
+	}
+
+	return stats
+}
@@ -0,0 +1,63 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   RateLimit
+		calls   int
+		timeout time.Duration
+		wantErr error
+	}{
+		{name: "within burst", limit: RateLimit{Rate: 1, Burst: 3}, calls: 3, timeout: time.Second},
+		{name: "throttled", limit: RateLimit{Rate: 50, Burst: 1}, calls: 2, timeout: time.Second},
+		{name: "deadline too short", limit: RateLimit{Rate: 0.1, Burst: 1}, calls: 2, timeout: time.Second, wantErr: ErrRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.limit)
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			var err error
+			for range tt.calls {
+				if err = b.Wait(ctx); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wait() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimitedNodeFailover(t *testing.T) {
+	limited := newJSONServer(t, map[string]string{"node": "limited"})
+	other := newJSONServer(t, map[string]string{"node": "other"})
+
+	cfg := NewPxGridConfig()
+	cfg.RateLimits.Nodes = map[string]RateLimit{"node0": {Rate: 0.01, Burst: 1}}
+	c := newTestConsumer(t, cfg)
+	svc := newTestService(c, "svc", limited.URL, other.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	want := []string{"limited", "other"}
+	for _, w := range want {
+		var result map[string]string
+		if _, err := svc.send(ctx, "call", map[string]any{}, RESTOptions{result: &result}, OrderedNodePicker()); err != nil {
+			t.Fatal(err)
+		}
+		if result["node"] != w {
+			t.Fatalf("served by %q, want %q", result["node"], w)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package gopxgrid
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactedKeys are the keys scrubbed by NewRedactingLogger, a key
+// matches if it contains one of them ignoring case
+var DefaultRedactedKeys = []string{"password", "secret", "authorization", "token", "cookie", "credential"}
+
+var (
+	_ slog.LogValuer = PxGridConfig{}
+	_ slog.LogValuer = AuthConfig{}
+	_ slog.LogValuer = ProxyConfig{}
+	_ slog.LogValuer = ServiceNode{}
+	_ slog.LogValuer = RESTCall{}
+	_ slog.LogValuer = ConfigFile{}
+	_ slog.LogValuer = (*Request)(nil)
+	_ slog.LogValuer = (*PubSubEndpoint)(nil)
+)
+
+func (c PxGridConfig) LogValue() slog.Value {
+	hosts := make([]string, len(c.Hosts))
+	for i, h := range c.Hosts {
+		hosts[i] = net.JoinHostPort(h.Host, strconv.Itoa(h.ControlPort))
+	}
+
+	return slog.GroupValue(
+		slog.Any("hosts", hosts),
+		slog.String("nodeName", c.NodeName),
+		slog.String("description", c.Description),
+		slog.Any("auth", c.Auth),
+		slog.Group("tls",
+			slog.Bool("clientCertificate", c.TLS.ClientCertificate != nil),
+			slog.Bool("insecure", c.TLS.InsecureTLS),
+			slog.Bool("ca", c.TLS.CA != nil),
+			slog.Any("pins", c.TLS.Pins),
+		),
+		slog.Group("dns",
+			slog.String("server", c.DNS.Server),
+			slog.Int("familyStrategy", int(c.DNS.FamilyStrategy)),
+			slog.Bool("cache", c.DNS.Cache),
+			slog.String("srv", c.DNS.SRV),
+		),
+		slog.Any("proxy", c.Proxy),
+		slog.Group("retry",
+			slog.Int("count", c.Retry.Count),
+			slog.Duration("wait", c.Retry.WaitTime),
+			slog.Duration("maxWait", c.Retry.MaxWaitTime),
+		),
+		slog.Duration("timeout", c.Timeout),
+	)
+}
+
+func (a AuthConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", a.Username),
+		slog.String("password", redactString(a.Password)),
+	)
+}
+
+func (c ProxyConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("url", redactURL(c.URL)),
+		slog.Any("bypass", c.Bypass),
+		slog.Bool("direct", c.Direct),
+	)
+}
+
+func (n ServiceNode) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", n.Name),
+		slog.String("nodeName", n.NodeName),
+		slog.Any("properties", n.Properties),
+		slog.String("secret", redactString(n.Secret)),
+	)
+}
+
+func (c RESTCall) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("method", c.Method),
+		slog.String("name", c.Name),
+		slog.String("service", c.Service),
+		slog.String("node", c.Node),
+		slog.String("url", redactURL(c.URL)),
+		slog.Any("header", redactHeader(c.Header, DefaultRedactedKeys)),
+	)
+}
+
+// LogValue hides the passwords and key passwords of the file, the proxy
+// credentials are removed from the URL
+func (f ConfigFile) LogValue() slog.Value {
+	// plain has no LogValue method, it is logged as is
+	type plain ConfigFile
+	p := plain(f)
+	p.Password = redactString(p.Password)
+	p.TLS.KeyPassword = redactString(p.TLS.KeyPassword)
+	p.Proxy.URL = redactURL(p.Proxy.URL)
+	return slog.AnyValue(p)
+}
+
+func (r *Request) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Any("header", redactHeader(r.header, DefaultRedactedKeys)),
+		slog.Bool("stream", r.stream),
+	}
+	if r.auth != nil {
+		attrs = append(attrs, slog.Any("auth", *r.auth))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func (e *PubSubEndpoint) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("wsURL", redactURL(e.wsURL)),
+		slog.String("nodeName", e.nodeName),
+	)
+}
+
+// redactString tells whether a secret is set without revealing it
+func redactString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL removes the password of URLs with user info, other strings are
+// returned as is. Unparsable URLs with user info are hidden completely
+func redactURL(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		if strings.Contains(s, "://") {
+			return redactedValue
+		}
+		return s
+	}
+	return u.Redacted()
+}
+
+func redactHeader(h http.Header, keys []string) http.Header {
+	if h == nil {
+		return nil
+	}
+	out := h.Clone()
+	for k := range out {
+		if matchesKey(k, keys) {
+			out[k] = []string{redactedValue}
+		}
+	}
+	return out
+}
+
+func matchesKey(key string, keys []string) bool {
+	key = strings.ToLower(key)
+	for _, k := range keys {
+		if strings.Contains(key, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ Logger = (*redactingLogger)(nil)
+
+type redactingLogger struct {
+	Logger
+	keys []string
+}
+
+// NewRedactingLogger wraps the logger to scrub the values of keys matching
+// one of keys, DefaultRedactedKeys if none are given. Groups and slog.LogValuer
+// values are resolved and scrubbed too, passwords are removed from URLs and
+// matching headers of http.Header values are replaced
+func NewRedactingLogger(logger Logger, keys ...string) Logger {
+	if len(keys) == 0 {
+		keys = DefaultRedactedKeys
+	}
+	return &redactingLogger{Logger: logger, keys: keys}
+}
+
+func (l *redactingLogger) Debug(msg string, args ...any) {
+	l.Logger.Debug(msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.Logger.DebugContext(ctx, msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Error(msg string, args ...any) {
+	l.Logger.Error(msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.Logger.ErrorContext(ctx, msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Info(msg string, args ...any) {
+	l.Logger.Info(msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.Logger.InfoContext(ctx, msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Warn(msg string, args ...any) {
+	l.Logger.Warn(msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.Logger.WarnContext(ctx, msg, l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) With(args ...any) Logger {
+	return &redactingLogger{Logger: l.Logger.With(l.redactArgs(args)...), keys: l.keys}
+}
+
+// redactArgs converts the key-value pairs and attributes of args to scrubbed
+// attributes, arguments without a key are kept as is
+func (l *redactingLogger) redactArgs(args []any) []any {
+	out := make([]any, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch a := args[i].(type) {
+		case slog.Attr:
+			out = append(out, l.redactAttr(a))
+		case string:
+			if i+1 >= len(args) {
+				out = append(out, a)
+				continue
+			}
+			out = append(out, l.redactAttr(slog.Any(a, args[i+1])))
+			i++
+		default:
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (l *redactingLogger) redactAttr(a slog.Attr) slog.Attr {
+	if matchesKey(a.Key, l.keys) {
+		return slog.String(a.Key, redactedValue)
+	}
+	return slog.Attr{Key: a.Key, Value: l.redactValue(a.Value)}
+}
+
+func (l *redactingLogger) redactValue(v slog.Value) slog.Value {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		out := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			out[i] = l.redactAttr(a)
+		}
+		return slog.GroupValue(out...)
+	case slog.KindString:
+		return slog.StringValue(redactURL(v.String()))
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case http.Header:
+			return slog.AnyValue(redactHeader(x, l.keys))
+		case *url.URL:
+			if x != nil {
+				return slog.StringValue(x.Redacted())
+			}
+		case url.URL:
+			return slog.StringValue(x.Redacted())
+		}
+	}
+	return v
+}
@@ -72,7 +72,7 @@ func (p *predicateNodePicker) PickNode() (*ServiceNode, bool, error) {
 
 	for i := p.last; i < len(p.nodes); i++ {
 		if p.predicate(i, p.nodes[i]) {
-			p.last = i
+			p.last = i + 1
 			return &p.nodes[i], i < len(p.nodes)-1, nil
 		}
 	}
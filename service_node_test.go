@@ -0,0 +1,65 @@
+package gopxgrid
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNodePickers(t *testing.T) {
+	nodes := ServiceNodeSlice{{Name: "x", NodeName: "a"}, {Name: "y", NodeName: "b"}, {Name: "x", NodeName: "c"}}
+
+	tests := []struct {
+		name    string
+		factory ServiceNodePickerFactory
+		want    []string
+	}{
+		{name: "ordered", factory: OrderedNodePicker(), want: []string{"a", "b", "c"}},
+		{name: "index", factory: IndexNodePicker(2, 0), want: []string{"a", "c"}},
+		{name: "name", factory: NameNodePicker("x"), want: []string{"a", "c"}},
+		{name: "predicate", factory: PredicateNodePicker(func(n ServiceNode) bool { return n.NodeName != "a" }), want: []string{"b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.factory(nodes)
+			var got []string
+			for range len(nodes) + 1 {
+				node, more, err := p.PickNode()
+				if err != nil {
+					if !errors.Is(err, ErrNodeNotFound) {
+						t.Fatal(err)
+					}
+					break
+				}
+				got = append(got, node.NodeName)
+				if !more {
+					break
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomNodePicker(t *testing.T) {
+	nodes := ServiceNodeSlice{{NodeName: "a"}, {NodeName: "b"}, {NodeName: "c"}}
+	p := RandomNodePicker()(nodes)
+
+	var got []string
+	for {
+		node, more, err := p.PickNode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, node.NodeName)
+		if !more {
+			break
+		}
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("picked %v, want every node once", got)
+	}
+}
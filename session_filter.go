@@ -0,0 +1,165 @@
+package gopxgrid
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+type (
+	// SessionFilter is a server side filter of SessionDirectoryRest.GetSessions
+	SessionFilter struct {
+		IPAddress        string       `json:"ipAddress,omitempty"`
+		MacAddress       string       `json:"macAddress,omitempty"`
+		UserName         string       `json:"userName,omitempty"`
+		State            SessionState `json:"state,omitempty"`
+		NasIPAddress     string       `json:"nasIpAddress,omitempty"`
+		NasIdentifier    string       `json:"nasIdentifier,omitempty"`
+		NasPortID        string       `json:"nasPortId,omitempty"`
+		CallingStationID string       `json:"callingStationId,omitempty"`
+		AuditSessionID   string       `json:"auditSessionId,omitempty"`
+		EndpointProfile  string       `json:"endpointProfile,omitempty"`
+		PostureStatus    string       `json:"postureStatus,omitempty"`
+		CTSSecurityGroup string       `json:"ctsSecurityGroup,omitempty"`
+		SSID             string       `json:"ssid,omitempty"`
+	}
+
+	// UserGroupFilter is a server side filter of SessionDirectoryRest.GetUserGroups
+	UserGroupFilter struct {
+		UserName string    `json:"userName,omitempty"`
+		Type     GroupType `json:"type,omitempty"`
+	}
+
+	filterValidator interface {
+		Validate() error
+	}
+)
+
+func NewSessionFilter() *SessionFilter {
+	return &SessionFilter{}
+}
+
+func (f *SessionFilter) WithIPAddress(ip string) *SessionFilter {
+	f.IPAddress = ip
+	return f
+}
+
+func (f *SessionFilter) WithMacAddress(mac string) *SessionFilter {
+	f.MacAddress = mac
+	return f
+}
+
+func (f *SessionFilter) WithUserName(userName string) *SessionFilter {
+	f.UserName = userName
+	return f
+}
+
+func (f *SessionFilter) WithState(state SessionState) *SessionFilter {
+	f.State = state
+	return f
+}
+
+func (f *SessionFilter) WithNasIPAddress(ip string) *SessionFilter {
+	f.NasIPAddress = ip
+	return f
+}
+
+func (f *SessionFilter) WithNasIdentifier(nasIdentifier string) *SessionFilter {
+	f.NasIdentifier = nasIdentifier
+	return f
+}
+
+func (f *SessionFilter) WithNasPortID(nasPortID string) *SessionFilter {
+	f.NasPortID = nasPortID
+	return f
+}
+
+func (f *SessionFilter) WithCallingStationID(callingStationID string) *SessionFilter {
+	f.CallingStationID = callingStationID
+	return f
+}
+
+func (f *SessionFilter) WithAuditSessionID(auditSessionID string) *SessionFilter {
+	f.AuditSessionID = auditSessionID
+	return f
+}
+
+func (f *SessionFilter) WithEndpointProfile(profile string) *SessionFilter {
+	f.EndpointProfile = profile
+	return f
+}
+
+func (f *SessionFilter) WithPostureStatus(status string) *SessionFilter {
+	f.PostureStatus = status
+	return f
+}
+
+func (f *SessionFilter) WithCTSSecurityGroup(group string) *SessionFilter {
+	f.CTSSecurityGroup = group
+	return f
+}
+
+func (f *SessionFilter) WithSSID(ssid string) *SessionFilter {
+	f.SSID = ssid
+	return f
+}
+
+// Validate checks the values of the filter before they are sent to ISE
+func (f *SessionFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	var errs []error
+	if f.IPAddress != "" {
+		if _, err := netip.ParseAddr(f.IPAddress); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ipAddress %q is not an IP address", ErrInvalidInput, f.IPAddress))
+		}
+	}
+	if f.NasIPAddress != "" {
+		if _, err := netip.ParseAddr(f.NasIPAddress); err != nil {
+			errs = append(errs, fmt.Errorf("%w: nasIpAddress %q is not an IP address", ErrInvalidInput, f.NasIPAddress))
+		}
+	}
+	if f.MacAddress != "" {
+		if _, err := net.ParseMAC(f.MacAddress); err != nil {
+			errs = append(errs, fmt.Errorf("%w: macAddress %q is not a MAC address", ErrInvalidInput, f.MacAddress))
+		}
+	}
+	if f.State != "" && !f.State.Valid() {
+		errs = append(errs, fmt.Errorf("%w: unknown state %q", ErrInvalidInput, f.State))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the values of the filter before they are sent to ISE
+func (f *UserGroupFilter) Validate() error {
+	if f == nil || f.Type == "" {
+		return nil
+	}
+
+	switch f.Type {
+	case GroupTypeActiveDirectory, GroupTypeIdentity, GroupTypeExternal, GroupTypeInterestingActiveDirectory:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown group type %q", ErrInvalidInput, f.Type)
+}
+
+func (s SessionState) Valid() bool {
+	switch s {
+	case SessionStateAuthenticating, SessionStateAuthenticated, SessionStatePostured,
+		SessionStateStarted, SessionStateDisconnected:
+		return true
+	}
+	return false
+}
+
+// validateFilter validates filters implementing Validate, other filters are passed as is
+func validateFilter(filter any) error {
+	if v, ok := filter.(filterValidator); ok {
+		return v.Validate()
+	}
+	return nil
+}
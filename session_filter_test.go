@@ -0,0 +1,54 @@
+package gopxgrid
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *SessionFilter
+		wantErr bool
+	}{
+		{name: "nil", filter: nil},
+		{name: "empty", filter: NewSessionFilter()},
+		{name: "valid", filter: NewSessionFilter().WithIPAddress("10.0.0.1").WithNasIPAddress("fe80::1").
+			WithMacAddress("00:11:22:33:44:55").WithState(SessionStateAuthenticated)},
+		{name: "bad ip", filter: NewSessionFilter().WithIPAddress("10.0.0"), wantErr: true},
+		{name: "bad nas ip", filter: NewSessionFilter().WithNasIPAddress("nas"), wantErr: true},
+		{name: "bad mac", filter: NewSessionFilter().WithMacAddress("00:11"), wantErr: true},
+		{name: "bad state", filter: NewSessionFilter().WithState("SLEEPING"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("Validate() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{name: "zero", t: time.Time{}, want: ""},
+		{name: "utc", t: time.Date(2024, 3, 1, 15, 15, 21, 0, time.UTC), want: "2024-03-01T15:15:21.000+00:00"},
+		{name: "offset", t: time.Date(2024, 3, 1, 15, 15, 21, 5e6, time.FixedZone("", -8*3600)), want: "2024-03-01T15:15:21.005-08:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatTimestamp(tt.t); got != tt.want {
+				t.Fatalf("FormatTimestamp() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
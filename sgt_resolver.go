@@ -0,0 +1,391 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrSGTNotFound = errors.New("no SGT found for the IP address")
+
+const (
+	// DefaultSGTCatalogueTTL is the time after which the security group catalogue is reloaded
+	DefaultSGTCatalogueTTL = 10 * time.Minute
+	// DefaultSGTBindingsTTL is the time after which the cached SXP bindings of a VPN are reloaded
+	DefaultSGTBindingsTTL = time.Minute
+	// DefaultSGTSessionsTTL is the time for which the session looked up for an IP address is reused
+	DefaultSGTSessionsTTL = 10 * time.Second
+	// DefaultSXPVPN is the VPN of the default SXP domain, used for bindings and
+	// lookups without a VPN
+	DefaultSXPVPN = "default"
+)
+
+type (
+	SGTSource string
+
+	// SGTResolution is the security group an IP address maps to
+	SGTResolution struct {
+		IP  netip.Addr
+		VPN string
+		// Tag is the SGT value, -1 if the catalogue has no group with Name
+		Tag int
+		// Name is the security group name, empty if the catalogue has no group with Tag
+		Name string
+		// Source is the source of truth of the resolution
+		Source SGTSource
+		// Timestamp is the time of the session, zero for SXP bindings
+		Timestamp time.Time
+		// Prefix is the matched prefix of the SXP binding
+		Prefix  netip.Prefix
+		Session *Session
+		Binding *TrustSecSXPBinding
+	}
+
+	sgtResolverOptions struct {
+		bindings     *SXPBindingTable
+		catalogueTTL time.Duration
+		bindingsTTL  time.Duration
+		sessionsTTL  time.Duration
+		skipSessions bool
+	}
+
+	// sgtSession is a cached session lookup, session is nil if the IP address had none
+	sgtSession struct {
+		session   *Session
+		fetchedAt time.Time
+	}
+
+	sgtVPNBindings struct {
+		table    *SXPBindingTable
+		loadedAt time.Time
+		// l serializes the loads of the VPN
+		l sync.Mutex
+	}
+
+	SGTResolverOption func(*sgtResolverOptions)
+
+	// SGTResolver resolves IP addresses to security groups.
+	//
+	// Precedence rules:
+	//  1. An active session of the IP address with a security group assigned wins,
+	//     it is the result of the authorization done by ISE for the endpoint.
+	//  2. Otherwise the SXP binding with the longest prefix containing the IP address
+	//     in the VPN is used, so host bindings win over subnet bindings.
+	//
+	// Names and tags are completed from the security group catalogue of TrustSecConfiguration.
+	SGTResolver struct {
+		c    *PxGridConsumer
+		opts sgtResolverOptions
+
+		byName      map[string]SecurityGroup
+		byTag       map[int]SecurityGroup
+		catalogueAt time.Time
+		l           sync.RWMutex
+
+		// vpns caches the bindings per VPN if no binding table is mirrored
+		vpns map[string]*sgtVPNBindings
+		vpnL sync.Mutex
+
+		// sessions caches the session lookups per IP address
+		sessions         map[netip.Addr]sgtSession
+		sessionsPrunedAt time.Time
+		sessionsL        sync.Mutex
+	}
+)
+
+const (
+	SGTSourceSession SGTSource = "session"
+	SGTSourceSXP     SGTSource = "sxp"
+)
+
+// WithSGTBindingTable makes the resolver use a mirrored binding table for SXP lookups
+// instead of caching the bindings of every resolved VPN
+func WithSGTBindingTable(table *SXPBindingTable) SGTResolverOption {
+	return func(o *sgtResolverOptions) {
+		o.bindings = table
+	}
+}
+
+// WithSGTCatalogueTTL sets the time after which the security group catalogue is reloaded
+func WithSGTCatalogueTTL(ttl time.Duration) SGTResolverOption {
+	return func(o *sgtResolverOptions) {
+		o.catalogueTTL = ttl
+	}
+}
+
+// WithSGTBindingsTTL sets the time after which the cached bindings of a VPN are
+// reloaded, it is not used with WithSGTBindingTable
+func WithSGTBindingsTTL(ttl time.Duration) SGTResolverOption {
+	return func(o *sgtResolverOptions) {
+		o.bindingsTTL = ttl
+	}
+}
+
+// WithSGTSessionsTTL sets the time for which the session looked up for an IP
+// address is reused, a non-positive ttl disables the cache
+func WithSGTSessionsTTL(ttl time.Duration) SGTResolverOption {
+	return func(o *sgtResolverOptions) {
+		o.sessionsTTL = ttl
+	}
+}
+
+// WithoutSGTSessions makes the resolver ignore sessions and use SXP bindings only
+func WithoutSGTSessions() SGTResolverOption {
+	return func(o *sgtResolverOptions) {
+		o.skipSessions = true
+	}
+}
+
+func NewSGTResolver(c *PxGridConsumer, opts ...SGTResolverOption) *SGTResolver {
+	o := sgtResolverOptions{
+		catalogueTTL: DefaultSGTCatalogueTTL,
+		bindingsTTL:  DefaultSGTBindingsTTL,
+		sessionsTTL:  DefaultSGTSessionsTTL,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &SGTResolver{
+		c:        c,
+		opts:     o,
+		byName:   make(map[string]SecurityGroup),
+		byTag:    make(map[int]SecurityGroup),
+		vpns:     make(map[string]*sgtVPNBindings),
+		sessions: make(map[netip.Addr]sgtSession),
+	}
+}
+
+// Refresh reloads the security group catalogue
+func (r *SGTResolver) Refresh(ctx context.Context) error {
+	byName := make(map[string]SecurityGroup)
+	byTag := make(map[int]SecurityGroup)
+	for rec, err := range r.c.TrustSecConfiguration().Rest().IterSecurityGroups(0).Do(ctx) {
+		if err != nil {
+			return err
+		}
+		if rec.Deleted {
+			continue
+		}
+		byName[rec.Record.Name] = rec.Record
+		byTag[rec.Record.Tag] = rec.Record
+	}
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.byName, r.byTag, r.catalogueAt = byName, byTag, time.Now()
+	return nil
+}
+
+// ApplySecurityGroup applies a security group topic message to the catalogue
+func (r *SGTResolver) ApplySecurityGroup(msg SecurityGroupTopicMessage) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if old, ok := r.byName[msg.SecurityGroup.Name]; ok {
+		delete(r.byTag, old.Tag)
+	}
+	if old, ok := r.byTag[msg.SecurityGroup.Tag]; ok {
+		delete(r.byName, old.Name)
+	}
+
+	if msg.OperationType == OperationTypeDelete {
+		delete(r.byName, msg.SecurityGroup.Name)
+		delete(r.byTag, msg.SecurityGroup.Tag)
+		return
+	}
+
+	r.byName[msg.SecurityGroup.Name] = msg.SecurityGroup
+	r.byTag[msg.SecurityGroup.Tag] = msg.SecurityGroup
+}
+
+func (r *SGTResolver) ensureCatalogue(ctx context.Context) error {
+	r.l.RLock()
+	fresh := !r.catalogueAt.IsZero() && (r.opts.catalogueTTL <= 0 || time.Since(r.catalogueAt) < r.opts.catalogueTTL)
+	r.l.RUnlock()
+
+	if fresh {
+		return nil
+	}
+	return r.Refresh(ctx)
+}
+
+func (r *SGTResolver) completeByName(res *SGTResolution, name string) {
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	res.Name = name
+	res.Tag = -1
+	if sg, ok := r.byName[name]; ok {
+		res.Tag = sg.Tag
+	}
+}
+
+func (r *SGTResolver) completeByTag(res *SGTResolution, tag int) {
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	res.Tag = tag
+	if sg, ok := r.byTag[tag]; ok {
+		res.Name = sg.Name
+	}
+}
+
+// Resolve returns the security group of the IP address in the VPN, DefaultSXPVPN if vpn is empty
+func (r *SGTResolver) Resolve(ctx context.Context, ip, vpn string) (SGTResolution, error) {
+	vpn = sxpVPN(vpn)
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return SGTResolution{}, fmt.Errorf("%w: %q is not an IP address", ErrInvalidInput, ip)
+	}
+	addr = addr.Unmap()
+
+	var errs []error
+	if err := r.ensureCatalogue(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to load security groups: %w", err))
+	}
+
+	res := SGTResolution{IP: addr, VPN: vpn}
+	if !r.opts.skipSessions {
+		found, err := r.resolveSession(ctx, &res)
+		if found {
+			return res, nil
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	found, err := r.resolveSXP(ctx, &res)
+	if found {
+		return res, nil
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return SGTResolution{}, errors.Join(append([]error{ErrSGTNotFound}, errs...)...)
+}
+
+func (r *SGTResolver) resolveSession(ctx context.Context, res *SGTResolution) (bool, error) {
+	s, err := r.session(ctx, res.IP)
+	if err != nil {
+		return false, err
+	}
+
+	if s == nil || s.CTSSecurityGroup == "" || s.State == SessionStateDisconnected {
+		return false, nil
+	}
+
+	r.completeByName(res, s.CTSSecurityGroup)
+	res.Source = SGTSourceSession
+	res.Timestamp = s.Timestamp.Time
+	res.Session = s
+	return true, nil
+}
+
+// session returns the session of the IP address, nil if there is none. The
+// lookups are cached for the sessions TTL, failed ones are not
+func (r *SGTResolver) session(ctx context.Context, ip netip.Addr) (*Session, error) {
+	ttl := r.opts.sessionsTTL
+	if ttl > 0 {
+		r.sessionsL.Lock()
+		cached, ok := r.sessions[ip]
+		r.sessionsL.Unlock()
+
+		if ok && time.Since(cached.fetchedAt) < ttl {
+			return cached.session, nil
+		}
+	}
+
+	sres, err := r.c.SessionDirectory().Rest().GetSessionByIPAddress(ip.String()).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if ttl <= 0 {
+		return sres.Result, nil
+	}
+
+	now := time.Now()
+	r.sessionsL.Lock()
+	defer r.sessionsL.Unlock()
+
+	// expired lookups are dropped once per TTL, so that the cache does not grow
+	// with every resolved address
+	if now.Sub(r.sessionsPrunedAt) >= ttl {
+		for addr, s := range r.sessions {
+			if now.Sub(s.fetchedAt) >= ttl {
+				delete(r.sessions, addr)
+			}
+		}
+		r.sessionsPrunedAt = now
+	}
+	r.sessions[ip] = sgtSession{session: sres.Result, fetchedAt: now}
+	return sres.Result, nil
+}
+
+func (r *SGTResolver) resolveSXP(ctx context.Context, res *SGTResolution) (bool, error) {
+	table := r.opts.bindings
+	if table == nil {
+		var err error
+		if table, err = r.vpnBindings(ctx, res.VPN); err != nil {
+			return false, err
+		}
+	}
+
+	b, ok := table.Lookup(res.VPN, res.IP)
+	if !ok {
+		return false, nil
+	}
+
+	tag, err := strconv.Atoi(b.Tag)
+	if err != nil {
+		return false, fmt.Errorf("invalid tag %q of binding %s", b.Tag, b.IPPrefix)
+	}
+
+	r.completeByTag(res, tag)
+	res.Source = SGTSourceSXP
+	res.Prefix, _ = ParseSXPPrefix(b.IPPrefix)
+	res.Binding = &b
+	return true, nil
+}
+
+// vpnBindings returns the cached bindings of the VPN, loading them if they are
+// missing or older than the bindings TTL
+func (r *SGTResolver) vpnBindings(ctx context.Context, vpn string) (*SXPBindingTable, error) {
+	r.vpnL.Lock()
+	v, ok := r.vpns[vpn]
+	if !ok {
+		v = &sgtVPNBindings{}
+		r.vpns[vpn] = v
+	}
+	r.vpnL.Unlock()
+
+	v.l.Lock()
+	defer v.l.Unlock()
+
+	if v.table != nil && (r.opts.bindingsTTL <= 0 || time.Since(v.loadedAt) < r.opts.bindingsTTL) {
+		return v.table, nil
+	}
+
+	var bindings []TrustSecSXPBinding
+	for b, err := range r.c.TrustSecSXP().Rest().StreamBindings(&SXPBindingFilter{VPN: vpn}).Do(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bindings: %w", err)
+		}
+		bindings = append(bindings, b)
+	}
+
+	table := NewSXPBindingTable()
+	if err := table.Load(bindings); err != nil {
+		return nil, err
+	}
+	v.table, v.loadedAt = table, time.Now()
+	return table, nil
+}
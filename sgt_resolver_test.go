@@ -0,0 +1,157 @@
+package gopxgrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSGTResolverBindingCache(t *testing.T) {
+	var loads atomic.Int32
+	vpns := make(chan string, 10)
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		TrustSecSXPServiceName + "/getBindings": func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Filter SXPBindingFilter `json:"filter"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			loads.Add(1)
+			vpns <- req.Filter.VPN
+			json.NewEncoder(w).Encode(map[string]any{"bindings": []TrustSecSXPBinding{
+				{Tag: "10", IPPrefix: "10.0.0.0/8", VPN: req.Filter.VPN},
+			}})
+		},
+	})
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		vpns      []string
+		wantLoads int32
+		wantVPNs  []string
+	}{
+		{name: "cached", ttl: time.Hour, vpns: []string{"guest", "guest", "guest"}, wantLoads: 1, wantVPNs: []string{"guest"}},
+		{name: "per vpn", ttl: time.Hour, vpns: []string{"guest", "corp", "guest"}, wantLoads: 2, wantVPNs: []string{"guest", "corp"}},
+		{name: "empty vpn is default", ttl: time.Hour, vpns: []string{"", DefaultSXPVPN}, wantLoads: 1, wantVPNs: []string{DefaultSXPVPN}},
+		{name: "expired", ttl: time.Nanosecond, vpns: []string{"guest", "guest"}, wantLoads: 2, wantVPNs: []string{"guest", "guest"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loads.Store(0)
+			r := NewSGTResolver(newISEConsumer(t, srv, nil), WithoutSGTSessions(), WithSGTBindingsTTL(tt.ttl))
+			for _, vpn := range tt.vpns {
+				res, err := r.Resolve(context.Background(), "10.1.2.3", vpn)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Tag != 10 || res.Source != SGTSourceSXP {
+					t.Fatalf("Resolve() = %+v, want tag 10 from SXP", res)
+				}
+			}
+			if n := loads.Load(); n != tt.wantLoads {
+				t.Fatalf("bindings loaded %d times, want %d", n, tt.wantLoads)
+			}
+			for _, want := range tt.wantVPNs {
+				if got := <-vpns; got != want {
+					t.Fatalf("bindings loaded for VPN %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSGTResolverBindingTable(t *testing.T) {
+	srv := newISEServer(t, nil)
+	table := NewSXPBindingTable()
+	table.Load([]TrustSecSXPBinding{
+		{Tag: "20", IPPrefix: "10.1.0.0/16", VPN: DefaultSXPVPN},
+		{Tag: "30", IPPrefix: "10.3.0.0/16"},
+	})
+
+	r := NewSGTResolver(newISEConsumer(t, srv, nil), WithoutSGTSessions(), WithSGTBindingTable(table))
+	res, err := r.Resolve(context.Background(), "10.1.2.3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tag != 20 || res.VPN != DefaultSXPVPN {
+		t.Fatalf("Resolve() = %+v, want tag 20 in the default VPN", res)
+	}
+
+	// the binding without a VPN is in the default one
+	for _, vpn := range []string{"", DefaultSXPVPN} {
+		res, err := r.Resolve(context.Background(), "10.3.0.1", vpn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Tag != 30 || res.VPN != DefaultSXPVPN {
+			t.Fatalf("Resolve(%q) = %+v, want tag 30 in the default VPN", vpn, res)
+		}
+	}
+
+	if _, err := r.Resolve(context.Background(), "10.2.0.1", ""); !errors.Is(err, ErrSGTNotFound) {
+		t.Fatalf("Resolve() = %v, want ErrSGTNotFound", err)
+	}
+	if _, err := r.Resolve(context.Background(), "host", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Resolve() = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestSGTResolverSessionCache(t *testing.T) {
+	var lookups atomic.Int32
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		SessionDirectoryServiceName + "/getSessionByIPAddress": func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				IPAddress string `json:"ipAddress"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			lookups.Add(1)
+			if req.IPAddress != "10.1.2.3" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewEncoder(w).Encode(Session{State: SessionStateStarted, IPAddresses: []string{req.IPAddress}, CTSSecurityGroup: "Employees"})
+		},
+	})
+	table := NewSXPBindingTable()
+
+	tests := []struct {
+		name        string
+		opts        []SGTResolverOption
+		ips         []string
+		wantLookups int32
+	}{
+		{name: "cached", ips: []string{"10.1.2.3", "10.1.2.3", "10.1.2.3"}, wantLookups: 1},
+		{name: "per address", ips: []string{"10.1.2.3", "10.9.9.9", "10.1.2.3", "10.9.9.9"}, wantLookups: 2},
+		{name: "expired", opts: []SGTResolverOption{WithSGTSessionsTTL(time.Nanosecond)}, ips: []string{"10.1.2.3", "10.1.2.3"}, wantLookups: 2},
+		{name: "disabled", opts: []SGTResolverOption{WithSGTSessionsTTL(0)}, ips: []string{"10.1.2.3", "10.1.2.3"}, wantLookups: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups.Store(0)
+			r := NewSGTResolver(newISEConsumer(t, srv, nil), append([]SGTResolverOption{WithSGTBindingTable(table)}, tt.opts...)...)
+			for _, ip := range tt.ips {
+				res, err := r.Resolve(context.Background(), ip, "")
+				if ip != "10.1.2.3" {
+					// the address without a session has no binding either
+					if !errors.Is(err, ErrSGTNotFound) {
+						t.Fatalf("Resolve(%s) = %v, want ErrSGTNotFound", ip, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Source != SGTSourceSession || res.Name != "Employees" {
+					t.Fatalf("Resolve(%s) = %+v, want the group of the session", ip, res)
+				}
+			}
+			if n := lookups.Load(); n != tt.wantLookups {
+				t.Fatalf("sessions looked up %d times, want %d", n, tt.wantLookups)
+			}
+		})
+	}
+}
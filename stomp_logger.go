@@ -15,19 +15,19 @@ func fromLogger(logger Logger) stomp.Logger {
 }
 
 func (l *stompLogger) Debugf(format string, value ...interface{}) {
-	l.Debug(fmt.Sprintf(format, value...))
+	l.Logger.Debug(fmt.Sprintf(format, value...))
 }
 func (l *stompLogger) Infof(format string, value ...interface{}) {
-	l.Info(fmt.Sprintf(format, value...))
+	l.Logger.Info(fmt.Sprintf(format, value...))
 }
 func (l *stompLogger) Warningf(format string, value ...interface{}) {
-	l.Warning(fmt.Sprintf(format, value...))
+	l.Logger.Warn(fmt.Sprintf(format, value...))
 }
 func (l *stompLogger) Errorf(format string, value ...interface{}) {
-	l.Error(fmt.Sprintf(format, value...))
+	l.Logger.Error(fmt.Sprintf(format, value...))
 }
 
-func (l *stompLogger) Debug(message string)   { l.Debug(message) }
-func (l *stompLogger) Info(message string)    { l.Info(message) }
-func (l *stompLogger) Warning(message string) { l.Warning(message) }
-func (l *stompLogger) Error(message string)   { l.Error(message) }
+func (l *stompLogger) Debug(message string)   { l.Logger.Debug(message) }
+func (l *stompLogger) Info(message string)    { l.Logger.Info(message) }
+func (l *stompLogger) Warning(message string) { l.Logger.Warn(message) }
+func (l *stompLogger) Error(message string)   { l.Logger.Error(message) }
@@ -0,0 +1,194 @@
+package gopxgrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+type (
+	// IterCallFinalizer performs a call lazily when the returned sequence is ranged over
+	IterCallFinalizer[T any] interface {
+		Do(ctx context.Context) iter.Seq2[T, error]
+		DoOnNode(ctx context.Context, node int) iter.Seq2[T, error]
+		DoOnNodeByName(ctx context.Context, nodeName string) iter.Seq2[T, error]
+		DoOnNodes(ctx context.Context, nodes ...int) iter.Seq2[T, error]
+	}
+
+	streamCall[T any] struct {
+		svc        *pxGridService
+		call       string
+		payload    any
+		field      string
+		idempotent bool
+
+		fatal error
+	}
+)
+
+func (c *streamCall[T]) Do(ctx context.Context) iter.Seq2[T, error] {
+	return c.seq(ctx)
+}
+
+func (c *streamCall[T]) DoOnNode(ctx context.Context, node int) iter.Seq2[T, error] {
+	return c.seq(ctx, IndexNodePicker(node))
+}
+
+func (c *streamCall[T]) DoOnNodeByName(ctx context.Context, nodeName string) iter.Seq2[T, error] {
+	if c.fatal != nil {
+		return c.seq(ctx)
+	}
+
+	idx, err := c.svc.FindNodeIndexByName(nodeName)
+	if err != nil {
+		return errorSeq[T](err)
+	}
+
+	return c.seq(ctx, IndexNodePicker(idx))
+}
+
+func (c *streamCall[T]) DoOnNodes(ctx context.Context, nodes ...int) iter.Seq2[T, error] {
+	return c.seq(ctx, IndexNodePicker(nodes...))
+}
+
+func (c *streamCall[T]) seq(ctx context.Context, pickNode ...ServiceNodePickerFactory) iter.Seq2[T, error] {
+	if c.fatal != nil {
+		return errorSeq[T](c.fatal)
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		// the response is not decoded, the caller must close Response.RawBody
+		res, err := c.svc.send(ctx, c.call, c.payload, RESTOptions{stream: true, idempotent: c.idempotent}, pickNode...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if res.RawBody == nil {
+			yield(zero, errors.New("no response body"))
+			return
+		}
+		defer res.RawBody.Close()
+
+		if res.StatusCode > 299 {
+			yield(zero, fmt.Errorf("unexpected status code: %d", res.StatusCode))
+			return
+		}
+		if res.StatusCode == 204 {
+			return
+		}
+
+		for item, err := range decodeJSONArrayField[T](res.RawBody, c.field) {
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *streamCall[T]) setIdempotent() {
+	c.idempotent = true
+}
+
+func newStreamCall[T any](svc *pxGridService, apiCall string, payload any, field string) IterCallFinalizer[T] {
+	return &streamCall[T]{
+		svc:     svc,
+		call:    apiCall,
+		payload: payload,
+		field:   field,
+	}
+}
+
+func newFailedStreamCall[T any](err error) IterCallFinalizer[T] {
+	return &streamCall[T]{
+		fatal: err,
+	}
+}
+
+func errorSeq[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
+
+// decodeJSONArrayField decodes elements of the array held by the field of
+// the top level JSON object one by one, other fields are skipped. A missing
+// field is an error, a null one yields nothing
+func decodeJSONArrayField[T any](r io.Reader, field string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		found := false
+		dec := json.NewDecoder(r)
+
+		tok, err := dec.Token()
+		if err == io.EOF {
+			yield(zero, fmt.Errorf("%w: empty body", ErrUnexpectedResponse))
+			return
+		}
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '{' {
+			yield(zero, fmt.Errorf("unexpected token %v, expected object", tok))
+			return
+		}
+
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			if key, _ := tok.(string); key != field {
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					yield(zero, err)
+					return
+				}
+				continue
+			}
+
+			found = true
+			tok, err = dec.Token()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if tok == nil {
+				continue
+			}
+			if d, ok := tok.(json.Delim); !ok || d != '[' {
+				yield(zero, fmt.Errorf("unexpected token %v in field %s, expected array", tok, field))
+				return
+			}
+
+			for dec.More() {
+				var item T
+				if err := dec.Decode(&item); err != nil {
+					yield(zero, err)
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if _, err := dec.Token(); err != nil {
+				yield(zero, err)
+				return
+			}
+		}
+
+		if !found {
+			yield(zero, fmt.Errorf("%w: field %s not found in response", ErrUnexpectedResponse, field))
+		}
+	}
+}
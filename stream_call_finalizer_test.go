@@ -0,0 +1,92 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeJSONArrayField(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []int
+		wantErr bool
+	}{
+		{name: "items", body: `{"items":[1,2,3]}`, want: []int{1, 2, 3}},
+		{name: "other fields skipped", body: `{"a":{"b":[4]},"items":[1],"c":"d"}`, want: []int{1}},
+		{name: "empty", body: `{"items":[]}`},
+		{name: "null", body: `{"items":null}`},
+		{name: "empty body", body: ``, wantErr: true},
+		{name: "missing field", body: `{"other":[1]}`, wantErr: true},
+		{name: "not an array", body: `{"items":{}}`, wantErr: true},
+		{name: "not an object", body: `[1]`, wantErr: true},
+		{name: "truncated", body: `{"items":[1,2`, want: []int{1, 2}, wantErr: true},
+		{name: "bad item", body: `{"items":[1,"x"]}`, want: []int{1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got []int
+				err error
+			)
+			for item, e := range decodeJSONArrayField[int](strings.NewReader(tt.body), "items") {
+				if e != nil {
+					err = e
+					break
+				}
+				got = append(got, item)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	stall := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[1,`))
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte(`2,`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-stall:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(stall)
+
+	// the body takes longer than the timeout but data arrives in between
+	c := newTestConsumer(t, NewPxGridConfig().SetTimeout(300*time.Millisecond))
+	svc := newTestService(c, "svc", srv.URL)
+
+	var (
+		got []int
+		err error
+	)
+	for item, e := range newStreamCall[int](svc, "call", map[string]any{}, "items").Do(context.Background()) {
+		if e != nil {
+			err = e
+			break
+		}
+		got = append(got, item)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("got %v before the stall, want [1 2]", got)
+	}
+}
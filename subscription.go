@@ -3,8 +3,11 @@ package gopxgrid
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/go-stomp/stomp/v3"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -13,6 +16,15 @@ type (
 
 		C             chan *Message[T]
 		PubSubService string
+
+		topic   string
+		journal *Journal
+		replay  *journalReplay
+	}
+
+	// journalReplay is the range of journal offsets delivered before the live messages
+	journalReplay struct {
+		from, end uint64
 	}
 
 	Message[T any] struct {
@@ -20,9 +32,36 @@ type (
 
 		Body           T
 		UnmarshalError error
+		// JournalError is set if the message could not be written to the
+		// journal, the message is delivered anyway
+		JournalError error
+		// Offset is the journal offset of the message, 0 without a journal or if
+		// the message could not be written to it
+		Offset uint64
+		// Replayed is set for messages read from the journal
+		Replayed bool
+
+		ctx context.Context
 	}
 )
 
+var (
+	ErrNoJournal          = errors.New("subscription has no journal")
+	ErrSubscriptionClosed = errors.New("subscription is closed")
+
+	errStopReplay = errors.New("replay stopped")
+)
+
+// Context returns a context carrying the span of the message receipt. The span
+// ends before the message is delivered, link the spans of further processing to
+// it with trace.LinkFromContext
+func (m *Message[T]) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
 func (s *Subscription[T]) Read() (T, error) {
 	msg, err := s.Subscription.Read()
 	if err != nil {
@@ -39,43 +78,127 @@ func (s *Subscription[T]) Read() (T, error) {
 	return result, nil
 }
 
-func translator[T any](in chan *stomp.Message) chan *Message[T] {
-	out := make(chan *Message[T])
+// translate delivers the replayed journal records and then the messages of in
+// to C, each message of in is written to the journal first
+func (s *Subscription[T]) translate(in chan *stomp.Message, observer Observer, tracer trace.Tracer, log Logger) {
+	defer close(s.C)
+
+	if s.replay != nil {
+		s.replayJournal(log)
+	}
 
-	go func() {
-		defer close(out)
+	for msg := range in {
+		_, span := tracer.Start(context.Background(), "pxgrid.message "+s.topic, trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attrTopic.String(s.topic)))
 
-		for msg := range in {
-			if msg.Err != nil {
-				out <- &Message[T]{
-					Message: msg,
+		m := &Message[T]{
+			Message: msg,
+			ctx:     messageContext(span),
+		}
+		err := msg.Err
+		if err == nil {
+			if s.journal != nil {
+				m.Offset, m.JournalError = s.journal.Append(s.topic, msg)
+				if m.JournalError != nil {
+					log.Error("Failed to write message to journal", "topic", s.topic, "error", m.JournalError)
 				}
-				continue
 			}
+			err = unmarshalMessage(m)
+			observer.MessageReceived(s.topic, err)
+		}
 
-			var body T
-			if err := json.Unmarshal(msg.Body, &body); err != nil {
-				out <- &Message[T]{
-					Message: msg,
-					Body:    body,
-				}
-			} else {
-				out <- &Message[T]{
-					Message:        msg,
-					UnmarshalError: err,
-				}
+		// the span covers the receipt only, the reader may take any time to process
+		endSpan(span, err)
+		s.C <- m
+	}
+}
+
+func unmarshalMessage[T any](m *Message[T]) error {
+	var body T
+	if err := json.Unmarshal(m.Message.Body, &body); err != nil {
+		m.UnmarshalError = err
+		return err
+	}
+	m.Body = body
+	return nil
+}
+
+// follow passes the bodies of the messages of sub to apply until ctx is done or
+// the subscription is closed. The errors of messages which failed to be read,
+// journaled or applied are passed to onError if it is not nil
+func follow[T any](ctx context.Context, sub *Subscription[T], apply func(T) error, onError func(error)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+
+			if msg.JournalError != nil && onError != nil {
+				onError(msg.JournalError)
+			}
+
+			err := msg.Err
+			if err == nil {
+				err = msg.UnmarshalError
+			}
+			if err == nil {
+				err = apply(msg.Body)
+			}
+			if err != nil && onError != nil {
+				onError(err)
 			}
 		}
-	}()
+	}
+}
+
+// replayJournal delivers the journaled messages of the topic in the replay
+// range. The range ends at the offset of the first live message, so that no
+// offset is delivered twice. A failed scan is delivered as a message with Err
+func (s *Subscription[T]) replayJournal(log Logger) {
+	err := s.journal.Scan(s.replay.from, func(rec *JournalRecord) error {
+		if rec.Offset >= s.replay.end {
+			return errStopReplay
+		}
+		if rec.Topic != s.topic {
+			return nil
+		}
 
-	return out
+		m := &Message[T]{
+			Message:  rec.Message,
+			Offset:   rec.Offset,
+			Replayed: true,
+		}
+		unmarshalMessage(m)
+		s.C <- m
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopReplay) {
+		log.Error("Failed to replay journal", "topic", s.topic, "error", err)
+		s.C <- &Message[T]{Message: &stomp.Message{Err: err}, Replayed: true}
+	}
 }
 
 type Subscriber[T any] interface {
 	WithServiceNodePicker(picker ServiceNodePickerFactory) Subscriber[T]
 	WithPubSubNodePicker(picker ServiceNodePickerFactory) Subscriber[T]
 	WithExplicitPubSub(pubsub PubSub) Subscriber[T]
+	// WithJournal writes every received message to the journal before it is
+	// delivered, the journal may be shared by subscriptions
+	WithJournal(journal *Journal) Subscriber[T]
+	// WithReplay delivers the journaled messages of the topic from the offset on
+	// to C before the live messages, it requires a journal. See
+	// Journal.CommittedOffset to resume after the last processed message
+	WithReplay(from uint64) Subscriber[T]
+	// WithReplaySince delivers the journaled messages received at or after t
+	// before the live messages, see WithReplay
+	WithReplaySince(t time.Time) Subscriber[T]
 	Subscribe(ctx context.Context) (*Subscription[T], error)
+	// Handle subscribes and calls handler for the received messages on a pool
+	// of workers until ctx is done or the subscription is closed
+	Handle(ctx context.Context, handler MessageHandler[T], opts HandleOptions[T]) error
 }
 
 type subscriber[T any] struct {
@@ -83,6 +206,10 @@ type subscriber[T any] struct {
 	topicProperty string
 	pubsub        PubSub
 	pubsubGetter  func() (string, error)
+	journal       *Journal
+	replay        bool
+	replayFrom    uint64
+	replaySince   time.Time
 
 	svcNodePicker    ServiceNodePickerFactory
 	pubSubNodePicker ServiceNodePickerFactory
@@ -101,6 +228,21 @@ func (s *subscriber[T]) WithExplicitPubSub(pubsub PubSub) Subscriber[T] {
 	return s
 }
 
+func (s *subscriber[T]) WithJournal(journal *Journal) Subscriber[T] {
+	s.journal = journal
+	return s
+}
+
+func (s *subscriber[T]) WithReplay(from uint64) Subscriber[T] {
+	s.replay, s.replayFrom, s.replaySince = true, from, time.Time{}
+	return s
+}
+
+func (s *subscriber[T]) WithReplaySince(t time.Time) Subscriber[T] {
+	s.replay, s.replayFrom, s.replaySince = true, 0, t
+	return s
+}
+
 func (s *subscriber[T]) WithServiceNodePicker(picker ServiceNodePickerFactory) Subscriber[T] {
 	s.svcNodePicker = picker
 	return s
@@ -148,7 +290,7 @@ func (s *subscriber[T]) populatePubSub(ctx context.Context) error {
 		return nil
 	}
 
-	s.svc.log.Debug("Populating PubSub in subscriber")
+	s.svc.log.DebugContext(ctx, "Populating PubSub in subscriber")
 	pubSubServiceName, err := s.getPubSubServiceName(ctx)
 	if err != nil {
 		return err
@@ -158,12 +300,32 @@ func (s *subscriber[T]) populatePubSub(ctx context.Context) error {
 	return nil
 }
 
+// replayRange returns the journal offsets to replay, nil without a replay. The
+// range ends at the next offset of the journal before anything is subscribed
+func (s *subscriber[T]) replayRange() (*journalReplay, error) {
+	if !s.replay {
+		return nil, nil
+	}
+	if s.journal == nil {
+		return nil, ErrNoJournal
+	}
+
+	from := s.replayFrom
+	if !s.replaySince.IsZero() {
+		var err error
+		if from, err = s.journal.OffsetAt(s.replaySince); err != nil {
+			return nil, err
+		}
+	}
+	return &journalReplay{from: from, end: s.journal.NextOffset()}, nil
+}
+
 func (s *subscriber[T]) Subscribe(ctx context.Context) (*Subscription[T], error) {
 	if s.svcNodePicker == nil {
 		s.svcNodePicker = OrderedNodePicker()
 	}
 
-	s.svc.log.Debug("Subscribing to topic", "topicProperty", s.topicProperty)
+	s.svc.log.DebugContext(ctx, "Subscribing to topic", "topicProperty", s.topicProperty)
 	if err := s.populatePubSub(ctx); err != nil {
 		return nil, err
 	}
@@ -173,7 +335,7 @@ func (s *subscriber[T]) Subscribe(ctx context.Context) (*Subscription[T], error)
 	}
 
 	for _, pNode := range s.pubsub.Nodes() {
-		s.svc.log.Debug("PubSub Node", "node", pNode.NodeName)
+		s.svc.log.DebugContext(ctx, "PubSub Node", "node", pNode.NodeName)
 		if pNode.Secret == "" {
 			err := s.pubsub.UpdateSecrets(ctx)
 			if err != nil {
@@ -187,17 +349,28 @@ func (s *subscriber[T]) Subscribe(ctx context.Context) (*Subscription[T], error)
 	if err != nil {
 		return nil, err
 	}
-	s.svc.log.Debug("Subscribing to topic", "topic", topic)
+
+	replay, err := s.replayRange()
+	if err != nil {
+		return nil, err
+	}
+	s.svc.log.DebugContext(ctx, "Subscribing to topic", "topic", topic)
 
 	sub, err := s.pubsub.Subscribe(ctx, s.pubSubNodePicker, topic)
 	if err != nil {
 		return nil, err
 	}
-	s.svc.log.Debug("STOMP Subscribed to topic", "topic", topic)
+	s.svc.log.DebugContext(ctx, "STOMP Subscribed to topic", "topic", topic)
 
-	return &Subscription[T]{
+	subscription := &Subscription[T]{
 		Subscription:  sub,
-		C:             translator[T](sub.C),
+		C:             make(chan *Message[T]),
 		PubSubService: s.pubsub.Name(),
-	}, nil
+		topic:         topic,
+		journal:       s.journal,
+		replay:        replay,
+	}
+	go subscription.translate(sub.C, observers(s.svc.ctrl.cfg.Observers), s.svc.ctrl.tracer, s.svc.log)
+
+	return subscription, nil
 }
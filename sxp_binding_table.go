@@ -0,0 +1,396 @@
+package gopxgrid
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type (
+	// SXPBindingChange describes a change applied to SXPBindingTable
+	SXPBindingChange struct {
+		OperationType OperationType
+		Binding       TrustSecSXPBinding
+		// Previous holds the replaced binding for updates and deletes
+		Previous *TrustSecSXPBinding
+	}
+
+	// SXPBindingTable mirrors the SXP binding table of ISE and answers
+	// longest prefix match lookups per VPN
+	SXPBindingTable struct {
+		vpns      map[string]*sxpVPNTable
+		byTag     map[string]map[sxpBindingKey]struct{}
+		listeners map[int]func(SXPBindingChange)
+		nextID    int
+
+		l sync.RWMutex
+		// updates serializes the updates with their notifications so that
+		// listeners get the changes in the order they were applied
+		updates sync.Mutex
+	}
+
+	sxpVPNTable struct {
+		v4 [33]map[netip.Prefix]TrustSecSXPBinding
+		v6 [129]map[netip.Prefix]TrustSecSXPBinding
+	}
+
+	sxpBindingKey struct {
+		vpn    string
+		prefix netip.Prefix
+	}
+)
+
+func NewSXPBindingTable() *SXPBindingTable {
+	return &SXPBindingTable{
+		vpns:      make(map[string]*sxpVPNTable),
+		byTag:     make(map[string]map[sxpBindingKey]struct{}),
+		listeners: make(map[int]func(SXPBindingChange)),
+	}
+}
+
+// sxpVPN returns the VPN, DefaultSXPVPN if it is empty
+func sxpVPN(vpn string) string {
+	if vpn == "" {
+		return DefaultSXPVPN
+	}
+	return vpn
+}
+
+// ParseSXPPrefix parses the IP prefix of a binding, a bare address is treated as a host prefix
+func ParseSXPPrefix(s string) (netip.Prefix, error) {
+	if p, err := netip.ParsePrefix(s); err == nil {
+		if p.Addr().Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		return p.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: invalid IP prefix %q", ErrInvalidInput, s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func (v *sxpVPNTable) bucket(p netip.Prefix, create bool) map[netip.Prefix]TrustSecSXPBinding {
+	var m *map[netip.Prefix]TrustSecSXPBinding
+	if p.Addr().Is4() {
+		m = &v.v4[p.Bits()]
+	} else {
+		m = &v.v6[p.Bits()]
+	}
+	if *m == nil && create {
+		*m = make(map[netip.Prefix]TrustSecSXPBinding)
+	}
+	return *m
+}
+
+func (v *sxpVPNTable) lookup(addr netip.Addr) (TrustSecSXPBinding, bool) {
+	addr = addr.Unmap()
+	buckets := v.v6[:]
+	if addr.Is4() {
+		buckets = v.v4[:]
+	}
+
+	for bits := len(buckets) - 1; bits >= 0; bits-- {
+		if len(buckets[bits]) == 0 {
+			continue
+		}
+		p, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if b, ok := buckets[bits][p]; ok {
+			return b, true
+		}
+	}
+
+	return TrustSecSXPBinding{}, false
+}
+
+func (v *sxpVPNTable) empty() bool {
+	for _, m := range v.v4 {
+		if len(m) > 0 {
+			return false
+		}
+	}
+	for _, m := range v.v6 {
+		if len(m) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *SXPBindingTable) get(key sxpBindingKey) (TrustSecSXPBinding, bool) {
+	v, ok := t.vpns[key.vpn]
+	if !ok {
+		return TrustSecSXPBinding{}, false
+	}
+	b, ok := v.bucket(key.prefix, false)[key.prefix]
+	return b, ok
+}
+
+func (t *SXPBindingTable) put(key sxpBindingKey, b TrustSecSXPBinding) *SXPBindingChange {
+	prev, existed := t.get(key)
+	if existed && prev == b {
+		return nil
+	}
+
+	v, ok := t.vpns[key.vpn]
+	if !ok {
+		v = &sxpVPNTable{}
+		t.vpns[key.vpn] = v
+	}
+	v.bucket(key.prefix, true)[key.prefix] = b
+
+	if existed {
+		t.untag(prev.Tag, key)
+	}
+	if t.byTag[b.Tag] == nil {
+		t.byTag[b.Tag] = make(map[sxpBindingKey]struct{})
+	}
+	t.byTag[b.Tag][key] = struct{}{}
+
+	if existed {
+		return &SXPBindingChange{OperationType: OperationTypeUpdate, Binding: b, Previous: &prev}
+	}
+	return &SXPBindingChange{OperationType: OperationTypeCreate, Binding: b}
+}
+
+func (t *SXPBindingTable) remove(key sxpBindingKey) *SXPBindingChange {
+	prev, existed := t.get(key)
+	if !existed {
+		return nil
+	}
+
+	v := t.vpns[key.vpn]
+	delete(v.bucket(key.prefix, false), key.prefix)
+	if v.empty() {
+		delete(t.vpns, key.vpn)
+	}
+	t.untag(prev.Tag, key)
+
+	return &SXPBindingChange{OperationType: OperationTypeDelete, Binding: prev, Previous: &prev}
+}
+
+func (t *SXPBindingTable) untag(tag string, key sxpBindingKey) {
+	delete(t.byTag[tag], key)
+	if len(t.byTag[tag]) == 0 {
+		delete(t.byTag, tag)
+	}
+}
+
+func (t *SXPBindingTable) notify(changes []SXPBindingChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	t.l.RLock()
+	listeners := make([]func(SXPBindingChange), 0, len(t.listeners))
+	ids := make([]int, 0, len(t.listeners))
+	for id := range t.listeners {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		listeners = append(listeners, t.listeners[id])
+	}
+	t.l.RUnlock()
+
+	for _, c := range changes {
+		for _, fn := range listeners {
+			fn(c)
+		}
+	}
+}
+
+// Load replaces the content of the table with the bindings.
+// Listeners are notified about the differences only.
+func (t *SXPBindingTable) Load(bindings []TrustSecSXPBinding) error {
+	keys := make([]sxpBindingKey, len(bindings))
+	for i, b := range bindings {
+		p, err := ParseSXPPrefix(b.IPPrefix)
+		if err != nil {
+			return err
+		}
+		keys[i] = sxpBindingKey{vpn: sxpVPN(b.VPN), prefix: p}
+	}
+
+	var changes []SXPBindingChange
+
+	t.updates.Lock()
+	defer t.updates.Unlock()
+
+	t.l.Lock()
+	seen := make(map[sxpBindingKey]struct{}, len(bindings))
+	for i, b := range bindings {
+		seen[keys[i]] = struct{}{}
+		if c := t.put(keys[i], b); c != nil {
+			changes = append(changes, *c)
+		}
+	}
+
+	var stale []sxpBindingKey
+	for vpn, v := range t.vpns {
+		for _, buckets := range [][]map[netip.Prefix]TrustSecSXPBinding{v.v4[:], v.v6[:]} {
+			for _, m := range buckets {
+				for p := range m {
+					key := sxpBindingKey{vpn: vpn, prefix: p}
+					if _, ok := seen[key]; !ok {
+						stale = append(stale, key)
+					}
+				}
+			}
+		}
+	}
+	for _, key := range stale {
+		if c := t.remove(key); c != nil {
+			changes = append(changes, *c)
+		}
+	}
+	t.l.Unlock()
+
+	t.notify(changes)
+	return nil
+}
+
+// Sync loads the full binding table from ISE
+func (t *SXPBindingTable) Sync(ctx context.Context, rest TrustSecSXPRest) error {
+	var bindings []TrustSecSXPBinding
+	for b, err := range rest.StreamBindings(nil).Do(ctx) {
+		if err != nil {
+			return err
+		}
+		bindings = append(bindings, b)
+	}
+
+	return t.Load(bindings)
+}
+
+// Apply applies a binding topic message to the table
+func (t *SXPBindingTable) Apply(msg TrustSecSXPBindingTopicMessage) error {
+	p, err := ParseSXPPrefix(msg.Binding.IPPrefix)
+	if err != nil {
+		return err
+	}
+	key := sxpBindingKey{vpn: sxpVPN(msg.Binding.VPN), prefix: p}
+
+	var change *SXPBindingChange
+	t.updates.Lock()
+	defer t.updates.Unlock()
+
+	t.l.Lock()
+	switch msg.OperationType {
+	case OperationTypeCreate, OperationTypeUpdate:
+		change = t.put(key, msg.Binding)
+	case OperationTypeDelete:
+		change = t.remove(key)
+	default:
+		t.l.Unlock()
+		return fmt.Errorf("%w: unknown operation %q", ErrInvalidInput, msg.OperationType)
+	}
+	t.l.Unlock()
+
+	if change != nil {
+		t.notify([]SXPBindingChange{*change})
+	}
+	return nil
+}
+
+// Follow applies messages of the binding topic subscription until the context
+// is done or the subscription is closed. Messages which failed to be read or
+// applied are passed to onError if it is not nil.
+func (t *SXPBindingTable) Follow(ctx context.Context, sub *Subscription[TrustSecSXPBindingTopicMessage], onError func(error)) error {
+	return follow(ctx, sub, t.Apply, onError)
+}
+
+// Lookup returns the binding of the longest prefix containing ip in the VPN.
+// Bindings and lookups without a VPN belong to DefaultSXPVPN
+func (t *SXPBindingTable) Lookup(vpn string, ip netip.Addr) (TrustSecSXPBinding, bool) {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	v, ok := t.vpns[sxpVPN(vpn)]
+	if !ok {
+		return TrustSecSXPBinding{}, false
+	}
+	return v.lookup(ip)
+}
+
+// LookupString is like Lookup but parses the IP address first
+func (t *SXPBindingTable) LookupString(vpn, ip string) (TrustSecSXPBinding, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return TrustSecSXPBinding{}, false
+	}
+	return t.Lookup(vpn, addr)
+}
+
+// ByTag returns all bindings with the tag
+func (t *SXPBindingTable) ByTag(tag string) []TrustSecSXPBinding {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	res := make([]TrustSecSXPBinding, 0, len(t.byTag[tag]))
+	for key := range t.byTag[tag] {
+		if b, ok := t.get(key); ok {
+			res = append(res, b)
+		}
+	}
+
+	slices.SortFunc(res, func(a, b TrustSecSXPBinding) int {
+		return cmp.Or(strings.Compare(a.VPN, b.VPN), strings.Compare(a.IPPrefix, b.IPPrefix))
+	})
+	return res
+}
+
+// VPNs returns the names of the VPNs known to the table
+func (t *SXPBindingTable) VPNs() []string {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	res := make([]string, 0, len(t.vpns))
+	for vpn := range t.vpns {
+		res = append(res, vpn)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Len returns the number of bindings in the table
+func (t *SXPBindingTable) Len() int {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	n := 0
+	for _, keys := range t.byTag {
+		n += len(keys)
+	}
+	return n
+}
+
+// OnChange registers a listener called for every change of the table.
+// Listeners are called synchronously after the table is updated, one change
+// at a time in the order the changes were applied. Listeners may read the
+// table but must not update it.
+// The returned function removes the listener.
+func (t *SXPBindingTable) OnChange(fn func(SXPBindingChange)) func() {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	id := t.nextID
+	t.nextID++
+	t.listeners[id] = fn
+
+	return func() {
+		t.l.Lock()
+		defer t.l.Unlock()
+		delete(t.listeners, id)
+	}
+}
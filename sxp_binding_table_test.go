@@ -0,0 +1,166 @@
+package gopxgrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/go-stomp/stomp/v3"
+)
+
+func TestSXPBindingTableLookup(t *testing.T) {
+	table := NewSXPBindingTable()
+	err := table.Load([]TrustSecSXPBinding{
+		{Tag: "10", IPPrefix: "10.0.0.0/8", VPN: "default"},
+		{Tag: "20", IPPrefix: "10.1.0.0/16", VPN: "default"},
+		{Tag: "30", IPPrefix: "10.1.2.3", VPN: "default"},
+		{Tag: "40", IPPrefix: "10.1.0.0/16", VPN: "guest"},
+		{Tag: "50", IPPrefix: "2001:db8::/32", VPN: "default"},
+		{Tag: "60", IPPrefix: "192.168.1.0/24"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		vpn, ip string
+		want    string
+	}{
+		{vpn: "default", ip: "10.2.0.1", want: "10"},
+		{vpn: "default", ip: "10.1.9.9", want: "20"},
+		{vpn: "default", ip: "10.1.2.3", want: "30"},
+		{vpn: "default", ip: "::ffff:10.1.2.3", want: "30"},
+		{vpn: "guest", ip: "10.1.2.3", want: "40"},
+		{vpn: "guest", ip: "10.2.0.1"},
+		{vpn: "default", ip: "2001:db8::1", want: "50"},
+		{vpn: "default", ip: "192.168.0.1"},
+		{vpn: "other", ip: "10.1.2.3"},
+		// bindings and lookups without a VPN are in the default one
+		{vpn: "", ip: "10.1.2.3", want: "30"},
+		{vpn: "default", ip: "192.168.1.1", want: "60"},
+		{vpn: "", ip: "192.168.1.1", want: "60"},
+		{vpn: "guest", ip: "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.vpn+"/"+tt.ip, func(t *testing.T) {
+			b, ok := table.LookupString(tt.vpn, tt.ip)
+			if ok != (tt.want != "") || b.Tag != tt.want {
+				t.Fatalf("Lookup() = %q, %v, want %q", b.Tag, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSXPBindingTableApply(t *testing.T) {
+	table := NewSXPBindingTable()
+	var ops []OperationType
+	table.OnChange(func(c SXPBindingChange) { ops = append(ops, c.OperationType) })
+
+	b := TrustSecSXPBinding{Tag: "10", IPPrefix: "10.0.0.0/24", VPN: "guest"}
+	msgs := []TrustSecSXPBindingTopicMessage{
+		{OperationType: OperationTypeCreate, Binding: b},
+		{OperationType: OperationTypeUpdate, Binding: b},
+		{OperationType: OperationTypeUpdate, Binding: TrustSecSXPBinding{Tag: "20", IPPrefix: b.IPPrefix, VPN: b.VPN}},
+		{OperationType: OperationTypeDelete, Binding: b},
+		{OperationType: OperationTypeDelete, Binding: b},
+	}
+	for _, m := range msgs {
+		if err := table.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []OperationType{OperationTypeCreate, OperationTypeUpdate, OperationTypeDelete}
+	if !slices.Equal(ops, want) {
+		t.Fatalf("changes = %v, want %v", ops, want)
+	}
+	if vpns := table.VPNs(); len(vpns) != 0 {
+		t.Fatalf("VPNs() = %v after deleting the last binding, want none", vpns)
+	}
+	if n := len(table.ByTag("20")); n != 0 {
+		t.Fatalf("ByTag() = %d bindings, want 0", n)
+	}
+
+	err := table.Apply(TrustSecSXPBindingTopicMessage{OperationType: "MOVE", Binding: b})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Apply() = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestSXPBindingTableChangeOrder(t *testing.T) {
+	table := NewSXPBindingTable()
+	last := make(map[string]string)
+	table.OnChange(func(c SXPBindingChange) {
+		if c.OperationType == OperationTypeDelete {
+			delete(last, c.Binding.IPPrefix)
+			return
+		}
+		last[c.Binding.IPPrefix] = c.Binding.Tag
+	})
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				b := TrustSecSXPBinding{Tag: fmt.Sprint(w, i), IPPrefix: fmt.Sprintf("10.0.0.%d", i%4), VPN: "default"}
+				op := OperationTypeUpdate
+				if i%5 == 0 {
+					op = OperationTypeDelete
+				}
+				if err := table.Apply(TrustSecSXPBindingTopicMessage{OperationType: op, Binding: b}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the listener replayed the changes in order, so it ends with the table state
+	for i := range 4 {
+		prefix := fmt.Sprintf("10.0.0.%d", i)
+		b, ok := table.Lookup("default", netip.MustParseAddr(prefix))
+		if got, seen := last[prefix]; seen != ok || got != b.Tag {
+			t.Fatalf("listener has %q, %v for %s, table has %q, %v", got, seen, prefix, b.Tag, ok)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	sub := &Subscription[int]{C: make(chan *Message[int], 4)}
+	sub.C <- &Message[int]{Message: &stomp.Message{}, Body: 1}
+	sub.C <- &Message[int]{Message: &stomp.Message{}, UnmarshalError: errors.New("bad json")}
+	sub.C <- &Message[int]{Message: &stomp.Message{}, Body: 2}
+	sub.C <- &Message[int]{Message: &stomp.Message{}, Body: 3}
+	close(sub.C)
+
+	var (
+		applied []int
+		errs    []error
+	)
+	err := follow(context.Background(), sub, func(v int) error {
+		if v == 2 {
+			return errors.New("apply failed")
+		}
+		applied = append(applied, v)
+		return nil
+	}, func(err error) { errs = append(errs, err) })
+	if err != nil {
+		t.Fatalf("follow() = %v, want nil once closed", err)
+	}
+	if !slices.Equal(applied, []int{1, 3}) || len(errs) != 2 {
+		t.Fatalf("applied %v with errors %v", applied, errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	open := &Subscription[int]{C: make(chan *Message[int])}
+	if err := follow(ctx, open, func(int) error { return nil }, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("follow() = %v, want context.Canceled", err)
+	}
+}
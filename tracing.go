@@ -0,0 +1,55 @@
+package gopxgrid
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/vkumov/go-pxgrid"
+
+const (
+	attrService    = attribute.Key("pxgrid.service")
+	attrCall       = attribute.Key("pxgrid.call")
+	attrNode       = attribute.Key("pxgrid.node")
+	attrPeerNode   = attribute.Key("pxgrid.peer_node")
+	attrTopic      = attribute.Key("pxgrid.topic")
+	attrWSURL      = attribute.Key("pxgrid.ws_url")
+	attrStatusCode = attribute.Key("http.response.status_code")
+)
+
+// newTracer returns the tracer of the provider or a noop tracer if the provider is not set
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+func setResponseAttributes(span trace.Span, res *Response) {
+	if res == nil {
+		return
+	}
+	span.SetAttributes(attrStatusCode.Int(res.StatusCode))
+	if res.StatusCode > 299 {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// messageContext returns a context carrying the span of a received message
+func messageContext(span trace.Span) context.Context {
+	return trace.ContextWithSpan(context.Background(), span)
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
 	"net/url"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -18,7 +24,10 @@ type transport struct {
 	tls      *TLSConfig
 	dns      *DNSConfig
 	resolver *net.Resolver
+	cache    *dnsCache
+	proxy    func(addr string) (*url.URL, error)
 	auth     AuthConfig
+	timeout  time.Duration
 
 	tlsMutex sync.RWMutex
 }
@@ -47,16 +56,20 @@ func tlsCfg(cfg *TLSConfig) *TLSConfig {
 
 func newTransport(cfg *PxGridConfig) *transport {
 	s := &transport{
-		client: resty.New(),
-		tls:    tlsCfg(&cfg.TLS),
-		dns:    dnsCfg(&cfg.DNS),
-		auth:   cfg.Auth,
+		client:  resty.New(),
+		tls:     tlsCfg(&cfg.TLS),
+		dns:     dnsCfg(&cfg.DNS),
+		auth:    cfg.Auth,
+		timeout: cfg.Timeout,
+		proxy:   cfg.Proxy.proxyFunc(),
 	}
 
+	var server *net.UDPAddr
 	if s.dns.Server != "" {
 		host := s.dns.Server
 		ip, err := ParseDNSHost(host)
 		if err == nil {
+			server = ip
 			s.resolver = &net.Resolver{
 				PreferGo: true,
 				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
@@ -66,8 +79,17 @@ func newTransport(cfg *PxGridConfig) *transport {
 			}
 		}
 	}
+	if s.dns.Cache {
+		s.cache = newDNSCache(server, s.resolver, s.dns)
+	}
 
-	s.client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: s.tls.InsecureTLS})
+	if t, err := s.client.Transport(); err == nil {
+		// proxies are applied by DialContext
+		t.Proxy = nil
+		t.DialContext = s.DialContext
+		s.client.SetTransport(&hostTransports{s: s, base: t, transports: make(map[tlsKey]*http.Transport)})
+	}
+	s.applyRetry(cfg.Retry)
 
 	s.client.SetHeaders(map[string]string{
 		"Content-Type": "application/json",
@@ -77,65 +99,75 @@ func newTransport(cfg *PxGridConfig) *transport {
 	return s
 }
 
-func (s *transport) getOneIPAddr(addrs []net.IPAddr, err error) (net.IPAddr, error) {
-	if err != nil {
-		return net.IPAddr{}, err
+func (s *transport) applyRetry(cfg RetryConfig) {
+	if cfg.Count <= 0 {
+		return
 	}
 
-	switch s.dns.FamilyStrategy {
-	case IPv4:
-		for _, i := range addrs {
-			if i.IP.To4() != nil {
-				return i, nil
-			}
-		}
-		return net.IPAddr{}, &net.AddrError{Err: "no IPv4 address found", Addr: ""}
-	case IPv6:
-		for _, i := range addrs {
-			if i.IP.To4() == nil {
-				return i, nil
-			}
-		}
-		return net.IPAddr{}, &net.AddrError{Err: "no IPv6 address found", Addr: ""}
-	case IPv46:
-		var firstIPv6 net.IPAddr
-		for _, i := range addrs {
-			if i.IP.To4() != nil {
-				return i, nil
-			}
-			if firstIPv6.IP == nil {
-				firstIPv6 = i
-			}
-		}
-		if firstIPv6.IP != nil {
-			return firstIPv6, nil
+	s.client.SetRetryCount(cfg.Count)
+	if cfg.WaitTime > 0 {
+		s.client.SetRetryWaitTime(cfg.WaitTime)
+	}
+	if cfg.MaxWaitTime > 0 {
+		s.client.SetRetryMaxWaitTime(cfg.MaxWaitTime)
+	}
+	s.client.AddRetryCondition(func(r *resty.Response, err error) bool {
+		// the condition replaces the default retry on errors, so requests
+		// which did not opt in are never retried
+		if r == nil || r.Request == nil || r.Request.Context().Value(idempotentKey{}) == nil {
+			return false
 		}
-		return net.IPAddr{}, &net.AddrError{Err: "no IPv4 or IPv6 address found", Addr: ""}
-	case IPv64:
-		var firstIPv4 net.IPAddr
-		for _, i := range addrs {
-			if i.IP.To4() == nil {
-				return i, nil
-			}
-			if firstIPv4.IP == nil {
-				firstIPv4 = i
-			}
+		if err != nil {
+			return true
 		}
-		if firstIPv4.IP != nil {
-			return firstIPv4, nil
+		switch r.StatusCode() {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
 		}
-		return net.IPAddr{}, &net.AddrError{Err: "no IPv4 or IPv6 address found", Addr: ""}
+		return false
+	})
+}
+
+func (s *transport) ResolveHost(ctx context.Context, host string) (net.IPAddr, error) {
+	addrs, err := s.ResolveHostAll(ctx, host)
+	if err != nil {
+		return net.IPAddr{}, err
 	}
+	return addrs[0], nil
+}
 
-	return net.IPAddr{}, &net.AddrError{Err: "unknown strategy", Addr: ""}
+// ResolveHostAll returns the addresses of the host in the order of the
+// INETFamilyStrategy, the families are interleaved as in RFC 8305
+func (s *transport) ResolveHostAll(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var (
+		addrs []net.IPAddr
+		err   error
+	)
+	if s.cache != nil {
+		addrs, err = s.cache.LookupIPAddr(ctx, host)
+	} else {
+		addrs, err = s.lookupResolver().LookupIPAddr(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return orderIPAddrs(addrs, s.dns.FamilyStrategy)
 }
 
-func (s *transport) ResolveHost(ctx context.Context, host string) (net.IPAddr, error) {
-	if s.resolver == nil {
-		return s.getOneIPAddr(net.DefaultResolver.LookupIPAddr(ctx, host))
+// LookupSRV returns the SRV records of the name ordered by priority and weight
+func (s *transport) LookupSRV(ctx context.Context, name string) ([]*net.SRV, error) {
+	if s.cache != nil {
+		return s.cache.LookupSRV(ctx, name)
 	}
+	_, srvs, err := s.lookupResolver().LookupSRV(ctx, "", "", name)
+	return srvs, err
+}
 
-	return s.getOneIPAddr(s.resolver.LookupIPAddr(ctx, host))
+func (s *transport) lookupResolver() *net.Resolver {
+	if s.resolver == nil {
+		return net.DefaultResolver
+	}
+	return s.resolver
 }
 
 func (s *transport) UpdateClientCertificate(cert *tls.Certificate) {
@@ -145,18 +177,57 @@ func (s *transport) UpdateClientCertificate(cert *tls.Certificate) {
 	s.tls.ClientCertificate = cert
 }
 
+// DialContext connects to the host through the proxy if one applies to it,
+// the connection is established by dialDirect otherwise
 func (s *transport) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if s.proxy != nil {
+		proxyURL, err := s.proxy(addr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			return dialProxy(ctx, proxyURL, s.dialDirect, network, addr)
+		}
+	}
+	return s.dialDirect(ctx, network, addr)
+}
+
+// dialDirect connects to the addresses of the host one after another with
+// the happy eyeballs staggering, see dialParallel
+func (s *transport) dialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
 	dialer := &net.Dialer{
-		Resolver: s.resolver,
+		Timeout:   dialAttemptTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+
+	addrs, err := s.ResolveHostAll(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve host: %w", err)
 	}
-	return dialer.DialContext(ctx, network, addr)
+	return dialParallel(ctx, dialer, network, addrs, port)
 }
 
 func (s *transport) ClientTLSConfig() *tls.Config {
+	return s.clientTLSConfig("")
+}
+
+// clientTLSConfig checks the pins of the host, the server name of the connection is used if empty
+func (s *transport) clientTLSConfig(host string) *tls.Config {
 	s.tlsMutex.RLock()
 	defer s.tlsMutex.RUnlock()
 
-	tls := &tls.Config{InsecureSkipVerify: s.tls.InsecureTLS}
+	tls := &tls.Config{
+		InsecureSkipVerify: s.tls.InsecureTLS,
+		VerifyConnection:   s.tls.verifyPins(host),
+	}
 	if s.tls.ClientCertificate != nil {
 		tls.Certificates = append(tls.Certificates, *s.tls.ClientCertificate)
 	}
@@ -167,6 +238,78 @@ func (s *transport) ClientTLSConfig() *tls.Config {
 	return tls
 }
 
+// tlsKey selects the transport of a request, roots replace the CA of tls if set
+type tlsKey struct {
+	host  string
+	roots *x509.CertPool
+	tls   *TLSConfig
+}
+
+// tlsOverrideKey carries the tlsKey of requests with their own TLS settings
+type tlsOverrideKey struct{}
+
+// hostTransports is the round tripper of the REST client. The client is shared
+// by the requests, so instead of changing the TLS config per request it keeps a
+// transport per host and TLS settings, the config of a transport never changes
+type hostTransports struct {
+	s          *transport
+	base       *http.Transport
+	transports map[tlsKey]*http.Transport
+	l          sync.Mutex
+}
+
+func (h *hostTransports) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := tlsKey{tls: h.s.tls}
+	if o, ok := req.Context().Value(tlsOverrideKey{}).(tlsKey); ok {
+		key = o
+	}
+	key.host = req.URL.Hostname()
+
+	h.l.Lock()
+	t, ok := h.transports[key]
+	if !ok {
+		t = h.base.Clone()
+		t.TLSClientConfig = h.s.hostTLSConfig(key)
+		h.transports[key] = t
+	}
+	h.l.Unlock()
+
+	return t.RoundTrip(req)
+}
+
+func (h *hostTransports) CloseIdleConnections() {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	for _, t := range h.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+// hostTLSConfig returns the TLS config of the transport of the key, the client
+// certificate is read on every handshake as it may be updated
+func (s *transport) hostTLSConfig(key tlsKey) *tls.Config {
+	cfg := &tls.Config{
+		ServerName:         key.host,
+		InsecureSkipVerify: key.tls.InsecureTLS,
+		VerifyConnection:   key.tls.verifyPins(key.host),
+		RootCAs:            key.tls.CA,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			s.tlsMutex.RLock()
+			defer s.tlsMutex.RUnlock()
+
+			if key.tls.ClientCertificate == nil {
+				return &tls.Certificate{}, nil
+			}
+			return key.tls.ClientCertificate, nil
+		},
+	}
+	if key.roots != nil {
+		cfg.RootCAs = key.roots
+	}
+	return cfg
+}
+
 func (s *transport) NewRequest(ctx context.Context) *Request {
 	clonedAuth := s.auth
 	return &Request{
@@ -179,6 +322,9 @@ func (s *transport) NewRequest(ctx context.Context) *Request {
 	}
 }
 
+// idempotentKey marks the context of requests which may be retried
+type idempotentKey struct{}
+
 type (
 	Request struct {
 		s       *transport
@@ -188,30 +334,26 @@ type (
 		tls     *TLSConfig
 		client  *resty.Client
 		result  interface{}
+		header  http.Header
+		stream  bool
+		// idempotent requests may be retried, see RetryConfig
+		idempotent bool
 	}
 
 	Response struct {
 		StatusCode int
 		Body       string
 		Result     interface{}
+		Header     http.Header
+		// Elapsed is the time from sending the request until the response was received
+		Elapsed time.Duration
+		// RemoteAddr is the address of the node which served the request
+		RemoteAddr string
+		// RawBody is the undecoded response body of a streamed request, the caller must close it
+		RawBody io.ReadCloser
 	}
 )
 
-func (r *Request) getTLSClientConfig(serverName string) *tls.Config {
-	cfg := &tls.Config{
-		InsecureSkipVerify: r.tls.InsecureTLS,
-	}
-	if r.tls.ClientCertificate != nil {
-		cfg.Certificates = []tls.Certificate{*r.tls.ClientCertificate}
-	}
-
-	cfg.ServerName = serverName
-	if r.rootCAs != nil {
-		cfg.RootCAs = r.rootCAs
-	}
-	return cfg
-}
-
 func (r *Request) getAuth() (string, string) {
 	if r.auth == nil {
 		return "", ""
@@ -244,18 +386,42 @@ func (r *Request) SetUsername(username string) *Request {
 	return r
 }
 
-// SetRootCAs sets the root CAs for the request.
+// SetRootCAs sets the root CAs for the request, TLSConfig.CA or the system
+// roots are used if not set. Requests with the same pool share connections.
 func (r *Request) SetRootCAs(rootCAs *x509.CertPool) *Request {
 	r.rootCAs = rootCAs
 	return r
 }
 
-// SetTLSConfig sets the TLS configuration for the request.
+// SetTLSConfig sets the TLS configuration for the request. Requests with the
+// same configuration share connections.
 func (r *Request) SetTLSConfig(tls *TLSConfig) *Request {
 	r.tls = tls
 	return r
 }
 
+// AddHeader adds a header value to the request.
+func (r *Request) AddHeader(key, value string) *Request {
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Add(key, value)
+	return r
+}
+
+// SetStream makes the request keep the response body undecoded in Response.RawBody.
+func (r *Request) SetStream(stream bool) *Request {
+	r.stream = stream
+	return r
+}
+
+// SetIdempotent allows retrying the request as configured by RetryConfig.
+// Only requests which can be repeated without side effects should be retried
+func (r *Request) SetIdempotent(idempotent bool) *Request {
+	r.idempotent = idempotent
+	return r
+}
+
 func (r *Request) SetResult(result interface{}) *Request {
 	r.result = result
 	return r
@@ -263,18 +429,58 @@ func (r *Request) SetResult(result interface{}) *Request {
 
 // Post sends a POST request to the specified URL with the given payload.
 func (r *Request) Post(u string, payload interface{}) (*Response, error) {
+	return r.Do(http.MethodPost, u, payload)
+}
+
+// Get sends a GET request to the specified URL.
+func (r *Request) Get(u string) (*Response, error) {
+	return r.Do(http.MethodGet, u, nil)
+}
+
+// Put sends a PUT request to the specified URL with the given payload.
+func (r *Request) Put(u string, payload interface{}) (*Response, error) {
+	return r.Do(http.MethodPut, u, payload)
+}
+
+// Delete sends a DELETE request to the specified URL with the given payload.
+func (r *Request) Delete(u string, payload interface{}) (*Response, error) {
+	return r.Do(http.MethodDelete, u, payload)
+}
+
+// Do sends a request with the given method to the specified URL.
+// The payload is omitted from the request if it is nil.
+func (r *Request) Do(method, u string, payload interface{}) (*Response, error) {
 	o, err := url.Parse(u)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	hostname := o.Hostname()
-	ip, err := r.s.ResolveHost(r.ctx, hostname)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve host: %w", err)
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// the timeout does not use a deadline as a streamed body is read after Do
+	// returns, the context of a stream is released when the body is closed
+	if r.idempotent {
+		ctx = context.WithValue(ctx, idempotentKey{}, true)
 	}
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	cancel := func() { cancelCause(context.Canceled) }
+	if r.s.timeout > 0 {
+		timer := time.AfterFunc(r.s.timeout, func() { cancelCause(context.DeadlineExceeded) })
+		defer timer.Stop()
+	}
+	keepCtx := false
+	defer func() {
+		if !keepCtx {
+			cancel()
+		}
+	}()
 
-	port := 8910
+	// the host name stays in the URL for the Host header and SNI, the
+	// transport dials the resolved addresses
+	hostname := o.Hostname()
+	port := DefaultControlPort
 	if o.Port() != "" {
 		port, err = strconv.Atoi(o.Port())
 		if err != nil {
@@ -282,31 +488,102 @@ func (r *Request) Post(u string, payload interface{}) (*Response, error) {
 		}
 	}
 
-	r.client.SetTLSClientConfig(r.getTLSClientConfig(hostname))
-	req := r.client.R()
+	// the transport is picked by the host and the TLS settings, see hostTransports
+	if r.rootCAs != nil || r.tls != r.s.tls {
+		ctx = context.WithValue(ctx, tlsOverrideKey{}, tlsKey{roots: r.rootCAs, tls: r.tls})
+	}
+	// the remote address is taken from the connection the request got, the
+	// resty trace is not used as it is written by dials of other requests
+	var remoteAddr net.Addr
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { remoteAddr = info.Conn.RemoteAddr() },
+	})
+	req := r.client.R().SetContext(ctx)
 
 	if r.auth != nil {
 		req.SetBasicAuth(r.getAuth())
 	}
-	if r.result != nil {
+	if r.stream {
+		req.SetDoNotParseResponse(true)
+	} else if r.result != nil {
 		req.SetResult(r.result)
 	}
+	for k, v := range r.header {
+		for _, vv := range v {
+			req.Header.Add(k, vv)
+		}
+	}
+
+	if payload != nil {
+		req.SetBody(payload)
+	}
 
-	resp, err := req.
-		SetHeader("Host", hostname).
-		SetBody(payload).
-		Post(fmt.Sprintf("https://%s:%d%s", ip.String(), port, o.Path))
+	target := url.URL{
+		Scheme:   "https",
+		Host:     net.JoinHostPort(hostname, strconv.Itoa(port)),
+		Path:     o.Path,
+		RawQuery: o.RawQuery,
+	}
+	resp, err := req.Execute(method, target.String())
 	if err != nil {
+		if errors.Is(err, context.Canceled) && errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+			err = context.DeadlineExceeded
+		}
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	done := Response{
-		Body:       resp.String(),
 		StatusCode: resp.StatusCode(),
+		Header:     resp.Header(),
+		Elapsed:    resp.Time(),
+	}
+	if remoteAddr != nil {
+		done.RemoteAddr = remoteAddr.String()
+	}
+	if r.stream {
+		keepCtx = true
+		body := &cancelOnClose{ReadCloser: resp.RawBody(), ctx: ctx, cancel: cancel, timeout: r.s.timeout}
+		if r.s.timeout > 0 {
+			// the body is limited by the time between reads, not as a whole
+			body.idle = time.AfterFunc(r.s.timeout, func() { cancelCause(context.DeadlineExceeded) })
+		}
+		done.RawBody = body
+		return &done, nil
 	}
+
+	done.Body = resp.String()
 	if r.result != nil {
 		done.Result = resp.Result()
 	}
 
 	return &done, nil
 }
+
+// cancelOnClose releases the request context once a streamed body is closed,
+// the body fails with context.DeadlineExceeded if no data arrived for timeout
+type cancelOnClose struct {
+	io.ReadCloser
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	idle    *time.Timer
+}
+
+func (c *cancelOnClose) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if c.idle != nil && n > 0 {
+		c.idle.Reset(c.timeout)
+	}
+	if err != nil && err != io.EOF && errors.Is(context.Cause(c.ctx), context.DeadlineExceeded) {
+		err = fmt.Errorf("stream idle for %s: %w", c.timeout, context.DeadlineExceeded)
+	}
+	return n, err
+}
+
+func (c *cancelOnClose) Close() error {
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,183 @@
+package gopxgrid
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordedRequest is what the echo server received
+type recordedRequest struct {
+	method, path, body string
+}
+
+// newEchoServer returns a TLS server recording the last request, the responses
+// carry the X-Method header after a short delay
+func newEchoServer(t *testing.T) (*httptest.Server, *atomic.Pointer[recordedRequest]) {
+	t.Helper()
+
+	var last atomic.Pointer[recordedRequest]
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last.Store(&recordedRequest{method: r.Method, path: r.URL.Path, body: string(body)})
+		time.Sleep(5 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Method", r.Method)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &last
+}
+
+func TestRequestMethods(t *testing.T) {
+	srv, last := newEchoServer(t)
+	c := newTestConsumer(t, nil)
+	u, _ := url.Parse(srv.URL)
+
+	payload := map[string]any{"name": "policy"}
+	tests := []struct {
+		name     string
+		do       func(r *Request, u string) (*Response, error)
+		method   string
+		wantBody string
+	}{
+		{name: "put", do: func(r *Request, u string) (*Response, error) { return r.Put(u, payload) }, method: http.MethodPut, wantBody: `{"name":"policy"}`},
+		{name: "delete", do: func(r *Request, u string) (*Response, error) { return r.Delete(u, payload) }, method: http.MethodDelete, wantBody: `{"name":"policy"}`},
+		{name: "delete without body", do: func(r *Request, u string) (*Response, error) { return r.Delete(u, nil) }, method: http.MethodDelete},
+		{name: "custom", do: func(r *Request, u string) (*Response, error) { return r.Do("PATCH", u, payload) }, method: "PATCH", wantBody: `{"name":"policy"}`},
+		{name: "get", do: func(r *Request, u string) (*Response, error) { return r.Get(u) }, method: http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.do(c.svc.NewRequest(context.Background()), srv.URL+"/call")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := last.Load()
+			if got.method != tt.method || got.path != "/call" || got.body != tt.wantBody {
+				t.Fatalf("request = %+v, want %s with body %q", got, tt.method, tt.wantBody)
+			}
+			if res.StatusCode != http.StatusOK || res.Body != `{"ok":true}` {
+				t.Fatalf("response = %d %q", res.StatusCode, res.Body)
+			}
+			if res.Header.Get("X-Method") != tt.method {
+				t.Fatalf("header = %v, want the response headers", res.Header)
+			}
+			if res.Elapsed < 5*time.Millisecond {
+				t.Fatalf("elapsed = %v, want the time of the request", res.Elapsed)
+			}
+			if res.RemoteAddr != u.Host {
+				t.Fatalf("remote address = %q, want %q", res.RemoteAddr, u.Host)
+			}
+		})
+	}
+}
+
+func TestAnyRESTWithMethod(t *testing.T) {
+	var got atomic.Pointer[recordedRequest]
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		"svc/policy": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got.Store(&recordedRequest{method: r.Method, path: r.URL.Path, body: string(body)})
+			w.Header().Set("X-Method", r.Method)
+			w.Write([]byte(`{"name":"policy"}`))
+		},
+	})
+	c := newISEConsumer(t, srv, nil)
+	u, _ := url.Parse(srv.URL)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "PATCH"} {
+		t.Run(method, func(t *testing.T) {
+			res, err := c.Service("svc").AnyRESTWithMethod(method, "policy", map[string]any{"name": "policy"}).Do(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r := got.Load(); r.method != method || r.body != `{"name":"policy"}` {
+				t.Fatalf("request = %+v, want %s with the payload", r, method)
+			}
+			if res.StatusCode != http.StatusOK || res.Body != `{"name":"policy"}` {
+				t.Fatalf("response = %d %q", res.StatusCode, res.Body)
+			}
+			if res.Header.Get("X-Method") != method || res.Elapsed <= 0 || res.RemoteAddr != u.Host {
+				t.Fatalf("response header %v, elapsed %v, remote address %q", res.Header, res.Elapsed, res.RemoteAddr)
+			}
+		})
+	}
+}
+
+func TestRetryOnlyIdempotentCalls(t *testing.T) {
+	var attempts atomic.Int32
+	unavailable := func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	srv := newISEServer(t, map[string]http.HandlerFunc{
+		SessionDirectoryServiceName + "/getSessions":            unavailable,
+		ANCConfigServiceName + "/applyEndpointByMacAddress":     unavailable,
+		ANCConfigServiceName + "/deletePolicyByName":            unavailable,
+		TrustSecConfigurationServiceName + "/getSecurityGroups": unavailable,
+		"svc/getThings": unavailable,
+	})
+	c := newISEConsumer(t, srv, NewPxGridConfig().SetRetry(2, time.Millisecond, 2*time.Millisecond))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		do   func() error
+		want int32
+	}{
+		{name: "query", do: func() error {
+			_, err := c.SessionDirectory().Rest().GetSessions("", nil).Do(ctx)
+			return err
+		}, want: 3},
+		{name: "streamed query", do: func() error {
+			for _, err := range c.SessionDirectory().Rest().StreamSessions("", nil).Do(ctx) {
+				return err
+			}
+			return nil
+		}, want: 3},
+		{name: "paged query", do: func() error {
+			for _, err := range c.TrustSecConfiguration().Rest().IterSecurityGroups(10).Do(ctx) {
+				return err
+			}
+			return nil
+		}, want: 3},
+		{name: "operation", do: func() error {
+			_, err := c.ANCConfig().Rest().ApplyEndpointByMACAddress(testMAC, "quarantine").Do(ctx)
+			return err
+		}, want: 1},
+		{name: "operation without result", do: func() error {
+			_, err := c.ANCConfig().Rest().DeletePolicyByName("quarantine").Do(ctx)
+			return err
+		}, want: 1},
+		// the name of the call does not make it a query
+		{name: "any call", do: func() error {
+			_, err := c.Service("svc").AnyREST("getThings", map[string]any{}).Do(ctx)
+			return err
+		}, want: 1},
+		{name: "any call with GET", do: func() error {
+			_, err := c.Service("svc").AnyRESTWithMethod(http.MethodGet, "getThings", nil).Do(ctx)
+			return err
+		}, want: 3},
+		{name: "any call with PATCH", do: func() error {
+			_, err := c.Service("svc").AnyRESTWithMethod("PATCH", "getThings", nil).Do(ctx)
+			return err
+		}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts.Store(0)
+			if err := tt.do(); err == nil {
+				t.Fatal("call succeeded, want the status code error")
+			}
+			if n := attempts.Load(); n != tt.want {
+				t.Fatalf("sent %d times, want %d", n, tt.want)
+			}
+		})
+	}
+}